@@ -1,64 +1,232 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"github.com/Jacobo0312/go-web/cmd/server"
 	"github.com/Jacobo0312/go-web/config"
-	"github.com/Jacobo0312/go-web/pkg/firebase"
-	_ "github.com/go-sql-driver/mysql"
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/mysql"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/Jacobo0312/go-web/internal/auth"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+	mysqldriver "github.com/go-sql-driver/mysql"
 )
 
+const usage = `Usage: %s [flags] <command> [arguments]
+
+Commands:
+  serve [--migrate=up|check]     serve the API, migrating the schema first or only checking it
+  migrate up                     apply the pending migrations
+  migrate down N                 roll back the last N migrations
+  migrate goto V                 migrate up or down to version V
+  migrate status                 print the schema version and the latest migration
+  migrate force V                set the version, without migrating, after fixing a failed migration
+  seed                           create the sample catalog when there are no products
+  user create [flags]            create a user, run "user create --help" for its flags
+  user set-role <user> <role>    change the role of a user, given by id or email
+  user disable <user>            disable a user, given by id or email
+  products import <file>         create the products in a .json or .csv file
+
+Flags, which configure every command:
+
+`
+
+// commands maps each command name to its implementation. args are the
+// arguments after the name.
+var commands = map[string]func(ctx context.Context, configs *config.Store, args []string) error{
+	"serve":    runServe,
+	"migrate":  runMigrate,
+	"seed":     runSeed,
+	"user":     runUser,
+	"products": runProducts,
+}
+
+// usageError reports invalid arguments
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// errInvalidFlags is returned when the flags of a command are invalid. The
+// flag package has already printed why.
+var errInvalidFlags = errors.New("invalid flags")
+
+// parseFlags parses the flags of a command
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errInvalidFlags
+	}
+	return nil
+}
+
 func main() {
-	cfg, err := config.Load()
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), usage, flags.Name())
+		flags.PrintDefaults()
+	}
+	printConfig := flags.Bool("print-config", false, "print the configuration, with secrets redacted, and exit")
+	loader, err := config.NewLoader(flags, os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	var configs *config.Store
+	if err == nil {
+		configs, err = config.NewStore(loader)
+	}
 	if err != nil {
-		log.Fatalf("Load error config: %v", err)
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
 	}
+	cfg := configs.Current()
 
-	//Firebase connection
-	firebase.InitFirebase()
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Error printing config: %v", err)
+		}
+		return
+	}
 
-	// DB connection
-	log.Println("Connecting to database...")
-	db, err := sql.Open("mysql", cfg.DBConnString)
-	if err != nil {
-		log.Fatalf("Error open database: %v", err)
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	run, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	//Logging
+	var logLevel slog.LevelVar
+	setLogLevel := func(cfg *config.Config) {
+		// Validated when loaded
+		level, _ := logging.ParseLevel(cfg.Log.Level)
+		logLevel.Set(level)
 	}
-	defer db.Close()
+	setLogLevel(cfg)
+	configs.Subscribe(setLogLevel)
 
-	// Run migrations
-	log.Println("Running migrations...")
-	if err := runMigrations(db); err != nil {
-		log.Fatalf("Error running migrations: %v", err)
+	// The other commands print their results on stdout
+	var logOutput io.Writer = os.Stderr
+	if args[0] == "serve" {
+		logOutput = os.Stdout
+	}
+	logger, err := logging.New(logOutput, cfg.Log.Format, &logLevel)
+	if err != nil {
+		log.Fatalf("Error initializing logger: %v", err)
 	}
+	// Also routes the log package, used by some dependencies, through logger
+	slog.SetDefault(logger)
 
-	srv := server.New(cfg, db)
+	// Kubernetes sends SIGTERM before killing the pod
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := srv.Start(); err != nil {
-		log.Fatalf("Starting server error: %v", err)
+	err = run(ctx, configs, args[1:])
+	var usageErr *usageError
+	switch {
+	case err == nil:
+	case errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errInvalidFlags):
+		stop()
+		os.Exit(2)
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(os.Stderr, "%v\nRun %s --help for the usage.\n", err, flags.Name())
+		stop()
+		os.Exit(2)
+	case args[0] == "serve":
+		slog.Error("Server error", "error", err)
+		stop()
+		os.Exit(1)
+	default:
+		// Validation errors span several lines
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		stop()
+		os.Exit(1)
 	}
 }
 
-func runMigrations(db *sql.DB) error {
-	driver, err := mysql.WithInstance(db, &mysql.Config{})
-	if err != nil {
-		return err
+// newIdentityProvider returns the identity provider selected in the config
+func newIdentityProvider(cfg *config.Config, db *sql.DB) (identity.IdentityProvider, error) {
+	switch cfg.Auth.Provider {
+	case config.AuthProviderFirebase:
+		return identity.NewFirebaseProvider(context.Background(), cfg.Auth.Firebase.CredentialsFile)
+	case config.AuthProviderLocal:
+		slog.Info("Using local identity provider")
+		timeouts := timeoutsOf(cfg)
+		keys := identity.NewKeyManager(auth.NewKeyRepository(db, timeouts), cfg.Auth.Local.KeyRotation, cfg.Auth.Local.TokenTTL)
+		return identity.NewLocalProvider(auth.NewCredentialRepository(db, timeouts), keys, cfg.Auth.Local.Issuer, cfg.Auth.Local.TokenTTL), nil
+	default:
+		return nil, fmt.Errorf("unknown auth provider %q", cfg.Auth.Provider)
 	}
+}
 
-	m, err := migrate.NewWithDatabaseInstance(
-		"file://db/migrations",
-		"mysql", driver)
+// timeoutsOf returns the deadlines every repository bounds its queries with
+func timeoutsOf(cfg *config.Config) database.Timeouts {
+	return database.Timeouts{Read: cfg.DB.ReadTimeout, Write: cfg.DB.WriteTimeout}
+}
+
+// openDB opens the MySQL connection pool with the options the repositories
+// rely on
+func openDB(cfg config.DBConfig) (*sql.DB, error) {
+	dbCfg, err := mysqldriver.ParseDSN(cfg.DSN)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return err
+	// Timestamps are scanned into time.Time
+	dbCfg.ParseTime = true
+	// Migrations with several statements (schema plus seed data) need it
+	dbCfg.MultiStatements = true
+	// RowsAffected counts matched rows, so an update that changes nothing is
+	// not mistaken for a missing id
+	dbCfg.ClientFoundRows = true
+
+	db, err := sql.Open("mysql", dbCfg.FormatDSN())
+	if err != nil {
+		return nil, err
 	}
+	configurePool(db, cfg)
+	return db, nil
+}
 
-	return nil
+// configurePool sizes the connection pool. It applies to an open pool too.
+func configurePool(db *sql.DB, cfg config.DBConfig) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
+// violationsError joins the validation violations of an input, one per line
+func violationsError(violations []appErrors.Violation) error {
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = v.Field + ": " + v.Message
+	}
+	return errors.New(strings.Join(lines, "\n"))
 }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Jacobo0312/go-web/config"
+	"github.com/Jacobo0312/go-web/db/migrations"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// runMigrate runs "migrate up", "migrate down N", "migrate goto V",
+// "migrate status" and "migrate force V"
+func runMigrate(ctx context.Context, configs *config.Store, args []string) error {
+	if len(args) == 0 {
+		return usagef("migrate needs a subcommand")
+	}
+	cfg := configs.Current()
+
+	var action func(m *migrate.Migrate) error
+	switch sub, rest := args[0], args[1:]; sub {
+	case "up", "status":
+		if len(rest) != 0 {
+			return usagef("migrate %s takes no arguments", sub)
+		}
+		if sub == "up" {
+			action = (*migrate.Migrate).Up
+		}
+	case "down":
+		n, err := numberArg(sub, rest, 1)
+		if err != nil {
+			return err
+		}
+		action = func(m *migrate.Migrate) error { return m.Steps(-n) }
+	case "goto":
+		v, err := numberArg(sub, rest, 0)
+		if err != nil {
+			return err
+		}
+		action = func(m *migrate.Migrate) error { return m.Migrate(uint(v)) }
+	case "force":
+		// -1 forgets every migration
+		v, err := numberArg(sub, rest, -1)
+		if err != nil {
+			return err
+		}
+		action = func(m *migrate.Migrate) error { return m.Force(v) }
+	default:
+		return usagef("unknown migrate subcommand %q", sub)
+	}
+
+	db, err := openDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	m, err := newMigrator(db, cfg.DB.MigrationsDir)
+	if err != nil {
+		return err
+	}
+	go func() {
+		// Stops after the running migration
+		<-ctx.Done()
+		m.GracefulStop <- true
+	}()
+
+	if action != nil {
+		if err := action(m); errors.Is(err, migrate.ErrNoChange) {
+			slog.Info("No migrations to apply")
+		} else if err != nil {
+			return err
+		}
+	}
+	return printMigrationStatus(m, cfg.DB.MigrationsDir)
+}
+
+// numberArg parses the only argument of a migrate subcommand, which must be
+// at least min
+func numberArg(sub string, args []string, min int) (int, error) {
+	if len(args) != 1 {
+		return 0, usagef("migrate %s takes one number", sub)
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < min {
+		return 0, usagef("migrate %s: %q is not a number of at least %d", sub, args[0], min)
+	}
+	return n, nil
+}
+
+// printMigrationStatus prints the schema version and the latest migration
+func printMigrationStatus(m *migrate.Migrate, dir string) error {
+	latest, err := latestMigration(dir)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Printf("version: none\nlatest: %d\n", latest)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("version: %d\ndirty: %t\nlatest: %d\n", version, dirty, latest)
+	if dirty {
+		fmt.Printf("Migration %d failed. Fix the schema by hand, then run \"migrate force V\" with the version it is at.\n", version)
+	}
+	return nil
+}
+
+// openMigrations returns the migrations embedded in the binary or, when dir
+// is set, those in dir
+func openMigrations(dir string) (source.Driver, error) {
+	if dir == "" {
+		return iofs.New(migrations.FS, ".")
+	}
+	return iofs.New(os.DirFS(dir), ".")
+}
+
+// newMigrator returns a migrator for the migrations of openMigrations. Its
+// progress is logged. It takes over db: closing it closes db too.
+func newMigrator(db *sql.DB, dir string) (*migrate.Migrate, error) {
+	src, err := openMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	driver, err := mysql.WithInstance(db, &mysql.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "mysql", driver)
+	if err != nil {
+		return nil, err
+	}
+	m.Log = migrateLogger{}
+	return m, nil
+}
+
+// prepareSchema refuses a schema that is dirty or ahead of the migrations,
+// which happens after a failed migration or when rolling back to an older
+// build. Then, if apply is set, it applies the pending migrations. It
+// returns the latest version, which readiness expects.
+func prepareSchema(db *sql.DB, dir string, apply bool) (uint, error) {
+	latest, err := latestMigration(dir)
+	if err != nil {
+		return 0, err
+	}
+	m, err := newMigrator(db, dir)
+	if err != nil {
+		return 0, err
+	}
+
+	version, dirty, err := m.Version()
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+	case err != nil:
+		return 0, err
+	case dirty:
+		return 0, fmt.Errorf("migration %d failed and the schema is dirty, fix it and run \"migrate force\"", version)
+	case version > latest:
+		return 0, fmt.Errorf("the schema is at version %d, ahead of the latest migration of this build, %d", version, latest)
+	}
+
+	if apply {
+		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+			return 0, err
+		}
+	}
+	return latest, nil
+}
+
+// latestMigration returns the version of the last migration
+func latestMigration(dir string) (uint, error) {
+	src, err := openMigrations(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, fmt.Errorf("no migrations found: %w", err)
+	}
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+// migrateLogger logs the progress of migrations
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...any) {
+	slog.Info(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (migrateLogger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestMigration(t *testing.T) {
+	embedded, err := latestMigration("")
+	require.NoError(t, err)
+	// The embedded migrations match the directory they come from
+	fromDir, err := latestMigration(filepath.Join("..", "db", "migrations"))
+	require.NoError(t, err)
+	assert.Equal(t, fromDir, embedded)
+
+	dir := t.TempDir()
+	for _, name := range []string{"1_init.up.sql", "1_init.down.sql", "7_add_index.up.sql", "7_add_index.down.sql"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o600))
+	}
+	latest, err := latestMigration(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), latest)
+
+	_, err = latestMigration(t.TempDir())
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Jacobo0312/go-web/config"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/internal/product"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/validation"
+)
+
+// runProducts runs "products import <file>"
+func runProducts(ctx context.Context, configs *config.Store, args []string) error {
+	if len(args) != 2 || args[0] != "import" {
+		return usagef("expected products import <file>")
+	}
+	cfg := configs.Current()
+
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+	products, err := decodeProducts(data, filepath.Ext(args[1]))
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[1], err)
+	}
+
+	db, err := openDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	service, _ := newProductService(cfg, db)
+	return importProducts(ctx, service, products)
+}
+
+// newProductService returns the product service used by the API, without
+// live change notifications. Events still reach the outbox, so webhooks
+// fire.
+func newProductService(cfg *config.Config, db *sql.DB) (product.ProductService, product.ProductRepository) {
+	timeouts := timeoutsOf(cfg)
+	repo := product.NewProductRepository(db, timeouts)
+	return product.NewProductService(repo, database.NewTransactor(db, timeouts), events.NewOutbox(), nil, nil), repo
+}
+
+// importProducts creates products one by one, so a failure leaves the
+// previous ones created
+func importProducts(ctx context.Context, service product.ProductService, products []domain.Product) error {
+	for i := range products {
+		if err := service.CreateProduct(ctx, &products[i]); err != nil {
+			return fmt.Errorf("creating product %d, %q: %w; the %d before it were created", i+1, products[i].Name, err, i)
+		}
+	}
+	fmt.Printf("Imported %d products\n", len(products))
+	return nil
+}
+
+// decodeProducts decodes and validates a JSON array of products, or a CSV
+// file whose header names the columns: name, price, description and
+// category. ext selects the format. Every invalid product is reported.
+func decodeProducts(data []byte, ext string) ([]domain.Product, error) {
+	var products []domain.Product
+	var err error
+	switch strings.ToLower(ext) {
+	case ".json":
+		products, err = decodeProductsJSON(data)
+	case ".csv":
+		products, err = decodeProductsCSV(data)
+	default:
+		return nil, fmt.Errorf("unsupported format %q, use .json or .csv", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for i, p := range products {
+		if violations := validation.Struct(p, "en"); len(violations) > 0 {
+			errs = append(errs, fmt.Errorf("product %d:\n%w", i+1, violationsError(violations)))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func decodeProductsJSON(data []byte) ([]domain.Product, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var products []domain.Product
+	if err := decoder.Decode(&products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func decodeProductsCSV(data []byte) ([]domain.Product, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading the header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "name", "price", "description", "category":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+	for _, name := range []string{"name", "price"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var products []domain.Product
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return products, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		price, err := strconv.ParseFloat(record[columns["price"]], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: price %q is not a number", line, record[columns["price"]])
+		}
+		p := domain.Product{Name: record[columns["name"]], Price: price}
+		if i, ok := columns["description"]; ok {
+			p.Description = record[i]
+		}
+		if i, ok := columns["category"]; ok {
+			p.Category = record[i]
+		}
+		products = append(products, p)
+	}
+}
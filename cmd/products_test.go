@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeProducts(t *testing.T) {
+	expected := []domain.Product{
+		{Name: "Ceramic Mug", Price: 9.99, Category: "Accessories"},
+		{Name: "Burr Grinder", Price: 89, Description: "Manual, steel burrs"},
+	}
+
+	t.Run("json", func(t *testing.T) {
+		products, err := decodeProducts([]byte(`[
+			{"name": "Ceramic Mug", "price": 9.99, "category": "Accessories"},
+			{"name": "Burr Grinder", "price": 89, "description": "Manual, steel burrs"}
+		]`), ".json")
+		require.NoError(t, err)
+		assert.Equal(t, expected, products)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		products, err := decodeProducts([]byte("Name, price, category, description\n"+
+			"Ceramic Mug,9.99,Accessories,\n"+
+			"Burr Grinder,89,,\"Manual, steel burrs\"\n"), ".CSV")
+		require.NoError(t, err)
+		assert.Equal(t, expected, products)
+	})
+
+	t.Run("sample catalog", func(t *testing.T) {
+		products, err := decodeProducts(sampleProducts, ".json")
+		require.NoError(t, err)
+		assert.NotEmpty(t, products)
+	})
+
+	errorTests := []struct {
+		name, data, ext, err string
+	}{
+		{"unsupported format", "", ".xml", `unsupported format ".xml", use .json or .csv`},
+		{"unknown json field", `[{"name": "Mug", "sku": "M1"}]`, ".json", `json: unknown field "sku"`},
+		{"unknown csv column", "name,price,sku\n", ".csv", `unknown column "sku"`},
+		{"missing csv column", "name\nMug\n", ".csv", `missing column "price"`},
+		{"invalid price", "name,price\nMug,9.99\nGrinder,cheap\n", ".csv", `line 3: price "cheap" is not a number`},
+		{"every invalid product", `[{"name": "", "price": 1}, {"name": "Mug", "price": 2}, {"name": "Grinder", "price": -1}]`, ".json",
+			"product 1:\nname: is required\nproduct 3:\nprice: must be at least 0"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeProducts([]byte(tt.data), tt.ext)
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
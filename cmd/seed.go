@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+
+	"github.com/Jacobo0312/go-web/config"
+)
+
+// sampleProducts is the catalog created by seed
+//
+//go:embed seed/products.json
+var sampleProducts []byte
+
+// runSeed creates the sample catalog for development. It does nothing when
+// there are products already, so it can run on every start.
+func runSeed(ctx context.Context, configs *config.Store, args []string) error {
+	if len(args) != 0 {
+		return usagef("seed takes no arguments")
+	}
+	cfg := configs.Current()
+
+	products, err := decodeProducts(sampleProducts, ".json")
+	if err != nil {
+		return fmt.Errorf("sample catalog: %w", err)
+	}
+
+	db, err := openDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	service, repo := newProductService(cfg, db)
+	existing, err := repo.GetBatch(ctx, 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		slog.Info("There are products already, skipping the sample catalog")
+		return nil
+	}
+	return importProducts(ctx, service, products)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/Jacobo0312/go-web/cmd/server"
+	"github.com/Jacobo0312/go-web/config"
+	"github.com/Jacobo0312/go-web/pkg/tracing"
+)
+
+// Values of serve --migrate
+const (
+	// migrateUp applies the pending migrations before serving
+	migrateUp = "up"
+	// migrateCheck leaves them to a separate job, and readiness fails
+	// until it has applied them
+	migrateCheck = "check"
+)
+
+// runServe serves the API until ctx is done. It refuses to start when the
+// schema is dirty or ahead of the migrations of this build.
+func runServe(ctx context.Context, configs *config.Store, args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	migrateMode := flags.String("migrate", migrateUp, `"up" applies the pending migrations before serving, "check" only checks the schema`)
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return usagef("serve takes no arguments")
+	}
+	if *migrateMode != migrateUp && *migrateMode != migrateCheck {
+		return usagef("serve --migrate must be %q or %q, got %q", migrateUp, migrateCheck, *migrateMode)
+	}
+	// Before anything else, so a SIGHUP sent while starting does not kill
+	// the server
+	configs.ReloadOnSIGHUP()
+	cfg := configs.Current()
+
+	// DB connection
+	slog.Info("Connecting to database...")
+	db, err := openDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+	configs.Subscribe(func(cfg *config.Config) {
+		configurePool(db, cfg.DB)
+	})
+
+	//Identity provider
+	provider, err := newIdentityProvider(cfg, db)
+	if err != nil {
+		return fmt.Errorf("initializing identity provider: %w", err)
+	}
+
+	// Migrations
+	slog.Info("Checking migrations...", "mode", *migrateMode)
+	migrationVersion, err := prepareSchema(db, cfg.DB.MigrationsDir, *migrateMode == migrateUp)
+	if err != nil {
+		return fmt.Errorf("preparing the schema: %w", err)
+	}
+
+	//Tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:     cfg.Tracing.Exporter,
+		ServiceName:  cfg.Tracing.ServiceName,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return fmt.Errorf("initializing tracing: %w", err)
+	}
+
+	srv := server.New(configs, db, provider, migrationVersion)
+	runErr := srv.Run(ctx)
+
+	// Flush the spans of the last requests
+	flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+	slog.Info("Server stopped")
+	return nil
+}
@@ -1,60 +1,348 @@
 package server
 
 import (
+	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/Jacobo0312/go-web/config"
+	"github.com/Jacobo0312/go-web/internal/account"
+	"github.com/Jacobo0312/go-web/internal/apikey"
+	"github.com/Jacobo0312/go-web/internal/auth"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/internal/feed"
 	"github.com/Jacobo0312/go-web/internal/handlers"
+	"github.com/Jacobo0312/go-web/internal/notification"
 	"github.com/Jacobo0312/go-web/internal/product"
+	"github.com/Jacobo0312/go-web/internal/push"
+	"github.com/Jacobo0312/go-web/internal/rbac"
+	"github.com/Jacobo0312/go-web/internal/realtime"
 	"github.com/Jacobo0312/go-web/internal/user"
+	"github.com/Jacobo0312/go-web/internal/webhook"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/health"
 	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/Jacobo0312/go-web/pkg/lifecycle"
+	"github.com/Jacobo0312/go-web/pkg/metrics"
 	"github.com/Jacobo0312/go-web/pkg/middlewares"
 )
 
 type Server struct {
-	config *config.Config
-	router *http.ServeMux
-	db     *sql.DB
+	// config is the configuration at startup. Settings that are reloaded
+	// reach the subsystems through configs.Subscribe.
+	config   *config.Config
+	configs  *config.Store
+	router   *http.ServeMux
+	db       *sql.DB
+	provider identity.IdentityProvider
+	// migrationVersion is the schema version readiness expects
+	migrationVersion uint
+	workers          *lifecycle.Manager
+	// shuttingDown fails readiness as soon as shutdown begins
+	shuttingDown atomic.Bool
 }
 
-func New(cfg *config.Config, db *sql.DB) *Server {
+func New(configs *config.Store, db *sql.DB, provider identity.IdentityProvider, migrationVersion uint) *Server {
 	return &Server{
-		config: cfg,
-		router: http.NewServeMux(),
-		db:     db,
+		config:           configs.Current(),
+		configs:          configs,
+		router:           http.NewServeMux(),
+		db:               db,
+		provider:         provider,
+		migrationVersion: migrationVersion,
+		workers:          lifecycle.NewManager(),
 	}
 }
 
-func (s *Server) Start() error {
+// Run serves until ctx is done and then shuts down gracefully: readiness
+// fails, in-flight requests are drained and the background workers are
+// stopped
+func (s *Server) Run(ctx context.Context) error {
+
+	//Metrics
+	metricsRegistry := metrics.NewRegistry()
+	metricsRegistry.RegisterRuntime()
+	metricsRegistry.RegisterDBStats(s.db)
+	s.router.Handle("GET /metrics", metricsRegistry.Handler())
 
 	//Health check
 	s.router.HandleFunc("GET /ping", func(w http.ResponseWriter, r *http.Request) {
 		helpers.RespondWithJSON(w, http.StatusOK, map[string]string{"message": "pong"})
 	})
 
+	healthOptions := health.RegistryOptions{Timeout: s.config.Health.CheckTimeout, CacheTTL: s.config.Health.CacheTTL}
+	// Liveness has no dependency checks: restarting does not fix a database outage
+	liveness := health.NewRegistry(healthOptions)
+	readiness := health.NewRegistry(healthOptions)
+	readiness.Register("database", health.PingDB(s.db))
+	readiness.Register("migrations", health.MigrationVersion(s.db, s.migrationVersion))
+	if pinger, ok := s.provider.(identity.Pinger); ok {
+		readiness.Register("identity_provider", health.CheckerFunc(pinger.Ping))
+	}
+	healthHandler := handlers.NewHealthHandler(liveness, readiness, s.shuttingDown.Load)
+	healthHandler.RegisterRoutes(s.router)
+
+	// Every repository bounds its queries with these deadlines
+	timeouts := database.Timeouts{Read: s.config.DB.ReadTimeout, Write: s.config.DB.WriteTimeout}
+
+	//Notifications
+	renderer, err := notification.NewRenderer(s.config.Mail.DefaultLocale)
+	if err != nil {
+		return err
+	}
+	mailer, err := s.newMailer()
+	if err != nil {
+		return err
+	}
+	outboxRepo := notification.NewOutboxRepository(s.db, timeouts)
+	notifier := notification.NewNotifier(renderer, outboxRepo)
+	dispatcher := notification.NewDispatcher(outboxRepo, mailer, notification.DispatcherOptions{
+		Interval:    5 * time.Second,
+		BatchSize:   50,
+		MaxAttempts: 8,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  6 * time.Hour,
+	})
+
+	s.workers.Go("email outbox", dispatcher.Run)
+
+	//Access control
+	transactor := database.NewTransactor(s.db, timeouts)
+	outbox := events.NewOutbox()
+	userRepo := user.NewUserRepository(s.db, timeouts)
+	rbacRepo := rbac.NewRBACRepository(s.db, timeouts)
+	apiKeyRepo := apikey.NewAPIKeyRepository(s.db, timeouts)
+	apiKeyService := apikey.NewAPIKeyService(apiKeyRepo)
+	rbacService := rbac.NewRBACService(rbacRepo, userRepo, transactor, outbox, s.provider)
+	authenticate := middlewares.AuthMiddleware(s.provider, apiKeyService)
+	authorizer := rbac.NewAuthorizer(rbacService, userRepo, authenticate)
+	roleHandler := handlers.NewRoleHandler(rbacService, authorizer)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService, authorizer)
+
+	roleHandler.RegisterRoutes(s.router)
+	apiKeyHandler.RegisterRoutes(s.router)
+
+	//Configuration
+	configHandler := handlers.NewConfigHandler(s.configs.Status, authorizer)
+
+	configHandler.RegisterRoutes(s.router)
+
+	s.workers.Go("config reloader", func(ctx context.Context) {
+		s.configs.Watch(ctx, 5*time.Second)
+	})
+
+	//Webhooks
+	webhookRepo := webhook.NewWebhookRepository(s.db, timeouts)
+	webhookService := webhook.NewWebhookService(webhookRepo)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, authorizer)
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, webhook.DispatcherOptions{
+		Interval:    2 * time.Second,
+		BatchSize:   100,
+		Concurrency: 8,
+		Timeout:     10 * time.Second,
+		MaxAttempts: 10,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  12 * time.Hour,
+	})
+
+	webhookHandler.RegisterRoutes(s.router)
+
+	s.workers.Go("webhook dispatcher", webhookDispatcher.Run)
+
+	//Local auth
+	var sessions account.SessionRevoker
+	if localProvider, ok := s.provider.(*identity.LocalProvider); ok {
+		refreshTokenRepo := auth.NewRefreshTokenRepository(s.db, timeouts)
+		sessions = refreshTokenRepo
+		authService := auth.NewAuthService(localProvider, refreshTokenRepo, s.config.Auth.Local.RefreshTTL)
+		authHandler := handlers.NewAuthHandler(authService, authorizer)
+
+		authHandler.RegisterRoutes(s.router)
+	}
+
+	//Push notifications
+	deviceRepo := push.NewDeviceRepository(s.db, timeouts)
+	pushService := push.NewPushService(deviceRepo)
+	deviceHandler := handlers.NewDeviceHandler(pushService, authorizer)
+
+	deviceHandler.RegisterRoutes(s.router)
+
+	var priceDrops product.PriceDropListener
+	if s.config.Push.FCMProjectID != "" {
+		sender, err := push.NewFCMSender(context.Background(), push.FCMConfig{
+			Endpoint:        s.config.Push.FCMEndpoint,
+			ProjectID:       s.config.Push.FCMProjectID,
+			CredentialsFile: s.config.Push.FCMCredentialsFile,
+		})
+		if err != nil {
+			return err
+		}
+		pushDispatcher := push.NewDispatcher(deviceRepo, sender, push.DispatcherOptions{
+			QueueSize:   1000,
+			BatchSize:   500,
+			Concurrency: 16,
+		})
+		priceDrops = pushDispatcher
+
+		s.workers.Go("push dispatcher", pushDispatcher.Run)
+	} else {
+		slog.Warn("FCM_PROJECT_ID is not set, push notifications are disabled")
+	}
+
 	//Product
-	productRepo := product.NewProductRepository(s.db)
-	productService := product.NewProductService(productRepo)
-	productHandler := handlers.NewProductHandler(productService)
+	productRepo := product.NewProductRepository(s.db, timeouts)
+	productChanges := events.NewBroker(events.BrokerOptions{
+		BufferSize:     64,
+		HistorySize:    1000,
+		MaxSubscribers: 500,
+	})
+	realtimeHub := realtime.NewHub(realtime.HubOptions{
+		SendBuffer:            64,
+		MaxConnectionsPerUser: 5,
+		MaxTopics:             100,
+		MaxMessageSize:        4096,
+		PingInterval:          30 * time.Second,
+		PongWait:              60 * time.Second,
+		WriteWait:             10 * time.Second,
+	})
+	productService := product.NewTracedProductService(
+		product.NewProductService(productRepo, transactor, outbox, events.Publishers{productChanges, realtimeHub, events.NewCounter(metricsRegistry)}, priceDrops),
+	)
+	productHandler := handlers.NewProductHandler(productService, authorizer)
+	metricsRegistry.NewGaugeFunc("product_stream_subscribers", "Open product event streams.", func() float64 {
+		return float64(productChanges.Subscribers())
+	})
+	metricsRegistry.NewGaugeFunc("websocket_connections", "Open WebSocket connections.", func() float64 {
+		return float64(realtimeHub.Connections())
+	})
+
+	productStreamHandler := handlers.NewProductStreamHandler(productChanges, 15*time.Second)
 
 	productHandler.RegisterRoutes(s.router)
+	productStreamHandler.RegisterRoutes(s.router)
+
+	//Realtime
+	webSocketHandler := handlers.NewWebSocketHandler(realtimeHub, authenticate, s.config.Server.WSAllowedOrigins)
+	s.configs.Subscribe(func(cfg *config.Config) {
+		webSocketHandler.SetAllowedOrigins(cfg.Server.WSAllowedOrigins)
+	})
+
+	webSocketHandler.RegisterRoutes(s.router)
+
+	//Feeds
+	feedService := feed.NewFeedService(productRepo, s.config.Server.PublicBaseURL, s.config.Feed.Currency)
+	feedHandler := handlers.NewFeedHandler(feedService)
+
+	feedHandler.RegisterRoutes(s.router)
 
 	//User
-	userRepo := user.NewUserRepository(s.db)
-	userService := user.NewUserService(userRepo)
-	userHandler := handlers.NewUserHandler(userService)
+	userService := user.NewTracedUserService(user.NewUserService(userRepo, rbacRepo, transactor, outbox, s.provider, notifier))
+	userHandler := handlers.NewUserHandler(userService, authorizer)
 
 	userHandler.RegisterRoutes(s.router)
 
-	middleware := middlewares.MiddlewareChain(middlewares.LoggingMiddleware)
+	//Account
+	accountService := account.NewAccountService(
+		account.NewTokenRepository(s.db, timeouts),
+		userRepo,
+		s.provider,
+		sessions,
+		account.NewTokenSigner(s.config.Account.TokenSecret),
+		notifier,
+		account.Options{
+			PublicBaseURL:    s.config.Server.PublicBaseURL,
+			VerificationTTL:  s.config.Account.EmailVerificationTTL,
+			PasswordResetTTL: s.config.Account.PasswordResetTTL,
+		},
+	)
+	accountHandler := handlers.NewAccountHandler(accountService, authorizer)
+
+	accountHandler.RegisterRoutes(s.router)
+
+	middleware := middlewares.MiddlewareChain(
+		middlewares.LoggingMiddleware(slog.Default(), s.router),
+		middlewares.TracingMiddleware,
+		middlewares.MetricsMiddleware(metricsRegistry),
+	)
 
-	log.Printf("Starting server on %s", s.config.ServerAddr)
+	slog.Info("Starting server", "addr", s.config.Server.Addr)
 	server := &http.Server{
-		Addr:    s.config.ServerAddr,
-		Handler: middleware(s.router),
+		Addr:              s.config.Server.Addr,
+		Handler:           middleware(s.router),
+		ReadHeaderTimeout: s.config.Server.ReadHeaderTimeout,
+		ReadTimeout:       s.config.Server.ReadTimeout,
+		WriteTimeout:      s.config.Server.WriteTimeout,
+		IdleTimeout:       s.config.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
+	}
+	// Shutdown does not wait for hijacked WebSockets and would wait for
+	// streams until the timeout, so both are told to finish
+	server.RegisterOnShutdown(productChanges.Close)
+	server.RegisterOnShutdown(realtimeHub.Close)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		s.stopWorkers()
+		return err
+	case <-ctx.Done():
 	}
 
-	return server.ListenAndServe()
+	return s.shutdown(server)
+}
+
+// shutdown fails readiness, waits ShutdownDelay for load balancers to notice,
+// drains the connections and stops the workers
+func (s *Server) shutdown(server *http.Server) error {
+	slog.Info("Shutting down server")
+	s.shuttingDown.Store(true)
+	time.Sleep(s.config.Server.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Server.ShutdownTimeout)
+	defer cancel()
+
+	err := server.Shutdown(ctx)
+	if err != nil {
+		slog.Error("Error draining connections", "error", err)
+	}
+
+	s.stopWorkers()
+	return err
+}
+
+func (s *Server) stopWorkers() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := s.workers.Stop(ctx); err != nil {
+		slog.Error("Error stopping background workers", "error", err)
+	}
+}
+
+// newMailer returns the mailer selected in the config
+func (s *Server) newMailer() (notification.Mailer, error) {
+	switch s.config.Mail.Driver {
+	case config.MailDriverSMTP:
+		return notification.NewSMTPMailer(notification.SMTPConfig{
+			Host:     s.config.Mail.SMTP.Host,
+			Port:     s.config.Mail.SMTP.Port,
+			Username: s.config.Mail.SMTP.Username,
+			Password: s.config.Mail.SMTP.Password,
+			From:     s.config.Mail.From,
+		}), nil
+	case config.MailDriverFile:
+		slog.Info("Writing emails to files", "dir", s.config.Mail.Dir)
+		return notification.NewFileMailer(s.config.Mail.Dir, s.config.Mail.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", s.config.Mail.Driver)
+	}
 }
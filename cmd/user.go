@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Jacobo0312/go-web/config"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/internal/notification"
+	"github.com/Jacobo0312/go-web/internal/rbac"
+	"github.com/Jacobo0312/go-web/internal/user"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/validation"
+)
+
+// userCommands are the services behind the user commands, built like in the
+// server so users change in the identity provider too
+type userCommands struct {
+	repo  user.UserRepository
+	users user.UserService
+	rbac  rbac.RBACService
+}
+
+func newUserCommands(cfg *config.Config, db *sql.DB) (*userCommands, error) {
+	provider, err := newIdentityProvider(cfg, db)
+	if err != nil {
+		return nil, fmt.Errorf("initializing identity provider: %w", err)
+	}
+	// Emails are queued in the outbox and sent by the server
+	renderer, err := notification.NewRenderer(cfg.Mail.DefaultLocale)
+	if err != nil {
+		return nil, err
+	}
+
+	timeouts := timeoutsOf(cfg)
+	notifier := notification.NewNotifier(renderer, notification.NewOutboxRepository(db, timeouts))
+	repo := user.NewUserRepository(db, timeouts)
+	roles := rbac.NewRBACRepository(db, timeouts)
+	transactor := database.NewTransactor(db, timeouts)
+	outbox := events.NewOutbox()
+	return &userCommands{
+		repo:  repo,
+		users: user.NewUserService(repo, roles, transactor, outbox, provider, notifier),
+		rbac:  rbac.NewRBACService(roles, repo, transactor, outbox, provider),
+	}, nil
+}
+
+// runUser runs "user create", "user set-role <user> <role>" and
+// "user disable <user>"
+func runUser(ctx context.Context, configs *config.Store, args []string) error {
+	if len(args) == 0 {
+		return usagef("user needs a subcommand")
+	}
+	cfg := configs.Current()
+
+	var run func(c *userCommands) (*domain.User, error)
+	switch sub, rest := args[0], args[1:]; sub {
+	case "create":
+		request, err := parseCreateUser(rest)
+		if err != nil {
+			return err
+		}
+		run = func(c *userCommands) (*domain.User, error) {
+			return c.users.CreateUser(ctx, request)
+		}
+	case "set-role":
+		if len(rest) != 2 {
+			return usagef("expected user set-role <user> <role>")
+		}
+		run = func(c *userCommands) (*domain.User, error) {
+			u, err := c.find(ctx, rest[0])
+			if err != nil {
+				return nil, err
+			}
+			return c.rbac.AssignRole(ctx, u.ID, rest[1])
+		}
+	case "disable":
+		if len(rest) != 1 {
+			return usagef("expected user disable <user>")
+		}
+		run = func(c *userCommands) (*domain.User, error) {
+			u, err := c.find(ctx, rest[0])
+			if err != nil {
+				return nil, err
+			}
+			disabled := true
+			return c.users.UpdateUser(ctx, u.ID, &domain.UpdateUserRequest{Disabled: &disabled})
+		}
+	default:
+		return usagef("unknown user subcommand %q", sub)
+	}
+
+	db, err := openDB(cfg.DB)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	defer db.Close()
+
+	c, err := newUserCommands(cfg, db)
+	if err != nil {
+		return err
+	}
+	u, err := run(c)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(u)
+}
+
+// find returns the user with the given id or, when it has an @, email
+func (c *userCommands) find(ctx context.Context, idOrEmail string) (*domain.User, error) {
+	if strings.Contains(idOrEmail, "@") {
+		return c.repo.FindByEmail(ctx, idOrEmail)
+	}
+	return c.repo.FindByID(ctx, idOrEmail)
+}
+
+// parseCreateUser parses the flags of "user create". The password is read
+// from the first line of stdin, so it stays out of the shell history.
+func parseCreateUser(args []string) (*domain.CreateUserRequest, error) {
+	request := &domain.CreateUserRequest{}
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: user create --email E --name N [--role R] [--locale L] < password")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&request.Email, "email", "", "email address, required")
+	flags.StringVar(&request.Name, "name", "", "display name, required")
+	flags.StringVar(&request.Role, "role", "user", "role")
+	flags.StringVar(&request.Locale, "locale", "", "language of the emails sent to the user, e.g. es")
+	if err := parseFlags(flags, args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, usagef("user create takes no arguments besides its flags")
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		return nil, fmt.Errorf("reading the password from stdin: %w", err)
+	}
+	request.Password = strings.TrimRight(password, "\r\n")
+
+	if violations := validation.Struct(request, "en"); len(violations) > 0 {
+		return nil, violationsError(violations)
+	}
+	return request, nil
+}
@@ -1,27 +1,212 @@
 package config
 
-import (
-	"log"
-	"os"
+import "time"
 
-	"github.com/joho/godotenv"
+// Supported mail drivers
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverFile = "file"
 )
 
+// Supported identity providers
+const (
+	AuthProviderFirebase = "firebase"
+	AuthProviderLocal    = "local"
+)
+
+// Config is the whole configuration. Each field is read, in increasing
+// precedence, from its default, the config file, its environment variable
+// (the env tag) and its command line flag (the yaml path, e.g.
+// --server.addr). Fields tagged secret are redacted when printed, and
+// fields tagged reload are applied by Store.Reload without a restart.
 type Config struct {
-	ServerAddr   string `json:"server_addr"`
-	DBConnString string `json:"db_conn_string"`
+	Server  ServerConfig  `yaml:"server"`
+	Health  HealthConfig  `yaml:"health"`
+	DB      DBConfig      `yaml:"db"`
+	Auth    AuthConfig    `yaml:"auth"`
+	Account AccountConfig `yaml:"account"`
+	Mail    MailConfig    `yaml:"mail"`
+	Push    PushConfig    `yaml:"push"`
+	Feed    FeedConfig    `yaml:"feed"`
+	Tracing TracingConfig `yaml:"tracing"`
+	Log     LogConfig     `yaml:"log"`
 }
 
-func Load() (*Config, error) {
-	err := godotenv.Load("./.env")
+type ServerConfig struct {
+	Addr string `yaml:"addr" env:"SERVER_ADDR"`
+	// ReadHeaderTimeout and ReadTimeout bound reading the request headers
+	// and the whole request
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	// WriteTimeout bounds writing the response. Zero keeps product streams
+	// open.
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownDelay keeps serving with failing readiness before draining,
+	// so load balancers stop sending new requests first
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"SHUTDOWN_DELAY"`
+	// ShutdownTimeout bounds the connection draining and, separately, the
+	// stop of the background workers
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// PublicBaseURL is the absolute URL used for links in feeds, sitemaps
+	// and emails
+	PublicBaseURL string `yaml:"public_base_url" env:"PUBLIC_BASE_URL"`
+	// WSAllowedOrigins are the browser origins, besides the server itself,
+	// allowed to open WebSockets
+	WSAllowedOrigins []string `yaml:"ws_allowed_origins" env:"WS_ALLOWED_ORIGINS" reload:"true"`
+}
 
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
+type HealthConfig struct {
+	// CheckTimeout bounds each readiness check
+	CheckTimeout time.Duration `yaml:"check_timeout" env:"HEALTH_CHECK_TIMEOUT"`
+	// CacheTTL is how long readiness check results are reused
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"HEALTH_CACHE_TTL"`
+}
 
-	return &Config{
-		ServerAddr:   os.Getenv("SERVER_ADDR"),
-		DBConnString: os.Getenv("DB_CONN_STRING"),
-	}, nil
+type DBConfig struct {
+	// DSN is the MySQL data source name. It holds the password.
+	DSN string `yaml:"dsn" env:"DB_CONN_STRING" secret:"true"`
+	// MigrationsDir reads the migrations from a directory instead of those
+	// embedded in the binary, e.g. to try a new one without rebuilding
+	MigrationsDir string `yaml:"migrations_dir" env:"DB_MIGRATIONS_DIR"`
+	// ReadTimeout and WriteTimeout bound each repository query and
+	// statement. Zero disables the deadline.
+	ReadTimeout  time.Duration `yaml:"read_timeout" env:"DB_READ_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"DB_WRITE_TIMEOUT"`
+	// MaxOpenConns and MaxIdleConns size the connection pool. Zero open
+	// connections means no limit.
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS" reload:"true"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" reload:"true"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" reload:"true"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" reload:"true"`
+}
+
+type AuthConfig struct {
+	// Provider selects the identity provider: "firebase" or "local"
+	Provider string             `yaml:"provider" env:"AUTH_PROVIDER"`
+	Firebase FirebaseAuthConfig `yaml:"firebase"`
+	Local    LocalAuthConfig    `yaml:"local"`
+}
+
+type FirebaseAuthConfig struct {
+	CredentialsFile string `yaml:"credentials_file" env:"FIREBASE_CREDENTIALS_FILE"`
+}
+
+type LocalAuthConfig struct {
+	Issuer      string        `yaml:"issuer" env:"LOCAL_AUTH_ISSUER"`
+	TokenTTL    time.Duration `yaml:"token_ttl" env:"LOCAL_AUTH_TOKEN_TTL"`
+	RefreshTTL  time.Duration `yaml:"refresh_ttl" env:"LOCAL_AUTH_REFRESH_TTL"`
+	KeyRotation time.Duration `yaml:"key_rotation" env:"LOCAL_AUTH_KEY_ROTATION"`
+}
+
+type AccountConfig struct {
+	// TokenSecret signs email verification and password reset tokens
+	TokenSecret          string        `yaml:"token_secret" env:"ACCOUNT_TOKEN_SECRET" secret:"true"`
+	EmailVerificationTTL time.Duration `yaml:"email_verification_ttl" env:"EMAIL_VERIFICATION_TTL"`
+	PasswordResetTTL     time.Duration `yaml:"password_reset_ttl" env:"PASSWORD_RESET_TTL"`
+}
 
+type MailConfig struct {
+	// Driver selects how emails leave the outbox: "smtp" or "file"
+	Driver        string     `yaml:"driver" env:"MAIL_DRIVER"`
+	From          string     `yaml:"from" env:"MAIL_FROM"`
+	Dir           string     `yaml:"dir" env:"MAIL_DIR"`
+	DefaultLocale string     `yaml:"default_locale" env:"DEFAULT_LOCALE"`
+	SMTP          SMTPConfig `yaml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD" secret:"true"`
+}
+
+type PushConfig struct {
+	// FCMProjectID enables push notifications. FCMEndpoint can point at a
+	// local stub, in which case FCMCredentialsFile may be left empty.
+	FCMProjectID       string `yaml:"fcm_project_id" env:"FCM_PROJECT_ID"`
+	FCMEndpoint        string `yaml:"fcm_endpoint" env:"FCM_ENDPOINT"`
+	FCMCredentialsFile string `yaml:"fcm_credentials_file" env:"FCM_CREDENTIALS_FILE"`
+}
+
+type FeedConfig struct {
+	Currency string `yaml:"currency" env:"FEED_CURRENCY"`
+}
+
+type TracingConfig struct {
+	// Exporter selects where spans go: "none", "stdout" or "otlp"
+	Exporter     string  `yaml:"exporter" env:"TRACING_EXPORTER"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"`
+	SampleRatio  float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName  string  `yaml:"service_name" env:"SERVICE_NAME"`
+}
+
+type LogConfig struct {
+	// Format selects the log handler: "text" or "json"
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+	// Level is the minimum level logged: "debug", "info", "warn" or "error"
+	Level string `yaml:"level" env:"LOG_LEVEL" reload:"true"`
+}
+
+// Default returns the configuration used when nothing overrides it
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   10 * time.Second,
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+			CacheTTL:     5 * time.Second,
+		},
+		DB: DBConfig{
+			ReadTimeout:     5 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			MaxOpenConns:    25,
+			MaxIdleConns:    25,
+			ConnMaxLifetime: 5 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Auth: AuthConfig{
+			Provider: AuthProviderFirebase,
+			Firebase: FirebaseAuthConfig{CredentialsFile: "./credentials.json"},
+			Local: LocalAuthConfig{
+				Issuer:      "go-web",
+				TokenTTL:    15 * time.Minute,
+				RefreshTTL:  30 * 24 * time.Hour,
+				KeyRotation: 30 * 24 * time.Hour,
+			},
+		},
+		Account: AccountConfig{
+			EmailVerificationTTL: 48 * time.Hour,
+			PasswordResetTTL:     time.Hour,
+		},
+		Mail: MailConfig{
+			Driver:        MailDriverFile,
+			From:          "go-web <no-reply@localhost>",
+			Dir:           "./tmp/mail",
+			DefaultLocale: "en",
+			SMTP:          SMTPConfig{Host: "localhost", Port: 1025},
+		},
+		Push: PushConfig{
+			FCMEndpoint: "https://fcm.googleapis.com",
+		},
+		Feed: FeedConfig{
+			Currency: "USD",
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "http://localhost:4318",
+			SampleRatio:  1,
+			ServiceName:  "go-web",
+		},
+		Log: LogConfig{
+			Format: "text",
+			Level:  "info",
+		},
+	}
 }
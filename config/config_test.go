@@ -0,0 +1,151 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDSN = "app:s3cret@tcp(localhost:3306)/app"
+
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	return Load(flags, args)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	t.Setenv("DB_CONN_STRING", testDSN)
+
+	cfg, err := load(t)
+	require.NoError(t, err)
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Empty(t, cfg.DB.MigrationsDir)
+	assert.Equal(t, "./credentials.json", cfg.Auth.Firebase.CredentialsFile)
+	assert.Equal(t, 5*time.Second, cfg.DB.ReadTimeout)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+  ws_allowed_origins: [https://app.example.com]
+db:
+  dsn: "`+testDSN+`"
+  max_open_conns: 10
+  max_idle_conns: 5
+log:
+  level: warn
+`)
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("DB_MAX_OPEN_CONNS", "20")
+	t.Setenv("LOG_LEVEL", "error")
+	t.Setenv("SERVER_READ_TIMEOUT", "1m")
+
+	cfg, err := load(t, "--log.level=debug")
+	require.NoError(t, err)
+
+	// Default
+	assert.Equal(t, 5*time.Second, cfg.Server.ReadHeaderTimeout)
+	// File
+	assert.Equal(t, ":9000", cfg.Server.Addr)
+	assert.Equal(t, []string{"https://app.example.com"}, cfg.Server.WSAllowedOrigins)
+	assert.Equal(t, 5, cfg.DB.MaxIdleConns)
+	// Environment over file
+	assert.Equal(t, 20, cfg.DB.MaxOpenConns)
+	assert.Equal(t, time.Minute, cfg.Server.ReadTimeout)
+	// Flag over environment
+	assert.Equal(t, "debug", cfg.Log.Level)
+}
+
+func TestLoadJSONFile(t *testing.T) {
+	file := writeFile(t, "config.json", `{"db": {"dsn": "`+testDSN+`", "read_timeout": "2s"}, "auth": {"provider": "local"}}`)
+
+	cfg, err := load(t, "--config", file)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, cfg.DB.ReadTimeout)
+	assert.Equal(t, AuthProviderLocal, cfg.Auth.Provider)
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Run("unknown key in file", func(t *testing.T) {
+		file := writeFile(t, "config.yaml", "server:\n  adress: \":9000\"\n")
+
+		_, err := load(t, "--config", file)
+		assert.ErrorContains(t, err, "field adress not found")
+	})
+
+	t.Run("invalid flag", func(t *testing.T) {
+		_, err := load(t, "--db.max_open_conns=many")
+		assert.ErrorContains(t, err, `"many" is not an integer`)
+	})
+
+	t.Run("every invalid setting is reported", func(t *testing.T) {
+		t.Setenv("SMTP_PORT", "smtp")
+		t.Setenv("AUTH_PROVIDER", "ldap")
+		t.Setenv("TRACING_SAMPLE_RATIO", "2")
+
+		_, err := load(t, "--db.read_timeout=-1s")
+		require.Error(t, err)
+		assert.Equal(t, `SMTP_PORT: "smtp" is not an integer
+db.read_timeout: must not be negative
+db.dsn: is required
+auth.provider: must be "firebase" or "local", got "ldap"
+tracing.sample_ratio: must be between 0 and 1`, err.Error())
+	})
+}
+
+func TestValidate(t *testing.T) {
+	cfg := Default()
+	cfg.DB.DSN = testDSN
+	cfg.Auth.Provider = AuthProviderLocal
+	cfg.Auth.Local.TokenTTL = 0
+	cfg.Mail.Driver = MailDriverSMTP
+	cfg.Mail.SMTP.Host = ""
+	cfg.Server.PublicBaseURL = "shop.example.com"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Equal(t, `server.public_base_url: must be an absolute URL
+auth.local.token_ttl: must be positive
+mail.smtp.host: is required by the smtp driver`, err.Error())
+}
+
+func TestPrint(t *testing.T) {
+	cfg := Default()
+	cfg.DB.DSN = testDSN
+	cfg.Mail.SMTP.Password = "hunter2"
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+	assert.Contains(t, out.String(), "dsn: REDACTED")
+	assert.Contains(t, out.String(), "password: REDACTED")
+	assert.Contains(t, out.String(), `token_secret: ""`)
+	assert.NotContains(t, out.String(), "s3cret")
+	assert.NotContains(t, out.String(), "hunter2")
+	// The config itself is untouched
+	assert.Equal(t, testDSN, cfg.DB.DSN)
+
+	// Once its secrets are set, the output is a valid config file
+	t.Setenv("DB_CONN_STRING", testDSN)
+	cfg2, err := load(t, "--config", writeFile(t, "printed.yaml", out.String()))
+	require.NoError(t, err)
+	assert.Equal(t, redacted, cfg2.Mail.SMTP.Password)
+	assert.Equal(t, cfg.Server.IdleTimeout, cfg2.Server.IdleTimeout)
+	assert.Equal(t, cfg.DB.MaxOpenConns, cfg2.DB.MaxOpenConns)
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the config file when --config is not given
+const FileEnv = "CONFIG_FILE"
+
+// Load builds the configuration from, in increasing precedence, the
+// defaults, the config file, the environment and the flags in args. See
+// NewLoader.
+func Load(flags *flag.FlagSet, args []string) (*Config, error) {
+	loader, err := NewLoader(flags, args)
+	if err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
+
+// Loader reads the configuration from its sources. The flags are parsed
+// once, and the config file is read again on every Load.
+type Loader struct {
+	file      string
+	overrides map[string]string
+}
+
+// NewLoader registers --config and a flag per field on flags, so callers can
+// add their own flags before, and parses args. Variables in ./.env are
+// added to the environment when the file exists, without overriding it.
+func NewLoader(flags *flag.FlagSet, args []string) (*Loader, error) {
+	l := &Loader{overrides: map[string]string{}}
+
+	flags.StringVar(&l.file, "config", "", "YAML or JSON config file (env "+FileEnv+")")
+	for _, f := range fieldsOf(Default()) {
+		usage := fmt.Sprintf("overrides %s (env %s)", f.path, f.env)
+		flags.Func(f.path, usage, func(s string) error {
+			// Checked now, so the error names the flag
+			if err := setString(reflect.New(f.value.Type()).Elem(), s); err != nil {
+				return err
+			}
+			l.overrides[f.path] = s
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := godotenv.Load("./.env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("loading .env: %w", err)
+	}
+	if l.file == "" {
+		l.file = os.Getenv(FileEnv)
+	}
+	return l, nil
+}
+
+// File returns the path of the config file, or "" when there is none
+func (l *Loader) File() string {
+	return l.file
+}
+
+// Load reads and validates the configuration. Every invalid value is
+// reported, not only the first one.
+func (l *Loader) Load() (*Config, error) {
+	cfg := Default()
+	if l.file != "" {
+		if err := loadFile(cfg, l.file); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fieldsOf(cfg) {
+		if value := os.Getenv(f.env); value != "" {
+			if err := setString(f.value, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.env, err))
+			}
+		}
+		if value, ok := l.overrides[f.path]; ok {
+			setString(f.value, value)
+		}
+	}
+	if err := errors.Join(append(errs, cfg.Validate())...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile reads a YAML file into cfg. JSON is valid YAML, so JSON files
+// work too. Unknown keys are an error, to catch typos.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// field is a leaf of Config
+type field struct {
+	// path is the dotted yaml path, e.g. "server.addr"
+	path   string
+	env    string
+	secret bool
+	// reload is true for settings applied without a restart
+	reload bool
+	value  reflect.Value
+}
+
+// fieldsOf lists the leaves of cfg in declaration order
+func fieldsOf(cfg *Config) []field {
+	var fields []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			path := prefix + sf.Tag.Get("yaml")
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), path+".")
+				continue
+			}
+			fields = append(fields, field{
+				path:   path,
+				env:    sf.Tag.Get("env"),
+				secret: sf.Tag.Get("secret") == "true",
+				reload: sf.Tag.Get("reload") == "true",
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return fields
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setString parses s into v. Lists are comma separated.
+func setString(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		v.SetFloat(n)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", s)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var values []string
+		for _, value := range strings.Split(s, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		v.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets in printed configurations
+const redacted = "REDACTED"
+
+// Redacted returns a copy of c with every secret that is set replaced
+func (c *Config) Redacted() *Config {
+	copied := *c
+	for _, f := range fieldsOf(&copied) {
+		if f.secret && f.value.Kind() == reflect.String && f.value.String() != "" {
+			f.value.SetString(redacted)
+		}
+	}
+	return &copied
+}
+
+// Print writes c as YAML, with its secrets redacted. Once the secrets are
+// set again, the output can be used as a config file.
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
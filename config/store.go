@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Status describes the active configuration
+type Status struct {
+	// Version starts at 1 and grows with every reload that changes a setting
+	Version  int64     `json:"version"`
+	LoadedAt time.Time `json:"loaded_at"`
+	// Checksum is the SHA-256 of the printed configuration, so instances can
+	// be compared without exposing it
+	Checksum string `json:"checksum"`
+	// PendingRestart lists the changed settings that only apply on restart
+	PendingRestart []string `json:"pending_restart,omitempty"`
+	// LastError is set when the last reload failed, and the previous
+	// configuration was kept
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Store holds the active configuration. Reload applies the settings tagged
+// reload from the sources again and notifies the subscribers; the others
+// keep their value until the next restart.
+type Store struct {
+	loader  *Loader
+	current atomic.Pointer[Config]
+	status  atomic.Pointer[Status]
+	// file is the state of the config file when it was first loaded
+	file fileState
+	// hup receives SIGHUP once ReloadOnSIGHUP is called
+	hup chan os.Signal
+
+	// mu serializes reloads and guards subscribers
+	mu          sync.Mutex
+	subscribers []func(*Config)
+}
+
+// NewStore loads the configuration with loader
+func NewStore(loader *Loader) (*Store, error) {
+	// Taken first, so edits made while loading are reloaded by Watch
+	file := stat(loader.File())
+	cfg, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{loader: loader, file: file}
+	s.current.Store(cfg)
+	s.status.Store(&Status{Version: 1, LoadedAt: time.Now(), Checksum: checksum(cfg)})
+	return s, nil
+}
+
+// Current returns the active configuration. It must not be modified.
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// Status returns the version of the active configuration and the outcome
+// of the last reload
+func (s *Store) Status() Status {
+	return *s.status.Load()
+}
+
+// Subscribe calls fn with the new configuration after every reload that
+// changes it. Calls are not concurrent, and fn must not call Reload or
+// Subscribe.
+func (s *Store) Subscribe(fn func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers = append(s.subscribers, fn)
+}
+
+// Reload reads the configuration again. When it is invalid the active one is
+// kept and the error is reported in Status.
+func (s *Store) Reload() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.Status()
+	next, err := s.loader.Load()
+	if err != nil {
+		now := time.Now()
+		status.LastError, status.LastErrorAt = err.Error(), &now
+		s.status.Store(&status)
+		return err
+	}
+
+	cfg, pending := merge(s.Current(), next)
+	status.PendingRestart, status.LastError, status.LastErrorAt = pending, "", nil
+	for _, path := range pending {
+		slog.Warn("Config setting changed but needs a restart", "setting", path)
+	}
+
+	if sum := checksum(cfg); sum != status.Checksum {
+		status.Version++
+		status.LoadedAt = time.Now()
+		status.Checksum = sum
+		s.current.Store(cfg)
+		for _, fn := range s.subscribers {
+			fn(cfg)
+		}
+	}
+	s.status.Store(&status)
+	return nil
+}
+
+// ReloadOnSIGHUP replaces the default action of SIGHUP, which kills the
+// process, with a reload by Watch. Call it before starting the workers:
+// signals that arrive before Watch runs are kept until it does.
+func (s *Store) ReloadOnSIGHUP() {
+	s.hup = make(chan os.Signal, 1)
+	signal.Notify(s.hup, syscall.SIGHUP)
+}
+
+// Watch reloads on SIGHUP, when ReloadOnSIGHUP was called, and whenever the
+// config file changes, checked every interval, until ctx is done
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	file, last := s.loader.File(), s.file
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.hup:
+			s.reload("signal")
+		case <-ticker.C:
+			if file == "" {
+				continue
+			}
+			if current := stat(file); current != last {
+				last = current
+				s.reload("file change")
+			}
+		}
+	}
+}
+
+// reload reloads and logs the outcome
+func (s *Store) reload(trigger string) {
+	if err := s.Reload(); err != nil {
+		slog.Error("Config reload failed, keeping the active config", "trigger", trigger, "error", err)
+		return
+	}
+	slog.Info("Config reloaded", "trigger", trigger, "version", s.Status().Version)
+}
+
+// fileState identifies a version of a file well enough to notice edits
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// stat returns the state of path, or the zero state when it can't be read
+func stat(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}
+}
+
+// merge returns a copy of current with the reloadable settings of next, and
+// the paths of the other settings that differ
+func merge(current, next *Config) (*Config, []string) {
+	merged := *current
+	nextFields := fieldsOf(next)
+
+	var pending []string
+	for i, f := range fieldsOf(&merged) {
+		value := nextFields[i].value
+		switch {
+		case f.reload:
+			f.value.Set(value)
+		case !reflect.DeepEqual(f.value.Interface(), value.Interface()):
+			pending = append(pending, f.path)
+		}
+	}
+	return &merged, pending
+}
+
+// checksum hashes the printed configuration. Secrets are redacted first, so
+// the checksum reveals nothing about them.
+func checksum(cfg *Config) string {
+	var buf bytes.Buffer
+	cfg.Print(&buf)
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T, file string) *Store {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	loader, err := NewLoader(flags, []string{"--config", file})
+	require.NoError(t, err)
+	store, err := NewStore(loader)
+	require.NoError(t, err)
+	return store
+}
+
+func TestStoreReload(t *testing.T) {
+	file := writeFile(t, "config.yaml", `
+server:
+  addr: ":9000"
+db:
+  dsn: "`+testDSN+`"
+`)
+	store := newStore(t, file)
+	initial := store.Status()
+	assert.Equal(t, int64(1), initial.Version)
+
+	var notified []*Config
+	store.Subscribe(func(cfg *Config) { notified = append(notified, cfg) })
+
+	t.Run("unchanged file keeps the version", func(t *testing.T) {
+		require.NoError(t, store.Reload())
+		assert.Equal(t, initial.Version, store.Status().Version)
+		assert.Empty(t, notified)
+	})
+
+	t.Run("reloadable settings are applied", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(file, []byte(`
+server:
+  addr: ":9001"
+  ws_allowed_origins: [https://app.example.com]
+db:
+  dsn: "`+testDSN+`"
+  max_open_conns: 5
+log:
+  level: debug
+`), 0o600))
+
+		require.NoError(t, store.Reload())
+		cfg := store.Current()
+		assert.Equal(t, "debug", cfg.Log.Level)
+		assert.Equal(t, 5, cfg.DB.MaxOpenConns)
+		assert.Equal(t, []string{"https://app.example.com"}, cfg.Server.WSAllowedOrigins)
+		// The address needs a restart
+		assert.Equal(t, ":9000", cfg.Server.Addr)
+
+		status := store.Status()
+		assert.Equal(t, int64(2), status.Version)
+		assert.NotEqual(t, initial.Checksum, status.Checksum)
+		assert.Equal(t, []string{"server.addr"}, status.PendingRestart)
+		assert.Equal(t, []*Config{cfg}, notified)
+	})
+
+	t.Run("invalid config keeps the active one", func(t *testing.T) {
+		active := store.Current()
+		require.NoError(t, os.WriteFile(file, []byte("log:\n  level: loud\n"), 0o600))
+
+		assert.Error(t, store.Reload())
+		assert.Same(t, active, store.Current())
+		status := store.Status()
+		assert.Equal(t, int64(2), status.Version)
+		assert.Contains(t, status.LastError, "log.level")
+		assert.NotNil(t, status.LastErrorAt)
+		assert.Len(t, notified, 1)
+	})
+}
+
+func TestStoreWatch(t *testing.T) {
+	file := writeFile(t, "config.yaml", "db:\n  dsn: \""+testDSN+"\"\n")
+	store := newStore(t, file)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		store.Watch(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  dsn: \""+testDSN+"\"\nlog:\n  level: error\n"), 0o600))
+	assert.Eventually(t, func() bool {
+		return store.Current().Log.Level == "error"
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestStoreReloadOnSIGHUP(t *testing.T) {
+	file := writeFile(t, "config.yaml", "db:\n  dsn: \""+testDSN+"\"\n")
+	store := newStore(t, file)
+	store.ReloadOnSIGHUP()
+	defer signal.Stop(store.hup)
+
+	// Sent before Watch runs, it must neither kill the process nor be lost
+	require.NoError(t, os.WriteFile(file, []byte("db:\n  dsn: \""+testDSN+"\"\nlog:\n  level: error\n"), 0o600))
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, process.Signal(syscall.SIGHUP))
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		// The file is not checked again during the test
+		store.Watch(ctx, time.Hour)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return store.Current().Log.Level == "error"
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Validate reports every invalid setting, one per line, named by its path
+// in the config file
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, path, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	for _, f := range fieldsOf(c) {
+		if f.value.Type() == durationType {
+			check(f.value.Int() >= 0, f.path, "must not be negative")
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr", "is required")
+	if c.Server.PublicBaseURL != "" {
+		u, err := url.Parse(c.Server.PublicBaseURL)
+		check(err == nil && u.Scheme != "" && u.Host != "", "server.public_base_url", "must be an absolute URL")
+	}
+
+	check(c.DB.DSN != "", "db.dsn", "is required")
+	if c.DB.DSN != "" {
+		_, err := mysql.ParseDSN(c.DB.DSN)
+		check(err == nil, "db.dsn", "is not a valid MySQL DSN")
+	}
+	check(c.DB.MaxOpenConns >= 0, "db.max_open_conns", "must not be negative")
+	check(c.DB.MaxIdleConns >= 0, "db.max_idle_conns", "must not be negative")
+
+	switch c.Auth.Provider {
+	case AuthProviderFirebase:
+		check(c.Auth.Firebase.CredentialsFile != "", "auth.firebase.credentials_file", "is required by the firebase provider")
+	case AuthProviderLocal:
+		check(c.Auth.Local.Issuer != "", "auth.local.issuer", "is required by the local provider")
+		check(c.Auth.Local.TokenTTL > 0, "auth.local.token_ttl", "must be positive")
+		check(c.Auth.Local.RefreshTTL > 0, "auth.local.refresh_ttl", "must be positive")
+		check(c.Auth.Local.KeyRotation > 0, "auth.local.key_rotation", "must be positive")
+	default:
+		check(false, "auth.provider", "must be %q or %q, got %q", AuthProviderFirebase, AuthProviderLocal, c.Auth.Provider)
+	}
+
+	check(c.Mail.From != "", "mail.from", "is required")
+	check(c.Mail.DefaultLocale != "", "mail.default_locale", "is required")
+	switch c.Mail.Driver {
+	case MailDriverSMTP:
+		check(c.Mail.SMTP.Host != "", "mail.smtp.host", "is required by the smtp driver")
+		check(c.Mail.SMTP.Port > 0 && c.Mail.SMTP.Port <= 65535, "mail.smtp.port", "must be between 1 and 65535")
+	case MailDriverFile:
+		check(c.Mail.Dir != "", "mail.dir", "is required by the file driver")
+	default:
+		check(false, "mail.driver", "must be %q or %q, got %q", MailDriverSMTP, MailDriverFile, c.Mail.Driver)
+	}
+
+	if c.Push.FCMProjectID != "" {
+		check(c.Push.FCMEndpoint != "", "push.fcm_endpoint", "is required when push notifications are enabled")
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		check(c.Tracing.OTLPEndpoint != "", "tracing.otlp_endpoint", "is required by the otlp exporter")
+	default:
+		check(false, "tracing.exporter", "must be \"none\", \"stdout\" or \"otlp\", got %q", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1")
+
+	check(c.Log.Format == "text" || c.Log.Format == "json", "log.format", "must be \"text\" or \"json\", got %q", c.Log.Format)
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level", "must be \"debug\", \"info\", \"warn\" or \"error\", got %q", c.Log.Level)
+
+	return errors.Join(errs...)
+}
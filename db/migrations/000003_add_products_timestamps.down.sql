@@ -0,0 +1,4 @@
+ALTER TABLE products
+    DROP INDEX idx_products_updated_at,
+    DROP COLUMN updated_at,
+    DROP COLUMN created_at;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE users
+    DROP COLUMN disabled;
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE users
+    DROP FOREIGN KEY fk_users_role;
+
+DROP TABLE IF EXISTS role_permissions;
+
+DROP TABLE IF EXISTS permissions;
+
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,44 @@
+CREATE TABLE
+    IF NOT EXISTS roles (
+        name VARCHAR(50) PRIMARY KEY,
+        description VARCHAR(255) NOT NULL DEFAULT ''
+    );
+
+CREATE TABLE
+    IF NOT EXISTS permissions (
+        name VARCHAR(100) PRIMARY KEY,
+        description VARCHAR(255) NOT NULL DEFAULT ''
+    );
+
+CREATE TABLE
+    IF NOT EXISTS role_permissions (
+        role VARCHAR(50) NOT NULL,
+        permission VARCHAR(100) NOT NULL,
+        PRIMARY KEY (role, permission),
+        CONSTRAINT fk_role_permissions_role FOREIGN KEY (role) REFERENCES roles (name) ON DELETE CASCADE ON UPDATE CASCADE,
+        CONSTRAINT fk_role_permissions_permission FOREIGN KEY (permission) REFERENCES permissions (name) ON DELETE CASCADE
+    );
+
+INSERT INTO
+    roles (name, description)
+VALUES
+    ('admin', 'Full access to the API'),
+    ('user', 'Regular customer');
+
+INSERT IGNORE INTO roles (name)
+SELECT DISTINCT role FROM users;
+
+INSERT INTO
+    permissions (name, description)
+VALUES
+    ('products:write', 'Create, update and delete products'),
+    ('users:read', 'Read any user'),
+    ('users:write', 'Create, update and delete users'),
+    ('roles:manage', 'Manage roles, permissions and role assignments');
+
+INSERT INTO
+    role_permissions (role, permission)
+SELECT 'admin', name FROM permissions;
+
+ALTER TABLE users
+    ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles (name) ON UPDATE CASCADE;
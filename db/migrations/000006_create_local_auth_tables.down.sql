@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE name = 'auth:manage';
+
+DROP TABLE IF EXISTS signing_keys;
+
+DROP TABLE IF EXISTS refresh_tokens;
+
+DROP TABLE IF EXISTS credentials;
@@ -0,0 +1,44 @@
+CREATE TABLE
+    IF NOT EXISTS credentials (
+        uid VARCHAR(36) PRIMARY KEY,
+        email VARCHAR(100) UNIQUE NOT NULL,
+        display_name VARCHAR(100) NOT NULL DEFAULT '',
+        password_hash VARBINARY(255) NOT NULL,
+        email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+        disabled BOOLEAN NOT NULL DEFAULT FALSE,
+        custom_claims JSON NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    IF NOT EXISTS refresh_tokens (
+        id VARCHAR(36) PRIMARY KEY,
+        uid VARCHAR(36) NOT NULL,
+        family_id VARCHAR(36) NOT NULL,
+        token_hash CHAR(64) UNIQUE NOT NULL,
+        expires_at TIMESTAMP NOT NULL,
+        revoked_at TIMESTAMP NULL,
+        replaced_by VARCHAR(36) NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        INDEX idx_refresh_tokens_family (family_id),
+        INDEX idx_refresh_tokens_uid (uid)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS signing_keys (
+        id VARCHAR(36) PRIMARY KEY,
+        private_key TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        retired_at TIMESTAMP NULL
+    );
+
+INSERT INTO
+    permissions (name, description)
+VALUES
+    ('auth:manage', 'Rotate token signing keys');
+
+INSERT INTO
+    role_permissions (role, permission)
+VALUES
+    ('admin', 'auth:manage');
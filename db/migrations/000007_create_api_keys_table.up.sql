@@ -0,0 +1,15 @@
+CREATE TABLE
+    IF NOT EXISTS api_keys (
+        id VARCHAR(36) PRIMARY KEY,
+        user_id VARCHAR(36) NOT NULL,
+        name VARCHAR(100) NOT NULL,
+        prefix VARCHAR(16) UNIQUE NOT NULL,
+        secret_hash CHAR(64) NOT NULL,
+        scopes JSON NOT NULL,
+        expires_at TIMESTAMP NULL,
+        last_used_at TIMESTAMP NULL,
+        revoked_at TIMESTAMP NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        INDEX idx_api_keys_user (user_id),
+        CONSTRAINT fk_api_keys_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
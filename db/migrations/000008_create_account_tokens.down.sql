@@ -0,0 +1,9 @@
+DELETE FROM permissions WHERE name = 'checkout';
+
+ALTER TABLE permissions
+    DROP COLUMN requires_verified_email;
+
+DROP TABLE IF EXISTS user_tokens;
+
+ALTER TABLE users
+    DROP COLUMN email_verified;
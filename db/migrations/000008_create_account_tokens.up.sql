@@ -0,0 +1,28 @@
+ALTER TABLE users
+    ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE
+    IF NOT EXISTS user_tokens (
+        id VARCHAR(36) PRIMARY KEY,
+        user_id VARCHAR(36) NOT NULL,
+        purpose VARCHAR(32) NOT NULL,
+        expires_at TIMESTAMP NOT NULL,
+        used_at TIMESTAMP NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        INDEX idx_user_tokens_user (user_id, purpose),
+        CONSTRAINT fk_user_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+ALTER TABLE permissions
+    ADD COLUMN requires_verified_email BOOLEAN NOT NULL DEFAULT FALSE;
+
+INSERT INTO
+    permissions (name, description, requires_verified_email)
+VALUES
+    ('checkout', 'Place orders', TRUE);
+
+INSERT INTO
+    role_permissions (role, permission)
+VALUES
+    ('admin', 'checkout'),
+    ('user', 'checkout');
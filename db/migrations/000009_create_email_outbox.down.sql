@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_outbox;
+
+ALTER TABLE users
+    DROP COLUMN locale;
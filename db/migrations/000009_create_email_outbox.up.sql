@@ -0,0 +1,18 @@
+ALTER TABLE users
+    ADD COLUMN locale VARCHAR(10) NOT NULL DEFAULT '';
+
+CREATE TABLE
+    IF NOT EXISTS email_outbox (
+        id BIGINT AUTO_INCREMENT PRIMARY KEY,
+        recipient VARCHAR(255) NOT NULL,
+        subject VARCHAR(255) NOT NULL,
+        text_body TEXT NOT NULL,
+        html_body MEDIUMTEXT NOT NULL,
+        status VARCHAR(16) NOT NULL DEFAULT 'pending',
+        attempts INT NOT NULL DEFAULT 0,
+        next_attempt_at TIMESTAMP NOT NULL,
+        last_error TEXT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        sent_at TIMESTAMP NULL,
+        INDEX idx_email_outbox_due (status, next_attempt_at)
+    );
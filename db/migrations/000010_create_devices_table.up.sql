@@ -0,0 +1,21 @@
+CREATE TABLE
+    IF NOT EXISTS devices (
+        id BIGINT AUTO_INCREMENT PRIMARY KEY,
+        user_id VARCHAR(36) NOT NULL,
+        platform VARCHAR(16) NOT NULL,
+        token VARCHAR(512) NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE KEY uq_devices_token (token),
+        INDEX idx_devices_user (user_id),
+        CONSTRAINT fk_devices_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
+
+CREATE TABLE
+    IF NOT EXISTS notification_preferences (
+        user_id VARCHAR(36) PRIMARY KEY,
+        orders BOOLEAN NOT NULL DEFAULT TRUE,
+        price_drops BOOLEAN NOT NULL DEFAULT TRUE,
+        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+        CONSTRAINT fk_notification_preferences_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+    );
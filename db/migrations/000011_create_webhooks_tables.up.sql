@@ -0,0 +1,49 @@
+CREATE TABLE
+    IF NOT EXISTS events_outbox (
+        id BIGINT AUTO_INCREMENT PRIMARY KEY,
+        event_type VARCHAR(64) NOT NULL,
+        payload JSON NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        dispatched_at TIMESTAMP NULL,
+        INDEX idx_events_outbox_pending (dispatched_at, id)
+    );
+
+CREATE TABLE
+    IF NOT EXISTS webhooks (
+        id VARCHAR(36) PRIMARY KEY,
+        url VARCHAR(2048) NOT NULL,
+        events JSON NOT NULL,
+        secret VARCHAR(64) NOT NULL,
+        active BOOLEAN NOT NULL DEFAULT TRUE,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE
+    IF NOT EXISTS webhook_deliveries (
+        id BIGINT AUTO_INCREMENT PRIMARY KEY,
+        webhook_id VARCHAR(36) NOT NULL,
+        event_id BIGINT NOT NULL,
+        event_type VARCHAR(64) NOT NULL,
+        payload JSON NOT NULL,
+        status VARCHAR(16) NOT NULL DEFAULT 'pending',
+        attempts INT NOT NULL DEFAULT 0,
+        next_attempt_at TIMESTAMP NOT NULL,
+        last_status_code INT NULL,
+        last_error TEXT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        delivered_at TIMESTAMP NULL,
+        UNIQUE KEY uq_webhook_deliveries_event (webhook_id, event_id),
+        INDEX idx_webhook_deliveries_due (status, next_attempt_at),
+        CONSTRAINT fk_webhook_deliveries_webhook FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE
+    );
+
+INSERT INTO
+    permissions (name, description)
+VALUES
+    ('webhooks:manage', 'Manage webhook subscriptions');
+
+INSERT INTO
+    role_permissions (role, permission)
+VALUES
+    ('admin', 'webhooks:manage');
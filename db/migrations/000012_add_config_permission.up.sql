@@ -0,0 +1,9 @@
+INSERT INTO
+    permissions (name, description)
+VALUES
+    ('config:read', 'Read the active configuration version');
+
+INSERT INTO
+    role_permissions (role, permission)
+VALUES
+    ('admin', 'config:read');
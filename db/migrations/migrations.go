@@ -0,0 +1,9 @@
+package migrations
+
+import "embed"
+
+// FS holds the SQL migrations, named <version>_<title>.<up|down>.sql, so the
+// binary applies them wherever it runs from
+//
+//go:embed *.sql
+var FS embed.FS
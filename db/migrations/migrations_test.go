@@ -0,0 +1,125 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+func TestMigrationsArePaired(t *testing.T) {
+	names, err := fs.Glob(FS, "*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, names)
+
+	// Titles and directions of each version
+	titles := map[uint64]string{}
+	directions := map[uint64][]string{}
+	for _, name := range names {
+		match := migrationName.FindStringSubmatch(name)
+		if !assert.NotNil(t, match, "%s is not named <version>_<title>.<up|down>.sql", name) {
+			continue
+		}
+		version, _ := strconv.ParseUint(match[1], 10, 64)
+		if title, ok := titles[version]; ok {
+			assert.Equal(t, title, match[2], "version %d has two titles", version)
+		}
+		titles[version] = match[2]
+		directions[version] = append(directions[version], match[3])
+	}
+
+	for version, found := range directions {
+		assert.ElementsMatch(t, []string{"up", "down"}, found, "migration %d_%s", version, titles[version])
+	}
+}
+
+// TestMigrationsRoundTrip applies each migration, rolls it back and applies
+// it again, checking that the schema matches each time. Then it rolls back
+// everything and applies it again. It needs an empty MySQL database, e.g.
+// TEST_MYSQL_DSN="root:rootpassword@tcp(localhost:3306)/go_web_test".
+func TestMigrationsRoundTrip(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	require.NoError(t, err)
+	cfg.MultiStatements = true
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	require.NoError(t, err)
+	defer db.Close()
+
+	src, err := iofs.New(FS, ".")
+	require.NoError(t, err)
+	driver, err := mysql.WithInstance(db, &mysql.Config{})
+	require.NoError(t, err)
+	m, err := migrate.NewWithInstance("iofs", src, "mysql", driver)
+	require.NoError(t, err)
+
+	_, _, err = m.Version()
+	require.ErrorIs(t, err, migrate.ErrNilVersion, "the database must be empty")
+	t.Cleanup(func() {
+		if err := m.Down(); err != nil {
+			t.Logf("rolling back: %v", err)
+		}
+	})
+
+	empty := schemaOf(t, db)
+	before := empty
+	for version, err := src.First(); err == nil; version, err = src.Next(version) {
+		require.NoError(t, m.Steps(1), "applying %d", version)
+		after := schemaOf(t, db)
+
+		require.NoError(t, m.Steps(-1), "rolling back %d", version)
+		require.Equal(t, before, schemaOf(t, db), "rolling back %d leaves a different schema", version)
+
+		require.NoError(t, m.Steps(1), "applying %d again", version)
+		require.Equal(t, after, schemaOf(t, db), "applying %d again gives a different schema", version)
+		before = after
+	}
+
+	require.NoError(t, m.Down())
+	assert.Equal(t, empty, schemaOf(t, db), "rolling back everything leaves tables or rows")
+	require.NoError(t, m.Up())
+	assert.Equal(t, before, schemaOf(t, db), "applying everything again gives a different schema")
+}
+
+var autoIncrement = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+
+// schemaOf describes every table, but the migration version, by its
+// definition and its number of rows
+func schemaOf(t *testing.T, db *sql.DB) map[string]string {
+	t.Helper()
+	rows, err := db.Query("SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name <> 'schema_migrations'")
+	require.NoError(t, err)
+	var tables []string
+	for rows.Next() {
+		var table string
+		require.NoError(t, rows.Scan(&table))
+		tables = append(tables, table)
+	}
+	require.NoError(t, rows.Err())
+	rows.Close()
+
+	schema := map[string]string{}
+	for _, table := range tables {
+		var name, definition string
+		var count int
+		require.NoError(t, db.QueryRow("SHOW CREATE TABLE `"+table+"`").Scan(&name, &definition))
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM `"+table+"`").Scan(&count))
+		schema[table] = fmt.Sprintf("%s\nrows: %d", autoIncrement.ReplaceAllString(definition, ""), count)
+	}
+	return schema
+}
@@ -0,0 +1,55 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+)
+
+type TokenRepository interface {
+	Create(ctx context.Context, t *domain.UserToken) error
+	// Consume marks an unexpired token as used and reports whether it was
+	// still available
+	Consume(ctx context.Context, id, userID, purpose string, at time.Time) (bool, error)
+}
+
+type tokenRepository struct {
+	DB       *sql.DB
+	timeouts database.Timeouts
+}
+
+func NewTokenRepository(db *sql.DB, timeouts database.Timeouts) TokenRepository {
+	return &tokenRepository{DB: db, timeouts: timeouts}
+}
+
+func (r *tokenRepository) Create(ctx context.Context, t *domain.UserToken) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "INSERT INTO user_tokens (id, user_id, purpose, expires_at) VALUES (?, ?, ?, ?)"
+	_, err := r.DB.ExecContext(ctx, query, t.ID, t.UserID, t.Purpose, t.ExpiresAt)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *tokenRepository) Consume(ctx context.Context, id, userID, purpose string, at time.Time) (bool, error) {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE user_tokens SET used_at = ? WHERE id = ? AND user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?"
+	result, err := r.DB.ExecContext(ctx, query, at, id, userID, purpose, at)
+	if err != nil {
+		return false, database.Translate(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, database.Translate(err)
+	}
+	return rows == 1, nil
+}
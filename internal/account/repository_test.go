@@ -0,0 +1,56 @@
+package account
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCreateToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewTokenRepository(db, database.Timeouts{})
+	token := &domain.UserToken{ID: "t1", UserID: "u1", Purpose: domain.TokenPurposeVerifyEmail, ExpiresAt: time.Now()}
+
+	mock.ExpectExec("INSERT INTO user_tokens").WithArgs(token.ID, token.UserID, token.Purpose, token.ExpiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Create(context.Background(), token))
+}
+
+func TestRepositoryConsumeToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewTokenRepository(db, database.Timeouts{})
+	now := time.Now()
+
+	t.Run("consumed", func(t *testing.T) {
+		mock.ExpectExec("UPDATE user_tokens SET used_at = \\? WHERE id = \\? AND user_id = \\? AND purpose = \\? AND used_at IS NULL AND expires_at > \\?").
+			WithArgs(now, "t1", "u1", domain.TokenPurposeResetPassword, now).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		consumed, err := repo.Consume(context.Background(), "t1", "u1", domain.TokenPurposeResetPassword, now)
+		assert.NoError(t, err)
+		assert.True(t, consumed)
+	})
+
+	t.Run("already used", func(t *testing.T) {
+		mock.ExpectExec("UPDATE user_tokens SET used_at").
+			WithArgs(now, "t1", "u1", domain.TokenPurposeResetPassword, now).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		consumed, err := repo.Consume(context.Background(), "t1", "u1", domain.TokenPurposeResetPassword, now)
+		assert.NoError(t, err)
+		assert.False(t, consumed)
+	})
+}
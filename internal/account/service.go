@@ -0,0 +1,231 @@
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/notification"
+	"github.com/Jacobo0312/go-web/internal/user"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+)
+
+// MinPasswordLength is the shortest password accepted on reset
+const MinPasswordLength = 8
+
+var (
+	// ErrEmailAlreadyVerified is returned when asking to verify a verified address
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	// ErrPasswordTooShort is returned when the new password is too short
+	ErrPasswordTooShort = fmt.Errorf("password must have at least %d characters", MinPasswordLength)
+)
+
+// AccountService interface
+type AccountService interface {
+	SendVerificationEmail(ctx context.Context, userID string) error
+	ConfirmEmail(ctx context.Context, token string) (*domain.User, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+// SessionRevoker signs a user out of every session. The local identity
+// provider implements it with its refresh tokens.
+type SessionRevoker interface {
+	RevokeUser(ctx context.Context, uid string) error
+}
+
+// Options configures the links and lifetime of the emailed tokens
+type Options struct {
+	PublicBaseURL    string
+	VerificationTTL  time.Duration
+	PasswordResetTTL time.Duration
+}
+
+type accountService struct {
+	repo     TokenRepository
+	userRepo user.UserRepository
+	provider identity.IdentityProvider
+	sessions SessionRevoker
+	signer   *TokenSigner
+	notifier notification.Notifier
+	options  Options
+}
+
+// NewAccountService return a new AccountService. sessions may be nil when the
+// identity provider revokes the sessions on a password change by itself, as
+// Firebase does.
+func NewAccountService(repo TokenRepository, userRepo user.UserRepository, provider identity.IdentityProvider, sessions SessionRevoker, signer *TokenSigner, notifier notification.Notifier, options Options) AccountService {
+	return &accountService{
+		repo:     repo,
+		userRepo: userRepo,
+		provider: provider,
+		sessions: sessions,
+		signer:   signer,
+		notifier: notifier,
+		options:  options,
+	}
+}
+
+// SendVerificationEmail email the user a link to verify its address
+func (s *accountService) SendVerificationEmail(ctx context.Context, userID string) error {
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if u.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.issue(ctx, u, domain.TokenPurposeVerifyEmail, s.options.VerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	data := notification.LinkData{Name: u.Name, Link: s.link("/verify-email", token), ExpiresInHours: hours(s.options.VerificationTTL)}
+	return s.notifier.Notify(ctx, u.Email, u.Locale, notification.TemplateVerifyEmail, data)
+}
+
+// ConfirmEmail mark the address of the token owner as verified in the
+// identity provider and the users table
+func (s *accountService) ConfirmEmail(ctx context.Context, token string) (*domain.User, error) {
+	u, err := s.consume(ctx, token, domain.TokenPurposeVerifyEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	verified := true
+	if _, err := s.provider.UpdateUser(ctx, u.ID, &identity.UserToUpdate{EmailVerified: &verified}); err != nil {
+		logging.FromContext(ctx).Error("Error verifying email in identity provider", "error", err)
+		return nil, err
+	}
+
+	u.EmailVerified = true
+	if err := s.userRepo.Update(ctx, u); err != nil {
+		logging.FromContext(ctx).Error("Error verifying email", "error", err)
+		verified = false
+		if _, rbErr := s.provider.UpdateUser(ctx, u.ID, &identity.UserToUpdate{EmailVerified: &verified}); rbErr != nil {
+			logging.FromContext(ctx).Error("Error restoring user in identity provider", "error", rbErr)
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// RequestPasswordReset email a reset link when the address belongs to a user.
+// Unknown addresses are ignored so callers cannot probe for accounts.
+func (s *accountService) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := s.userRepo.FindByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if u.Disabled {
+		return nil
+	}
+
+	token, err := s.issue(ctx, u, domain.TokenPurposeResetPassword, s.options.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	data := notification.LinkData{Name: u.Name, Link: s.link("/reset-password", token), ExpiresInHours: hours(s.options.PasswordResetTTL)}
+	return s.notifier.Notify(ctx, u.Email, u.Locale, notification.TemplateResetPassword, data)
+}
+
+// ResetPassword set a new password for the token owner and signs it out of
+// every session
+func (s *accountService) ResetPassword(ctx context.Context, token, password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+
+	u, err := s.consume(ctx, token, domain.TokenPurposeResetPassword)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.provider.UpdateUser(ctx, u.ID, &identity.UserToUpdate{Password: &password}); err != nil {
+		return err
+	}
+
+	if s.sessions == nil {
+		return nil
+	}
+	if err := s.sessions.RevokeUser(ctx, u.ID); err != nil {
+		logging.FromContext(ctx).Error("Error revoking sessions after a password reset", "error", err)
+		return err
+	}
+	return nil
+}
+
+// issue stores a token row for u and returns its signed form
+func (s *accountService) issue(ctx context.Context, u *domain.User, purpose string, ttl time.Duration) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	t := &domain.UserToken{
+		ID:        hex.EncodeToString(b),
+		UserID:    u.ID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl).UTC().Truncate(time.Second),
+	}
+	if err := s.repo.Create(ctx, t); err != nil {
+		return "", err
+	}
+
+	return s.signer.Sign(tokenClaims{ID: t.ID, UserID: t.UserID, Purpose: t.Purpose, ExpiresAt: t.ExpiresAt, Email: u.Email}), nil
+}
+
+// consume verifies token, marks it as used and returns its owner. Tokens sent
+// to an address the owner no longer has are invalid.
+func (s *accountService) consume(ctx context.Context, token, purpose string) (*domain.User, error) {
+	claims, err := s.signer.Verify(token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.userRepo.FindByID(ctx, claims.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(u.Email, claims.Email) {
+		return nil, ErrInvalidToken
+	}
+
+	consumed, err := s.repo.Consume(ctx, claims.ID, claims.UserID, purpose, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidToken
+	}
+
+	return u, nil
+}
+
+func (s *accountService) link(path, token string) string {
+	return strings.TrimRight(s.options.PublicBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// hours rounds d up to whole hours for the email copy
+func hours(d time.Duration) int {
+	return int((d + time.Hour - 1) / time.Hour)
+}
@@ -0,0 +1,240 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/notification"
+	"github.com/Jacobo0312/go-web/internal/user"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryTokenRepository keeps the tokens in a map indexed by id
+type memoryTokenRepository struct {
+	tokens map[string]*domain.UserToken
+}
+
+func (r *memoryTokenRepository) Create(ctx context.Context, t *domain.UserToken) error {
+	stored := *t
+	r.tokens[t.ID] = &stored
+	return nil
+}
+
+func (r *memoryTokenRepository) Consume(ctx context.Context, id, userID, purpose string, at time.Time) (bool, error) {
+	t, ok := r.tokens[id]
+	if !ok || t.UserID != userID || t.Purpose != purpose || t.UsedAt != nil || !t.ExpiresAt.After(at) {
+		return false, nil
+	}
+	t.UsedAt = &at
+	return true, nil
+}
+
+// memoryUserRepository keeps the users in a map indexed by id
+type memoryUserRepository struct {
+	users map[string]domain.User
+}
+
+func (r *memoryUserRepository) Register(ctx context.Context, u *domain.User) error {
+	r.users[u.ID] = *u
+	return nil
+}
+
+func (r *memoryUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &u, nil
+}
+
+func (r *memoryUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	for _, u := range r.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryUserRepository) GetAll(ctx context.Context) ([]domain.User, error) {
+	users := []domain.User{}
+	for _, u := range r.users {
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, u *domain.User) error {
+	r.users[u.ID] = *u
+	return nil
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, id string) error {
+	delete(r.users, id)
+	return nil
+}
+
+func (r *memoryUserRepository) WithTx(tx database.DBTX) user.UserRepository {
+	return r
+}
+
+// recordingNotifier keeps the last notification
+type recordingNotifier struct {
+	to, template string
+	data         notification.LinkData
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, to, locale, template string, data interface{}) error {
+	n.to, n.template = to, template
+	n.data = data.(notification.LinkData)
+	return nil
+}
+
+func (n *recordingNotifier) token(t *testing.T) string {
+	link, err := url.Parse(n.data.Link)
+	if err != nil {
+		t.Fatalf("invalid link %q: %v", n.data.Link, err)
+	}
+	return link.Query().Get("token")
+}
+
+// recordingRevoker keeps the users signed out
+type recordingRevoker struct {
+	revoked []string
+}
+
+func (r *recordingRevoker) RevokeUser(ctx context.Context, uid string) error {
+	r.revoked = append(r.revoked, uid)
+	return nil
+}
+
+type accountTest struct {
+	service  AccountService
+	users    *memoryUserRepository
+	provider *identity.LocalProvider
+	notifier *recordingNotifier
+	sessions *recordingRevoker
+	user     *identity.User
+}
+
+func setupAccountTest(t *testing.T) *accountTest {
+	ctx := context.Background()
+	keys := identity.NewKeyManager(identity.NewMemoryKeyStore(), time.Hour, time.Hour)
+	provider := identity.NewLocalProvider(identity.NewMemoryCredentialStore(), keys, "test", time.Minute)
+
+	created, err := provider.CreateUser(ctx, &identity.UserToCreate{Email: "john@example.com", Password: "secret123"})
+	assert.NoError(t, err)
+
+	users := &memoryUserRepository{users: map[string]domain.User{
+		created.UID: {ID: created.UID, Name: "John", Email: "john@example.com", Role: "user"},
+	}}
+	notifier := &recordingNotifier{}
+	sessions := &recordingRevoker{}
+	service := NewAccountService(
+		&memoryTokenRepository{tokens: map[string]*domain.UserToken{}},
+		users,
+		provider,
+		sessions,
+		NewTokenSigner("test-secret"),
+		notifier,
+		Options{PublicBaseURL: "https://shop.example.com/", VerificationTTL: time.Hour, PasswordResetTTL: time.Hour},
+	)
+
+	return &accountTest{service: service, users: users, provider: provider, notifier: notifier, sessions: sessions, user: created}
+}
+
+func TestConfirmEmail(t *testing.T) {
+	ctx := context.Background()
+	at := setupAccountTest(t)
+
+	assert.NoError(t, at.service.SendVerificationEmail(ctx, at.user.UID))
+	assert.Equal(t, "john@example.com", at.notifier.to)
+	assert.Equal(t, notification.TemplateVerifyEmail, at.notifier.template)
+	assert.Contains(t, at.notifier.data.Link, "https://shop.example.com/verify-email?token=")
+	assert.Equal(t, 1, at.notifier.data.ExpiresInHours)
+	token := at.notifier.token(t)
+
+	assert.ErrorIs(t, at.service.ResetPassword(ctx, token, "new-password"), ErrInvalidToken, "a verification token cannot reset the password")
+
+	u, err := at.service.ConfirmEmail(ctx, token)
+	assert.NoError(t, err)
+	assert.True(t, u.EmailVerified)
+	assert.True(t, at.users.users[at.user.UID].EmailVerified)
+
+	authenticated, err := at.provider.Authenticate(ctx, "john@example.com", "secret123")
+	assert.NoError(t, err)
+	assert.True(t, authenticated.EmailVerified)
+
+	_, err = at.service.ConfirmEmail(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidToken, "tokens are single-use")
+
+	assert.ErrorIs(t, at.service.SendVerificationEmail(ctx, at.user.UID), ErrEmailAlreadyVerified)
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	at := setupAccountTest(t)
+
+	assert.NoError(t, at.service.RequestPasswordReset(ctx, "nobody@example.com"))
+	assert.Empty(t, at.notifier.to)
+
+	assert.NoError(t, at.service.RequestPasswordReset(ctx, "john@example.com"))
+	token := at.notifier.token(t)
+
+	assert.ErrorIs(t, at.service.ResetPassword(ctx, token, "short"), ErrPasswordTooShort)
+	assert.ErrorIs(t, at.service.ResetPassword(ctx, token+"x", "new-password"), ErrInvalidToken)
+
+	assert.Empty(t, at.sessions.revoked)
+	assert.NoError(t, at.service.ResetPassword(ctx, token, "new-password"))
+	_, err := at.provider.Authenticate(ctx, "john@example.com", "new-password")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{at.user.UID}, at.sessions.revoked, "the old sessions are signed out")
+
+	assert.ErrorIs(t, at.service.ResetPassword(ctx, token, "another-password"), ErrInvalidToken)
+}
+
+func TestTokensVoidAfterEmailChange(t *testing.T) {
+	ctx := context.Background()
+	at := setupAccountTest(t)
+
+	assert.NoError(t, at.service.SendVerificationEmail(ctx, at.user.UID))
+	verification := at.notifier.token(t)
+	assert.NoError(t, at.service.RequestPasswordReset(ctx, "john@example.com"))
+	reset := at.notifier.token(t)
+
+	u := at.users.users[at.user.UID]
+	u.Email = "johnny@example.com"
+	at.users.users[u.ID] = u
+
+	_, err := at.service.ConfirmEmail(ctx, verification)
+	assert.ErrorIs(t, err, ErrInvalidToken, "the link verified the old address")
+	assert.False(t, at.users.users[u.ID].EmailVerified)
+	assert.ErrorIs(t, at.service.ResetPassword(ctx, reset, "new-password"), ErrInvalidToken, "the link was sent to the old address")
+
+	assert.NoError(t, at.service.SendVerificationEmail(ctx, u.ID))
+	assert.Equal(t, "johnny@example.com", at.notifier.to)
+	_, err = at.service.ConfirmEmail(ctx, at.notifier.token(t))
+	assert.NoError(t, err)
+}
+
+func TestTokenSignerExpiry(t *testing.T) {
+	signer := NewTokenSigner("test-secret")
+
+	expired := signer.Sign(tokenClaims{ID: "t1", UserID: "u1", Purpose: domain.TokenPurposeVerifyEmail, ExpiresAt: time.Now().Add(-time.Minute)})
+	_, err := signer.Verify(expired, domain.TokenPurposeVerifyEmail)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	valid := signer.Sign(tokenClaims{ID: "t1", UserID: "u1", Purpose: domain.TokenPurposeVerifyEmail, ExpiresAt: time.Now().Add(time.Minute), Email: `"a:b"@example.com`})
+	_, err = NewTokenSigner("other-secret").Verify(valid, domain.TokenPurposeVerifyEmail)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	claims, err := signer.Verify(valid, domain.TokenPurposeVerifyEmail)
+	assert.NoError(t, err)
+	assert.Equal(t, `"a:b"@example.com`, claims.Email, "emails may contain colons")
+}
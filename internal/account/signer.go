@@ -0,0 +1,98 @@
+package account
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, tampered with,
+// expired, already used or issued for another purpose
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// tokenClaims is the signed payload of an account token
+type tokenClaims struct {
+	ID        string
+	UserID    string
+	Purpose   string
+	ExpiresAt time.Time
+	// Email is the address the token was sent to. The token is void once the
+	// user changes it.
+	Email string
+}
+
+// TokenSigner signs account tokens with HMAC-SHA256
+type TokenSigner struct {
+	secret []byte
+}
+
+// NewTokenSigner return a new TokenSigner. Without a secret a random one is
+// generated, so tokens do not survive a restart or work across instances.
+func NewTokenSigner(secret string) *TokenSigner {
+	if secret == "" {
+		slog.Warn("ACCOUNT_TOKEN_SECRET is not set, using a random secret")
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+		return &TokenSigner{secret: key}
+	}
+	return &TokenSigner{secret: []byte(secret)}
+}
+
+// Sign encodes the claims as purpose:id:userID:expiry:email followed by the
+// MAC. The email goes last since it may contain colons.
+func (s *TokenSigner) Sign(c tokenClaims) string {
+	payload := strings.Join([]string{c.Purpose, c.ID, c.UserID, strconv.FormatInt(c.ExpiresAt.Unix(), 10), c.Email}, ":")
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded))
+}
+
+// Verify checks the signature, purpose and expiry of token. Whether the token
+// was already used is up to the caller.
+func (s *TokenSigner) Verify(token, purpose string) (*tokenClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.mac(encoded)) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	parts := strings.SplitN(string(payload), ":", 5)
+	if len(parts) != 5 || parts[0] != purpose {
+		return nil, ErrInvalidToken
+	}
+
+	expiresAt, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	c := &tokenClaims{ID: parts[1], UserID: parts[2], Purpose: parts[0], ExpiresAt: time.Unix(expiresAt, 0), Email: parts[4]}
+	if time.Now().After(c.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	return c, nil
+}
+
+func (s *TokenSigner) mac(payload string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
@@ -0,0 +1,135 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, k *domain.APIKey) error
+	FindByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error)
+	GetByUser(ctx context.Context, userID string) ([]domain.APIKey, error)
+	// Revoke reports whether an active key was revoked
+	Revoke(ctx context.Context, userID, id string) (bool, error)
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	DB       *sql.DB
+	timeouts database.Timeouts
+}
+
+func NewAPIKeyRepository(db *sql.DB, timeouts database.Timeouts) APIKeyRepository {
+	return &apiKeyRepository{DB: db, timeouts: timeouts}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, k *domain.APIKey) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	scopes, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return database.Translate(err)
+	}
+
+	query := "INSERT INTO api_keys (id, user_id, name, prefix, secret_hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = r.DB.ExecContext(ctx, query, k.ID, k.UserID, k.Name, k.Prefix, k.SecretHash, scopes, k.ExpiresAt, k.CreatedAt)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) FindByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_keys WHERE prefix = ?"
+	row := r.DB.QueryRowContext(ctx, query, prefix)
+
+	return scanAPIKey(row)
+}
+
+func (r *apiKeyRepository) GetByUser(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, user_id, name, prefix, secret_hash, scopes, expires_at, last_used_at, revoked_at, created_at FROM api_keys WHERE user_id = ? AND revoked_at IS NULL ORDER BY created_at"
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	keys := []domain.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, database.Translate(err)
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, userID, id string) (bool, error) {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE api_keys SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL"
+	result, err := r.DB.ExecContext(ctx, query, time.Now().UTC(), id, userID)
+	if err != nil {
+		return false, database.Translate(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, database.Translate(err)
+	}
+	return rows == 1, nil
+}
+
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE api_keys SET last_used_at = ? WHERE id = ?"
+	_, err := r.DB.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAPIKey(row scanner) (*domain.APIKey, error) {
+	var k domain.APIKey
+	var scopes []byte
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.SecretHash, &scopes, &expiresAt, &lastUsedAt, &revokedAt, &k.CreatedAt)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	if err := json.Unmarshal(scopes, &k.Scopes); err != nil {
+		return nil, database.Translate(err)
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return &k, nil
+}
@@ -0,0 +1,83 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+var apiKeyColumns = []string{"id", "user_id", "name", "prefix", "secret_hash", "scopes", "expires_at", "last_used_at", "revoked_at", "created_at"}
+
+func TestRepositoryCreateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db, database.Timeouts{})
+	key := &domain.APIKey{ID: "k1", UserID: "u1", Name: "etl", Prefix: "abc", SecretHash: "hash", Scopes: []string{"products:write"}, CreatedAt: time.Now()}
+
+	mock.ExpectExec("INSERT INTO api_keys").
+		WithArgs(key.ID, key.UserID, key.Name, key.Prefix, key.SecretHash, []byte(`["products:write"]`), key.ExpiresAt, key.CreatedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Create(context.Background(), key))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryFindAPIKeyByPrefix(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db, database.Timeouts{})
+	now := time.Now()
+
+	rows := sqlmock.NewRows(apiKeyColumns).
+		AddRow("k1", "u1", "etl", "abc", "hash", []byte(`["products:write"]`), nil, now, nil, now)
+	mock.ExpectQuery("SELECT (.+) FROM api_keys WHERE prefix = \\?").WithArgs("abc").WillReturnRows(rows)
+
+	key, err := repo.FindByPrefix(context.Background(), "abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", key.UserID)
+	assert.Equal(t, []string{"products:write"}, key.Scopes)
+	assert.Nil(t, key.ExpiresAt)
+	assert.NotNil(t, key.LastUsedAt)
+	assert.Nil(t, key.RevokedAt)
+}
+
+func TestRepositoryRevokeAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewAPIKeyRepository(db, database.Timeouts{})
+
+	t.Run("revoked", func(t *testing.T) {
+		mock.ExpectExec("UPDATE api_keys SET revoked_at = \\? WHERE id = \\? AND user_id = \\? AND revoked_at IS NULL").
+			WithArgs(sqlmock.AnyArg(), "k1", "u1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		revoked, err := repo.Revoke(context.Background(), "u1", "k1")
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+
+	t.Run("not owned", func(t *testing.T) {
+		mock.ExpectExec("UPDATE api_keys SET revoked_at").
+			WithArgs(sqlmock.AnyArg(), "k1", "u2").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		revoked, err := repo.Revoke(context.Background(), "u2", "k1")
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+}
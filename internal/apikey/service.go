@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+)
+
+const (
+	// keyPrefix identifies our keys in logs and secret scanners
+	keyPrefix = "gwk"
+	// lastUsedResolution limits how often last_used_at is written
+	lastUsedResolution = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey is returned for malformed, unknown, expired or revoked keys
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyNotFound is returned when revoking a key the user does not own
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrInvalidScopes is returned when a key is requested without scopes
+	ErrInvalidScopes = errors.New("api key needs at least one scope")
+)
+
+// APIKeyService interface
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, userID string, request *domain.CreateAPIKeyRequest) (*domain.CreatedAPIKey, error)
+	GetAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id string) error
+	VerifyAPIKey(ctx context.Context, key string) (string, []string, error)
+}
+
+type apiKeyService struct {
+	repo APIKeyRepository
+}
+
+// NewAPIKeyService return a new APIKeyService
+func NewAPIKeyService(repo APIKeyRepository) APIKeyService {
+	return &apiKeyService{repo: repo}
+}
+
+// CreateAPIKey generate a key for the user. The full key is only returned here.
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, userID string, request *domain.CreateAPIKeyRequest) (*domain.CreatedAPIKey, error) {
+	if len(request.Scopes) == 0 {
+		return nil, ErrInvalidScopes
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := randomHex(6)
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+
+	k := domain.APIKey{
+		ID:         id,
+		UserID:     userID,
+		Name:       request.Name,
+		Prefix:     prefix,
+		SecretHash: hashSecret(encodedSecret),
+		Scopes:     request.Scopes,
+		ExpiresAt:  request.ExpiresAt,
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	if err := s.repo.Create(ctx, &k); err != nil {
+		return nil, err
+	}
+
+	return &domain.CreatedAPIKey{
+		APIKey: k,
+		Key:    fmt.Sprintf("%s_%s_%s", keyPrefix, prefix, encodedSecret),
+	}, nil
+}
+
+// GetAPIKeys return the active keys of the user
+func (s *apiKeyService) GetAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	return s.repo.GetByUser(ctx, userID)
+}
+
+// RevokeAPIKey revoke a key of the user
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	revoked, err := s.repo.Revoke(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// VerifyAPIKey return the owner and scopes of a valid key
+func (s *apiKeyService) VerifyAPIKey(ctx context.Context, key string) (string, []string, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix {
+		return "", nil, ErrInvalidAPIKey
+	}
+
+	k, err := s.repo.FindByPrefix(ctx, parts[1])
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(k.SecretHash), []byte(hashSecret(parts[2]))) != 1 {
+		return "", nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if k.RevokedAt != nil || (k.ExpiresAt != nil && now.After(*k.ExpiresAt)) {
+		return "", nil, ErrInvalidAPIKey
+	}
+
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) > lastUsedResolution {
+		if err := s.repo.TouchLastUsed(ctx, k.ID, now.UTC()); err != nil {
+			logging.FromContext(ctx).Error("Error updating api key last use", "error", err)
+		}
+	}
+
+	return k.UserID, k.Scopes, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,124 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryAPIKeyRepository keeps the keys in a map indexed by id
+type memoryAPIKeyRepository struct {
+	keys    map[string]*domain.APIKey
+	touches int
+}
+
+func newMemoryAPIKeyRepository() *memoryAPIKeyRepository {
+	return &memoryAPIKeyRepository{keys: map[string]*domain.APIKey{}}
+}
+
+func (r *memoryAPIKeyRepository) Create(ctx context.Context, k *domain.APIKey) error {
+	stored := *k
+	r.keys[k.ID] = &stored
+	return nil
+}
+
+func (r *memoryAPIKeyRepository) FindByPrefix(ctx context.Context, prefix string) (*domain.APIKey, error) {
+	for _, k := range r.keys {
+		if k.Prefix == prefix {
+			found := *k
+			return &found, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryAPIKeyRepository) GetByUser(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	keys := []domain.APIKey{}
+	for _, k := range r.keys {
+		if k.UserID == userID && k.RevokedAt == nil {
+			keys = append(keys, *k)
+		}
+	}
+	return keys, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(ctx context.Context, userID, id string) (bool, error) {
+	k, ok := r.keys[id]
+	if !ok || k.UserID != userID || k.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	k.RevokedAt = &now
+	return true, nil
+}
+
+func (r *memoryAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	r.touches++
+	r.keys[id].LastUsedAt = &at
+	return nil
+}
+
+func TestVerifyAPIKey(t *testing.T) {
+	repo := newMemoryAPIKeyRepository()
+	service := NewAPIKeyService(repo)
+	ctx := context.Background()
+
+	created, err := service.CreateAPIKey(context.Background(), "u1", &domain.CreateAPIKeyRequest{Name: "etl", Scopes: []string{"products:write"}})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Key, "gwk_"+created.Prefix+"_"))
+	assert.NotContains(t, repo.keys[created.ID].SecretHash, strings.Split(created.Key, "_")[2])
+
+	t.Run("valid key", func(t *testing.T) {
+		userID, scopes, err := service.VerifyAPIKey(ctx, created.Key)
+		assert.NoError(t, err)
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, []string{"products:write"}, scopes)
+		assert.NotNil(t, repo.keys[created.ID].LastUsedAt)
+	})
+
+	t.Run("last use is throttled", func(t *testing.T) {
+		touches := repo.touches
+		_, _, err := service.VerifyAPIKey(ctx, created.Key)
+		assert.NoError(t, err)
+		assert.Equal(t, touches, repo.touches)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		_, _, err := service.VerifyAPIKey(ctx, "gwk_"+created.Prefix+"_wrong")
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("malformed key", func(t *testing.T) {
+		_, _, err := service.VerifyAPIKey(ctx, "not-a-key")
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("expired key", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		expired, err := service.CreateAPIKey(context.Background(), "u1", &domain.CreateAPIKeyRequest{Name: "old", Scopes: []string{"users:read"}, ExpiresAt: &past})
+		assert.NoError(t, err)
+
+		_, _, err = service.VerifyAPIKey(ctx, expired.Key)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("revoked key", func(t *testing.T) {
+		assert.ErrorIs(t, service.RevokeAPIKey(context.Background(), "u2", created.ID), ErrAPIKeyNotFound)
+		assert.NoError(t, service.RevokeAPIKey(context.Background(), "u1", created.ID))
+
+		_, _, err := service.VerifyAPIKey(ctx, created.Key)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+}
+
+func TestCreateAPIKeyRequiresScopes(t *testing.T) {
+	service := NewAPIKeyService(newMemoryAPIKeyRepository())
+
+	_, err := service.CreateAPIKey(context.Background(), "u1", &domain.CreateAPIKeyRequest{Name: "etl"})
+	assert.ErrorIs(t, err, ErrInvalidScopes)
+}
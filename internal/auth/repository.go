@@ -0,0 +1,300 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/go-sql-driver/mysql"
+)
+
+const mysqlDuplicateEntry = 1062
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, t *domain.RefreshToken) error
+	FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
+	// Rotate marks the token as used and replaced by the given one, only if it
+	// was not revoked yet. It reports whether the token was rotated.
+	Rotate(ctx context.Context, id, replacedBy string) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeUser revokes every family of the user, signing it out everywhere
+	RevokeUser(ctx context.Context, uid string) error
+}
+
+type refreshTokenRepository struct {
+	DB       *sql.DB
+	timeouts database.Timeouts
+}
+
+func NewRefreshTokenRepository(db *sql.DB, timeouts database.Timeouts) RefreshTokenRepository {
+	return &refreshTokenRepository{DB: db, timeouts: timeouts}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, t *domain.RefreshToken) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "INSERT INTO refresh_tokens (id, uid, family_id, token_hash, expires_at) VALUES (?, ?, ?, ?, ?)"
+	_, err := r.DB.ExecContext(ctx, query, t.ID, t.UID, t.FamilyID, t.TokenHash, t.ExpiresAt)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, uid, family_id, token_hash, expires_at, revoked_at, replaced_by FROM refresh_tokens WHERE token_hash = ?"
+	row := r.DB.QueryRowContext(ctx, query, tokenHash)
+
+	var t domain.RefreshToken
+	var revokedAt sql.NullTime
+	var replacedBy sql.NullString
+	err := row.Scan(&t.ID, &t.UID, &t.FamilyID, &t.TokenHash, &t.ExpiresAt, &revokedAt, &replacedBy)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	if revokedAt.Valid {
+		t.RevokedAt = &revokedAt.Time
+	}
+	if replacedBy.Valid {
+		t.ReplacedBy = &replacedBy.String
+	}
+	return &t, nil
+}
+
+func (r *refreshTokenRepository) Rotate(ctx context.Context, id, replacedBy string) (bool, error) {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE refresh_tokens SET revoked_at = ?, replaced_by = ? WHERE id = ? AND revoked_at IS NULL"
+	result, err := r.DB.ExecContext(ctx, query, time.Now().UTC(), replacedBy, id)
+	if err != nil {
+		return false, database.Translate(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, database.Translate(err)
+	}
+	return rows == 1, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL"
+	_, err := r.DB.ExecContext(ctx, query, time.Now().UTC(), familyID)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeUser(ctx context.Context, uid string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE refresh_tokens SET revoked_at = ? WHERE uid = ? AND revoked_at IS NULL"
+	_, err := r.DB.ExecContext(ctx, query, time.Now().UTC(), uid)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+// credentialRepository implements identity.CredentialStore on the credentials table
+type credentialRepository struct {
+	DB       *sql.DB
+	timeouts database.Timeouts
+}
+
+func NewCredentialRepository(db *sql.DB, timeouts database.Timeouts) identity.CredentialStore {
+	return &credentialRepository{DB: db, timeouts: timeouts}
+}
+
+func (r *credentialRepository) Create(ctx context.Context, c *identity.Credential) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	claims, err := encodeClaims(c.Claims)
+	if err != nil {
+		return database.Translate(err)
+	}
+
+	query := "INSERT INTO credentials (uid, email, display_name, password_hash, email_verified, disabled, custom_claims) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	_, err = r.DB.ExecContext(ctx, query, c.UID, c.Email, c.DisplayName, c.PasswordHash, c.EmailVerified, c.Disabled, claims)
+	return translateCredentialError(err)
+}
+
+func (r *credentialRepository) Get(ctx context.Context, uid string) (*identity.Credential, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT uid, email, display_name, password_hash, email_verified, disabled, custom_claims FROM credentials WHERE uid = ?"
+	return r.scan(r.DB.QueryRowContext(ctx, query, uid))
+}
+
+func (r *credentialRepository) GetByEmail(ctx context.Context, email string) (*identity.Credential, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT uid, email, display_name, password_hash, email_verified, disabled, custom_claims FROM credentials WHERE email = ?"
+	return r.scan(r.DB.QueryRowContext(ctx, query, email))
+}
+
+func (r *credentialRepository) Update(ctx context.Context, c *identity.Credential) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	claims, err := encodeClaims(c.Claims)
+	if err != nil {
+		return database.Translate(err)
+	}
+
+	query := "UPDATE credentials SET email = ?, display_name = ?, password_hash = ?, email_verified = ?, disabled = ?, custom_claims = ? WHERE uid = ?"
+	_, err = r.DB.ExecContext(ctx, query, c.Email, c.DisplayName, c.PasswordHash, c.EmailVerified, c.Disabled, claims, c.UID)
+	return translateCredentialError(err)
+}
+
+func (r *credentialRepository) Delete(ctx context.Context, uid string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "DELETE FROM credentials WHERE uid = ?"
+	result, err := r.DB.ExecContext(ctx, query, uid)
+	if err != nil {
+		return database.Translate(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return database.Translate(err)
+	}
+	if rows == 0 {
+		return identity.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *credentialRepository) scan(row *sql.Row) (*identity.Credential, error) {
+	var c identity.Credential
+	var claims []byte
+	err := row.Scan(&c.UID, &c.Email, &c.DisplayName, &c.PasswordHash, &c.EmailVerified, &c.Disabled, &claims)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, identity.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	if len(claims) > 0 {
+		if err := json.Unmarshal(claims, &c.Claims); err != nil {
+			return nil, database.Translate(err)
+		}
+	}
+	return &c, nil
+}
+
+func encodeClaims(claims map[string]interface{}) ([]byte, error) {
+	if len(claims) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(claims)
+}
+
+func translateCredentialError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return identity.ErrEmailExists
+	}
+	return database.Translate(err)
+}
+
+// keyRepository implements identity.KeyStore on the signing_keys table
+type keyRepository struct {
+	DB       *sql.DB
+	timeouts database.Timeouts
+}
+
+func NewKeyRepository(db *sql.DB, timeouts database.Timeouts) identity.KeyStore {
+	return &keyRepository{DB: db, timeouts: timeouts}
+}
+
+func (r *keyRepository) ListKeys(ctx context.Context, since time.Time) ([]identity.SigningKey, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, private_key, created_at, retired_at FROM signing_keys WHERE retired_at IS NULL OR retired_at > ? ORDER BY created_at DESC"
+	rows, err := r.DB.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	var keys []identity.SigningKey
+	for rows.Next() {
+		var key identity.SigningKey
+		var privateKey string
+		var retiredAt sql.NullTime
+		if err := rows.Scan(&key.ID, &privateKey, &key.CreatedAt, &retiredAt); err != nil {
+			return nil, database.Translate(err)
+		}
+
+		block, _ := pem.Decode([]byte(privateKey))
+		if block == nil {
+			return nil, fmt.Errorf("signing key %s is not PEM encoded", key.ID)
+		}
+		key.PrivateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, database.Translate(err)
+		}
+
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *keyRepository) RotateKey(ctx context.Context, key *identity.SigningKey) error {
+	privateKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key.PrivateKey),
+	})
+
+	return database.NewTransactor(r.DB, r.timeouts).WithinTx(ctx, func(tx database.DBTX) error {
+		// Locks the active keys first, so a concurrent rotation waits for
+		// this one and then retires its key
+		rows, err := tx.QueryContext(ctx, "SELECT id FROM signing_keys WHERE retired_at IS NULL FOR UPDATE")
+		if err != nil {
+			return database.Translate(err)
+		}
+		if err := rows.Close(); err != nil {
+			return database.Translate(err)
+		}
+
+		query := "UPDATE signing_keys SET retired_at = ? WHERE retired_at IS NULL"
+		if _, err := tx.ExecContext(ctx, query, key.CreatedAt); err != nil {
+			return database.Translate(err)
+		}
+
+		query = "INSERT INTO signing_keys (id, private_key, created_at) VALUES (?, ?, ?)"
+		_, err = tx.ExecContext(ctx, query, key.ID, string(privateKey), key.CreatedAt)
+		return database.Translate(err)
+	})
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCreateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRefreshTokenRepository(db, database.Timeouts{})
+	token := &domain.RefreshToken{ID: "t1", UID: "u1", FamilyID: "f1", TokenHash: "hash", ExpiresAt: time.Now()}
+
+	mock.ExpectExec("INSERT INTO refresh_tokens").WithArgs(token.ID, token.UID, token.FamilyID, token.TokenHash, token.ExpiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, repo.Create(context.Background(), token))
+}
+
+func TestRepositoryRotateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRefreshTokenRepository(db, database.Timeouts{})
+
+	t.Run("rotated", func(t *testing.T) {
+		mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = \\?, replaced_by = \\? WHERE id = \\? AND revoked_at IS NULL").
+			WithArgs(sqlmock.AnyArg(), "t2", "t1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		rotated, err := repo.Rotate(context.Background(), "t1", "t2")
+		assert.NoError(t, err)
+		assert.True(t, rotated)
+	})
+
+	t.Run("already revoked", func(t *testing.T) {
+		mock.ExpectExec("UPDATE refresh_tokens SET revoked_at").
+			WithArgs(sqlmock.AnyArg(), "t3", "t1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		rotated, err := repo.Rotate(context.Background(), "t1", "t3")
+		assert.NoError(t, err)
+		assert.False(t, rotated)
+	})
+}
+
+func TestRepositoryFindRefreshTokenByHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRefreshTokenRepository(db, database.Timeouts{})
+	expiresAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("token found", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "uid", "family_id", "token_hash", "expires_at", "revoked_at", "replaced_by"}).
+			AddRow("t1", "u1", "f1", "hash", expiresAt, expiresAt, "t2")
+		mock.ExpectQuery("SELECT (.+) FROM refresh_tokens WHERE token_hash = ?").WithArgs("hash").WillReturnRows(rows)
+
+		token, err := repo.FindByHash(context.Background(), "hash")
+		assert.NoError(t, err)
+		assert.Equal(t, "f1", token.FamilyID)
+		assert.Equal(t, "t2", *token.ReplacedBy)
+	})
+
+	t.Run("token not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM refresh_tokens WHERE token_hash = ?").WithArgs("missing").WillReturnError(sql.ErrNoRows)
+
+		token, err := repo.FindByHash(context.Background(), "missing")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		assert.Nil(t, token)
+	})
+}
+
+func TestRepositoryRevokeUserRefreshTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRefreshTokenRepository(db, database.Timeouts{})
+
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = \\? WHERE uid = \\? AND revoked_at IS NULL").
+		WithArgs(sqlmock.AnyArg(), "u1").WillReturnResult(sqlmock.NewResult(0, 2))
+
+	assert.NoError(t, repo.RevokeUser(context.Background(), "u1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryCreateCredential(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewCredentialRepository(db, database.Timeouts{})
+	credential := &identity.Credential{User: identity.User{UID: "u1", Email: "john@example.com"}, PasswordHash: []byte("hash")}
+
+	t.Run("duplicate email", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO credentials").WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+
+		err := repo.Create(context.Background(), credential)
+		assert.ErrorIs(t, err, identity.ErrEmailExists)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO credentials").WillReturnError(errors.New("database error"))
+
+		err := repo.Create(context.Background(), credential)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, identity.ErrEmailExists)
+	})
+}
+
+func TestRepositoryRotateKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewKeyRepository(db, database.Timeouts{})
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	key := &identity.SigningKey{ID: "k2", PrivateKey: privateKey, CreatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+
+	t.Run("retires the other keys in the same transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM signing_keys WHERE retired_at IS NULL FOR UPDATE").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("k1"))
+		mock.ExpectExec("UPDATE signing_keys SET retired_at = \\? WHERE retired_at IS NULL").WithArgs(key.CreatedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO signing_keys").WithArgs("k2", sqlmock.AnyArg(), key.CreatedAt).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.RotateKey(context.Background(), key))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed insert keeps the other keys", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id FROM signing_keys").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("k1"))
+		mock.ExpectExec("UPDATE signing_keys").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO signing_keys").WillReturnError(errors.New("database error"))
+		mock.ExpectRollback()
+
+		assert.Error(t, repo.RotateKey(context.Background(), key))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
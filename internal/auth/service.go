@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated refresh token is
+	// presented again. The whole token family is revoked when it happens.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// AuthService interface
+type AuthService interface {
+	Login(ctx context.Context, request *domain.LoginRequest) (*domain.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error)
+	Logout(ctx context.Context, refreshToken string) error
+	JWKS(ctx context.Context) (*identity.JWKS, error)
+	RotateKeys(ctx context.Context) error
+}
+
+type authService struct {
+	provider   *identity.LocalProvider
+	repo       RefreshTokenRepository
+	refreshTTL time.Duration
+}
+
+// NewAuthService return a new AuthService
+func NewAuthService(provider *identity.LocalProvider, repo RefreshTokenRepository, refreshTTL time.Duration) AuthService {
+	return &authService{provider: provider, repo: repo, refreshTTL: refreshTTL}
+}
+
+// Login checks the password and starts a new refresh token family
+func (s *authService) Login(ctx context.Context, request *domain.LoginRequest) (*domain.TokenPair, error) {
+	user, err := s.provider.Authenticate(ctx, request.Email, request.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	familyID, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, user.UID, familyID, "")
+}
+
+// Refresh exchanges a refresh token for a new pair. Every refresh token can
+// be used once; presenting a rotated token revokes its whole family.
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*domain.TokenPair, error) {
+	stored, err := s.repo.FindByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if stored.RevokedAt != nil {
+		if stored.ReplacedBy != nil {
+			logging.FromContext(ctx).Warn("Refresh token reuse detected, revoking family", "user_id", stored.UID, "family_id", stored.FamilyID)
+			if err := s.repo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	return s.issue(ctx, stored.UID, stored.FamilyID, stored.ID)
+}
+
+// Logout revokes the refresh token family
+func (s *authService) Logout(ctx context.Context, refreshToken string) error {
+	stored, err := s.repo.FindByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.repo.RevokeFamily(ctx, stored.FamilyID)
+}
+
+// JWKS return the public keys that verify access tokens
+func (s *authService) JWKS(ctx context.Context) (*identity.JWKS, error) {
+	return s.provider.Keys().JWKS(ctx)
+}
+
+// RotateKeys start signing with a new key
+func (s *authService) RotateKeys(ctx context.Context) error {
+	_, err := s.provider.Keys().Rotate(ctx)
+	return err
+}
+
+// issue signs an access token and stores a new refresh token in the family.
+// When previousID is set, that token is rotated to the new one.
+func (s *authService) issue(ctx context.Context, uid, familyID, previousID string) (*domain.TokenPair, error) {
+	accessToken, err := s.provider.IssueToken(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
+
+	if previousID != "" {
+		rotated, err := s.repo.Rotate(ctx, previousID, id)
+		if err != nil {
+			return nil, err
+		}
+		if !rotated {
+			// Another request rotated it first: treat it as a reuse
+			if err := s.repo.RevokeFamily(ctx, familyID); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+	}
+
+	err = s.repo.Create(ctx, &domain.RefreshToken{
+		ID:        id,
+		UID:       uid,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTTL).UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TokenPair{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.provider.TokenTTL().Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryRefreshTokenRepository keeps refresh tokens in memory
+type memoryRefreshTokenRepository struct {
+	tokens map[string]*domain.RefreshToken
+}
+
+func newMemoryRefreshTokenRepository() *memoryRefreshTokenRepository {
+	return &memoryRefreshTokenRepository{tokens: make(map[string]*domain.RefreshToken)}
+}
+
+func (m *memoryRefreshTokenRepository) Create(ctx context.Context, t *domain.RefreshToken) error {
+	stored := *t
+	m.tokens[t.ID] = &stored
+	return nil
+}
+
+func (m *memoryRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	for _, t := range m.tokens {
+		if t.TokenHash == tokenHash {
+			found := *t
+			return &found, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryRefreshTokenRepository) Rotate(ctx context.Context, id, replacedBy string) (bool, error) {
+	t := m.tokens[id]
+	if t == nil || t.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	t.ReplacedBy = &replacedBy
+	return true, nil
+}
+
+func (m *memoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *memoryRefreshTokenRepository) RevokeUser(ctx context.Context, uid string) error {
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.UID == uid && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func setupAuthServiceTest(t *testing.T) (AuthService, *identity.LocalProvider) {
+	keys := identity.NewKeyManager(identity.NewMemoryKeyStore(), 24*time.Hour, time.Hour)
+	provider := identity.NewLocalProvider(identity.NewMemoryCredentialStore(), keys, "go-web", 15*time.Minute)
+	_, err := provider.CreateUser(context.Background(), &identity.UserToCreate{Email: "john@example.com", Password: "secret123"})
+	assert.NoError(t, err)
+
+	return NewAuthService(provider, newMemoryRefreshTokenRepository(), time.Hour), provider
+}
+
+func TestServiceLogin(t *testing.T) {
+	service, provider := setupAuthServiceTest(t)
+	ctx := context.Background()
+
+	t.Run("valid credentials", func(t *testing.T) {
+		tokens, err := service.Login(ctx, &domain.LoginRequest{Email: "john@example.com", Password: "secret123"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Bearer", tokens.TokenType)
+		assert.Equal(t, int64(900), tokens.ExpiresIn)
+		assert.NotEmpty(t, tokens.RefreshToken)
+
+		_, err = provider.VerifyToken(ctx, tokens.AccessToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := service.Login(ctx, &domain.LoginRequest{Email: "john@example.com", Password: "wrong"})
+		assert.ErrorIs(t, err, identity.ErrInvalidCredentials)
+	})
+}
+
+func TestServiceRefresh(t *testing.T) {
+	service, _ := setupAuthServiceTest(t)
+	ctx := context.Background()
+
+	first, err := service.Login(ctx, &domain.LoginRequest{Email: "john@example.com", Password: "secret123"})
+	assert.NoError(t, err)
+
+	second, err := service.Refresh(ctx, first.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+	t.Run("reusing a rotated token revokes the family", func(t *testing.T) {
+		_, err := service.Refresh(ctx, first.RefreshToken)
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		_, err = service.Refresh(ctx, second.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		_, err := service.Refresh(ctx, "unknown")
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+}
+
+func TestServiceLogout(t *testing.T) {
+	service, _ := setupAuthServiceTest(t)
+	ctx := context.Background()
+
+	tokens, err := service.Login(ctx, &domain.LoginRequest{Email: "john@example.com", Password: "secret123"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, service.Logout(ctx, tokens.RefreshToken))
+
+	_, err = service.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Purposes of a UserToken
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// UserToken records a single-use token sent by email. The token itself is
+// signed and never stored; the row only tracks expiry and use.
+type UserToken struct {
+	ID        string
+	UserID    string
+	Purpose   string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ConfirmPasswordResetRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,max=128"`
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// APIKey is a personal key for machine-to-machine access. Only the prefix
+// of the key is stored in clear; the secret is kept as a hash.
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	SecretHash string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreatedAPIKey is returned once, when the key is created
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenPair is returned on login and refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken is a stored refresh token. Tokens issued from the same login
+// share a FamilyID, so reusing a rotated token can revoke the whole chain.
+type RefreshToken struct {
+	ID         string
+	UID        string
+	FamilyID   string
+	TokenHash  string
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy *string
+}
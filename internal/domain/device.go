@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Platforms a device can register from
+const (
+	PlatformAndroid = "android"
+	PlatformIOS     = "ios"
+	PlatformWeb     = "web"
+)
+
+// Device is a push token registered by a user
+type Device struct {
+	ID         int64     `json:"id"`
+	UserID     string    `json:"user_id"`
+	Platform   string    `json:"platform"`
+	Token      string    `json:"token"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+type RegisterDeviceRequest struct {
+	Platform string `json:"platform" validate:"required,oneof=android ios web"`
+	Token    string `json:"token" validate:"required,max=512"`
+}
+
+// NotificationPreferences are the push topics a user accepts. Users
+// without stored preferences accept every topic.
+type NotificationPreferences struct {
+	Orders     bool `json:"orders"`
+	PriceDrops bool `json:"price_drops"`
+}
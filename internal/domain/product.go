@@ -1,11 +1,20 @@
 package domain
 
+import "time"
+
 // Product struct
 // ID, Name, Price, Description y Category.
 type Product struct {
-	ID          int     `json:"id"`
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Description string  `json:"description"`
-	Category    string  `json:"category"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name" validate:"required,max=255"`
+	Price       float64    `json:"price" validate:"min=0"`
+	Description string     `json:"description" validate:"max=255"`
+	Category    string     `json:"category" validate:"max=255"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// ProductFeedStats summarizes the catalog for feeds and sitemaps
+type ProductFeedStats struct {
+	Count        int64
+	LastModified time.Time
 }
@@ -0,0 +1,23 @@
+package domain
+
+type Role struct {
+	Name        string   `json:"name" validate:"required,max=50,regex=^[a-z][a-z0-9_-]*$"`
+	Description string   `json:"description" validate:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// RequiresVerifiedEmail denies the permission to users that have not
+	// verified their email address, whatever their role
+	RequiresVerifiedEmail bool `json:"requires_verified_email"`
+}
+
+type UpdateRolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required,max=50"`
+}
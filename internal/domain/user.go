@@ -1,15 +1,31 @@
 package domain
 
 type User struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Role  string `json:"role"`
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	Locale        string `json:"locale"`
+	EmailVerified bool   `json:"email_verified"`
+	Disabled      bool   `json:"disabled"`
 }
 
 type CreateUserRequest struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Role     string `json:"role"`
+	Name     string `json:"name" validate:"required,max=100"`
+	Email    string `json:"email" validate:"required,email,max=100"`
+	Password string `json:"password" validate:"required,min=8,max=128"`
+	// Role must exist in the roles table, the service checks it before the
+	// identity provider creates the account
+	Role string `json:"role" validate:"required,max=50,regex=^[a-z][a-z0-9_-]*$"`
+	// Locale selects the language of the emails sent to the user, e.g. "es"
+	Locale string `json:"locale" validate:"max=10,regex=^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$"`
+}
+
+// UpdateUserRequest holds the fields that can be changed on a user.
+// Nil fields are left untouched.
+type UpdateUserRequest struct {
+	Name     *string `json:"name" validate:"notblank,max=100"`
+	Email    *string `json:"email" validate:"notblank,email,max=100"`
+	Locale   *string `json:"locale" validate:"max=10,regex=^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$"`
+	Disabled *bool   `json:"disabled"`
 }
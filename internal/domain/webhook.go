@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEventsAll subscribes a webhook to every event type
+const WebhookEventsAll = "*"
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead is set after the last attempt fails. Dead deliveries are
+	// only sent again when replayed.
+	DeliveryDead = "dead"
+)
+
+// Webhook is a subscription of a partner URL to domain events
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Subscribes report whether the webhook wants the event type
+func (w *Webhook) Subscribes(eventType string) bool {
+	for _, e := range w.Events {
+		if e == WebhookEventsAll || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required,max=2048"`
+	Events []string `json:"events" validate:"required"`
+	// Secret is generated when empty
+	Secret string `json:"secret" validate:"max=64"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url" validate:"max=2048"`
+	Events []string `json:"events"`
+	Secret *string  `json:"secret" validate:"max=64"`
+	Active *bool    `json:"active"`
+}
+
+// CreatedWebhook is returned once, when the webhook is created
+type CreatedWebhook struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDelivery is one event sent to one webhook
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      string          `json:"webhook_id"`
+	EventID        int64           `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code"`
+	LastError      *string         `json:"last_error"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+}
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrTooManySubscribers is returned by Subscribe when the broker is full
+	ErrTooManySubscribers = errors.New("too many subscribers")
+	// ErrBrokerClosed is returned by Subscribe after Close
+	ErrBrokerClosed = errors.New("broker closed")
+)
+
+// Publisher is told about committed changes
+type Publisher interface {
+	Publish(eventType string, data interface{})
+}
+
+// Publishers sends every event to each of its publishers
+type Publishers []Publisher
+
+func (p Publishers) Publish(eventType string, data interface{}) {
+	for _, publisher := range p {
+		publisher.Publish(eventType, data)
+	}
+}
+
+// BrokerOptions configures the broker
+type BrokerOptions struct {
+	// BufferSize is the number of events a subscriber can fall behind
+	// before it is evicted
+	BufferSize int
+	// HistorySize is the number of recent events kept for resuming
+	HistorySize int
+	// MaxSubscribers caps the open subscriptions, 0 means no limit
+	MaxSubscribers int
+}
+
+// Broker is an in-process pub/sub for live change streams. Event ids are
+// assigned by the broker and only mean something to this process.
+type Broker struct {
+	mu          sync.Mutex
+	options     BrokerOptions
+	lastID      int64
+	history     []Event
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// Subscription receives the events published after it was created
+type Subscription struct {
+	ch     chan Event
+	closed bool
+}
+
+// Events is closed when the subscription is evicted or unsubscribed
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// NewBroker return a new Broker
+func NewBroker(options BrokerOptions) *Broker {
+	return &Broker{options: options, subscribers: map[*Subscription]struct{}{}}
+}
+
+// Publish sends the event to every subscriber. Subscribers whose buffer is
+// full are evicted instead of slowing down the publisher.
+func (b *Broker) Publish(eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("Error encoding event", "event_type", eventType, "error", err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e := Event{ID: b.lastID, Type: eventType, Data: payload, CreatedAt: time.Now().UTC()}
+	b.history = append(b.history, e)
+	if len(b.history) > b.options.HistorySize {
+		b.history = b.history[len(b.history)-b.options.HistorySize:]
+	}
+
+	for s := range b.subscribers {
+		select {
+		case s.ch <- e:
+		default:
+			slog.Warn("Evicting slow event subscriber")
+			b.remove(s)
+		}
+	}
+}
+
+// Subscribe opens a subscription. When lastEventID is set, the events
+// published after it are returned as backlog; resumed is false when those
+// events are no longer known, so the client has to reload its state.
+func (b *Broker) Subscribe(lastEventID int64) (sub *Subscription, backlog []Event, resumed bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, false, ErrBrokerClosed
+	}
+	if b.options.MaxSubscribers > 0 && len(b.subscribers) >= b.options.MaxSubscribers {
+		return nil, nil, false, ErrTooManySubscribers
+	}
+
+	resumed = true
+	if lastEventID > 0 {
+		oldest := b.lastID + 1
+		if len(b.history) > 0 {
+			oldest = b.history[0].ID
+		}
+		// Ids from another process or older than the history cannot be resumed
+		if lastEventID > b.lastID || lastEventID < oldest-1 {
+			resumed = false
+		} else {
+			for _, e := range b.history {
+				if e.ID > lastEventID {
+					backlog = append(backlog, e)
+				}
+			}
+		}
+	}
+
+	sub = &Subscription{ch: make(chan Event, b.options.BufferSize)}
+	b.subscribers[sub] = struct{}{}
+	return sub, backlog, resumed, nil
+}
+
+// Unsubscribe closes the subscription
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remove(sub)
+}
+
+// Subscribers returns the number of open subscriptions
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+// Close ends every subscription, so streams can finish during shutdown
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subscribers {
+		b.remove(s)
+	}
+}
+
+// remove must be called with the lock held
+func (b *Broker) remove(sub *Subscription) {
+	delete(b.subscribers, sub)
+	if !sub.closed {
+		sub.closed = true
+		close(sub.ch)
+	}
+}
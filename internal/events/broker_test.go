@@ -0,0 +1,71 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrokerResume(t *testing.T) {
+	broker := NewBroker(BrokerOptions{BufferSize: 10, HistorySize: 3})
+	for i := 0; i < 5; i++ {
+		broker.Publish(ProductUpdated, map[string]int{"id": i})
+	}
+
+	t.Run("backlog after the last event id", func(t *testing.T) {
+		_, backlog, resumed, err := broker.Subscribe(3)
+		assert.NoError(t, err)
+		assert.True(t, resumed)
+		assert.Len(t, backlog, 2)
+		assert.Equal(t, int64(4), backlog[0].ID)
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		_, backlog, resumed, err := broker.Subscribe(5)
+		assert.NoError(t, err)
+		assert.True(t, resumed)
+		assert.Empty(t, backlog)
+	})
+
+	t.Run("older than the history", func(t *testing.T) {
+		_, _, resumed, err := broker.Subscribe(1)
+		assert.NoError(t, err)
+		assert.False(t, resumed)
+	})
+
+	t.Run("id from another process", func(t *testing.T) {
+		_, _, resumed, err := broker.Subscribe(42)
+		assert.NoError(t, err)
+		assert.False(t, resumed)
+	})
+}
+
+func TestBrokerEvictsSlowSubscribers(t *testing.T) {
+	broker := NewBroker(BrokerOptions{BufferSize: 1, HistorySize: 10})
+	slow, _, _, _ := broker.Subscribe(0)
+
+	broker.Publish(ProductCreated, map[string]int{"id": 1})
+	broker.Publish(ProductCreated, map[string]int{"id": 2})
+
+	e, ok := <-slow.Events()
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), e.ID)
+	_, ok = <-slow.Events()
+	assert.False(t, ok, "the subscription is closed once the buffer overflows")
+
+	// Unsubscribing an evicted subscription is safe
+	broker.Unsubscribe(slow)
+}
+
+func TestBrokerMaxSubscribers(t *testing.T) {
+	broker := NewBroker(BrokerOptions{BufferSize: 1, MaxSubscribers: 1})
+
+	sub, _, _, err := broker.Subscribe(0)
+	assert.NoError(t, err)
+	_, _, _, err = broker.Subscribe(0)
+	assert.ErrorIs(t, err, ErrTooManySubscribers)
+
+	broker.Unsubscribe(sub)
+	_, _, _, err = broker.Subscribe(0)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,19 @@
+package events
+
+import "github.com/Jacobo0312/go-web/pkg/metrics"
+
+// Counter counts the committed events by type, e.g. the products created
+type Counter struct {
+	events *metrics.CounterVec
+}
+
+// NewCounter return a Counter exported as business_events_total
+func NewCounter(registry *metrics.Registry) *Counter {
+	return &Counter{
+		events: registry.NewCounterVec("business_events_total", "Committed business events by type.", "type"),
+	}
+}
+
+func (c *Counter) Publish(eventType string, data interface{}) {
+	c.events.WithLabelValues(eventType).Inc()
+}
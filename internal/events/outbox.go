@@ -0,0 +1,89 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Jacobo0312/go-web/pkg/database"
+)
+
+// Event types
+const (
+	ProductCreated = "product.created"
+	ProductUpdated = "product.updated"
+	ProductDeleted = "product.deleted"
+	UserCreated    = "user.created"
+	UserUpdated    = "user.updated"
+	UserDeleted    = "user.deleted"
+)
+
+// Types lists every event type
+var Types = []string{ProductCreated, ProductUpdated, ProductDeleted, UserCreated, UserUpdated, UserDeleted}
+
+// Event is a domain change recorded in the outbox
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Outbox records events in the same transaction as the change they describe,
+// so an event is stored if and only if the change is committed
+type Outbox interface {
+	Add(ctx context.Context, tx database.DBTX, eventType string, data interface{}) error
+}
+
+type outbox struct{}
+
+// NewOutbox return a new Outbox
+func NewOutbox() Outbox {
+	return outbox{}
+}
+
+func (outbox) Add(ctx context.Context, tx database.DBTX, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO events_outbox (event_type, payload, created_at) VALUES (?, ?, ?)"
+	_, err = tx.ExecContext(ctx, query, eventType, payload, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// MemoryOutbox keeps the added events in memory, for tests
+type MemoryOutbox struct {
+	mu     sync.Mutex
+	events []Event
+	// Err, when set, is returned by Add instead of storing the event
+	Err error
+}
+
+func (o *MemoryOutbox) Add(ctx context.Context, tx database.DBTX, eventType string, data interface{}) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.Err != nil {
+		return o.Err
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	o.events = append(o.events, Event{ID: int64(len(o.events) + 1), Type: eventType, Data: payload, CreatedAt: time.Now().UTC()})
+	return nil
+}
+
+// Events returns a copy of the added events
+func (o *MemoryOutbox) Events() []Event {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]Event(nil), o.events...)
+}
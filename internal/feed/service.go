@@ -0,0 +1,267 @@
+package feed
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/product"
+)
+
+const (
+	// MaxURLsPerSitemap is the limit of URLs allowed in a single sitemap file
+	MaxURLsPerSitemap = 50000
+
+	batchSize = 500
+
+	sitemapNamespace  = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	merchantNamespace = "http://base.google.com/ns/1.0"
+)
+
+// FeedService interface
+type FeedService interface {
+	GetStats(ctx context.Context) (*domain.ProductFeedStats, error)
+	WriteProductFeed(ctx context.Context, w io.Writer) error
+	WriteSitemap(ctx context.Context, w io.Writer, stats *domain.ProductFeedStats) error
+	WriteSitemapPage(ctx context.Context, w io.Writer, page int) error
+}
+
+type feedService struct {
+	repo     product.ProductRepository
+	baseURL  string
+	currency string
+}
+
+// NewFeedService return a new FeedService
+func NewFeedService(repo product.ProductRepository, baseURL, currency string) FeedService {
+	return &feedService{
+		repo:     repo,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		currency: currency,
+	}
+}
+
+type merchantItem struct {
+	XMLName      xml.Name `xml:"item"`
+	ID           int      `xml:"g:id"`
+	Title        string   `xml:"g:title"`
+	Description  string   `xml:"g:description"`
+	Link         string   `xml:"g:link"`
+	Price        string   `xml:"g:price"`
+	ProductType  string   `xml:"g:product_type,omitempty"`
+	Availability string   `xml:"g:availability"`
+	Condition    string   `xml:"g:condition"`
+}
+
+type sitemapURL struct {
+	XMLName xml.Name `xml:"url"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
+}
+
+type sitemapRef struct {
+	XMLName xml.Name `xml:"sitemap"`
+	Loc     string   `xml:"loc"`
+	LastMod string   `xml:"lastmod,omitempty"`
+}
+
+// GetStats return the catalog size and last modification time
+func (s *feedService) GetStats(ctx context.Context) (*domain.ProductFeedStats, error) {
+	return s.repo.GetFeedStats(ctx)
+}
+
+// WriteProductFeed write a Google Merchant RSS feed with every product
+func (s *feedService) WriteProductFeed(ctx context.Context, w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	rss := xml.StartElement{
+		Name: xml.Name{Local: "rss"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "version"}, Value: "2.0"},
+			{Name: xml.Name{Local: "xmlns:g"}, Value: merchantNamespace},
+		},
+	}
+	channel := xml.StartElement{Name: xml.Name{Local: "channel"}}
+	if err := enc.EncodeToken(rss); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(channel); err != nil {
+		return err
+	}
+	if err := encodeText(enc, "title", "Products"); err != nil {
+		return err
+	}
+	if err := encodeText(enc, "link", s.baseURL); err != nil {
+		return err
+	}
+	if err := encodeText(enc, "description", "Product catalog feed"); err != nil {
+		return err
+	}
+
+	err := s.stream(ctx, 0, 0, func(p domain.Product) error {
+		return enc.Encode(merchantItem{
+			ID:           p.ID,
+			Title:        p.Name,
+			Description:  p.Description,
+			Link:         s.productURL(p.ID),
+			Price:        fmt.Sprintf("%.2f %s", p.Price, s.currency),
+			ProductType:  p.Category,
+			Availability: "in_stock",
+			Condition:    "new",
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := enc.EncodeToken(channel.End()); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(rss.End()); err != nil {
+		return err
+	}
+
+	return enc.Flush()
+}
+
+// WriteSitemap write the root sitemap: a plain urlset when the catalog fits in
+// a single file, or a sitemap index pointing to the paginated files otherwise
+func (s *feedService) WriteSitemap(ctx context.Context, w io.Writer, stats *domain.ProductFeedStats) error {
+	if stats.Count <= MaxURLsPerSitemap {
+		return s.WriteSitemapPage(ctx, w, 1)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	index := xml.StartElement{
+		Name: xml.Name{Local: "sitemapindex"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: sitemapNamespace}},
+	}
+	if err := enc.EncodeToken(index); err != nil {
+		return err
+	}
+
+	pages := SitemapPages(stats.Count)
+	for page := 1; page <= pages; page++ {
+		err := enc.Encode(sitemapRef{
+			Loc:     fmt.Sprintf("%s/sitemaps/products-%d.xml", s.baseURL, page),
+			LastMod: formatLastMod(stats.LastModified),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := enc.EncodeToken(index.End()); err != nil {
+		return err
+	}
+
+	return enc.Flush()
+}
+
+// WriteSitemapPage write the urlset for the given 1-based sitemap page
+func (s *feedService) WriteSitemapPage(ctx context.Context, w io.Writer, page int) error {
+	if page < 1 {
+		return fmt.Errorf("invalid sitemap page %d", page)
+	}
+
+	afterID, err := s.repo.GetCursorAt(ctx, int64(page-1) * MaxURLsPerSitemap)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	urlset := xml.StartElement{
+		Name: xml.Name{Local: "urlset"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: sitemapNamespace}},
+	}
+	if err := enc.EncodeToken(urlset); err != nil {
+		return err
+	}
+
+	err = s.stream(ctx, afterID, MaxURLsPerSitemap, func(p domain.Product) error {
+		entry := sitemapURL{Loc: s.productURL(p.ID)}
+		if p.UpdatedAt != nil {
+			entry.LastMod = formatLastMod(*p.UpdatedAt)
+		}
+		return enc.Encode(entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := enc.EncodeToken(urlset.End()); err != nil {
+		return err
+	}
+
+	return enc.Flush()
+}
+
+// SitemapPages return the number of sitemap files needed for count products
+func SitemapPages(count int64) int {
+	if count <= 0 {
+		return 1
+	}
+	return int((count + MaxURLsPerSitemap - 1) / MaxURLsPerSitemap)
+}
+
+// stream reads products in batches starting after afterID and calls fn for
+// each of them. A limit of 0 reads until the end of the table.
+func (s *feedService) stream(ctx context.Context, afterID int64, limit int, fn func(domain.Product) error) error {
+	read := 0
+	for {
+		size := batchSize
+		if limit > 0 && limit-read < size {
+			size = limit - read
+		}
+		if size <= 0 {
+			return nil
+		}
+
+		products, err := s.repo.GetBatch(ctx, afterID, size)
+		if err != nil {
+			return err
+		}
+
+		for _, p := range products {
+			if err := fn(p); err != nil {
+				return err
+			}
+		}
+
+		read += len(products)
+		if len(products) < size {
+			return nil
+		}
+		afterID = int64(products[len(products)-1].ID)
+	}
+}
+
+func (s *feedService) productURL(id int) string {
+	return fmt.Sprintf("%s/products/%d", s.baseURL, id)
+}
+
+func encodeText(enc *xml.Encoder, name, value string) error {
+	return enc.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: name}})
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,139 @@
+package feed
+
+import (
+	"context"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/product"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockProductRepository struct {
+	mock.Mock
+}
+
+func (m *mockProductRepository) Create(ctx context.Context, p *domain.Product) error {
+	args := m.Called(p)
+	return args.Error(0)
+}
+
+func (m *mockProductRepository) GetAll(ctx context.Context) ([]domain.Product, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (m *mockProductRepository) GetByID(ctx context.Context, id int64) (*domain.Product, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.Product), args.Error(1)
+}
+
+func (m *mockProductRepository) Update(ctx context.Context, p *domain.Product) error {
+	args := m.Called(p)
+	return args.Error(0)
+}
+
+func (m *mockProductRepository) Delete(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockProductRepository) GetBatch(ctx context.Context, afterID int64, limit int) ([]domain.Product, error) {
+	args := m.Called(afterID, limit)
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (m *mockProductRepository) GetCursorAt(ctx context.Context, offset int64) (int64, error) {
+	args := m.Called(offset)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockProductRepository) GetFeedStats(ctx context.Context) (*domain.ProductFeedStats, error) {
+	args := m.Called()
+	return args.Get(0).(*domain.ProductFeedStats), args.Error(1)
+}
+
+func (m *mockProductRepository) WithTx(tx database.DBTX) product.ProductRepository {
+	return m
+}
+
+func TestServiceWriteProductFeed(t *testing.T) {
+	mockRepo := new(mockProductRepository)
+	service := NewFeedService(mockRepo, "https://shop.example.com/", "EUR")
+
+	batch := make([]domain.Product, batchSize)
+	for i := range batch {
+		batch[i] = domain.Product{ID: i + 1, Name: "Product", Price: 9.5, Category: "Audio"}
+	}
+	mockRepo.On("GetBatch", int64(0), batchSize).Return(batch, nil).Once()
+	mockRepo.On("GetBatch", int64(batchSize), batchSize).Return([]domain.Product{{ID: 501, Name: "Last <one>", Price: 1}}, nil).Once()
+
+	var buf bytes.Buffer
+	err := service.WriteProductFeed(context.Background(), &buf)
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), `<rss version="2.0" xmlns:g="http://base.google.com/ns/1.0">`)
+	assert.Contains(t, buf.String(), "<g:link>https://shop.example.com/products/1</g:link>")
+	assert.Contains(t, buf.String(), "<g:price>9.50 EUR</g:price>")
+	assert.Contains(t, buf.String(), "<g:title>Last &lt;one&gt;</g:title>")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestServiceWriteSitemap(t *testing.T) {
+	lastModified := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("single urlset", func(t *testing.T) {
+		mockRepo := new(mockProductRepository)
+		service := NewFeedService(mockRepo, "https://shop.example.com", "USD")
+
+		mockRepo.On("GetCursorAt", int64(0)).Return(int64(0), nil).Once()
+		mockRepo.On("GetBatch", int64(0), batchSize).Return([]domain.Product{{ID: 7, UpdatedAt: &lastModified}}, nil).Once()
+
+		var buf bytes.Buffer
+		err := service.WriteSitemap(context.Background(), &buf, &domain.ProductFeedStats{Count: 1, LastModified: lastModified})
+
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+		assert.Contains(t, buf.String(), "<url><loc>https://shop.example.com/products/7</loc><lastmod>2024-05-01T10:00:00Z</lastmod></url>")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("sitemap index", func(t *testing.T) {
+		mockRepo := new(mockProductRepository)
+		service := NewFeedService(mockRepo, "https://shop.example.com", "USD")
+
+		var buf bytes.Buffer
+		err := service.WriteSitemap(context.Background(), &buf, &domain.ProductFeedStats{Count: MaxURLsPerSitemap*2 + 1, LastModified: lastModified})
+
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "<sitemapindex")
+		assert.Contains(t, buf.String(), "<loc>https://shop.example.com/sitemaps/products-3.xml</loc>")
+		assert.NotContains(t, buf.String(), "products-4.xml")
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceWriteSitemapPage(t *testing.T) {
+	mockRepo := new(mockProductRepository)
+	service := NewFeedService(mockRepo, "https://shop.example.com", "USD")
+
+	mockRepo.On("GetCursorAt", int64(MaxURLsPerSitemap)).Return(int64(50010), nil).Once()
+	mockRepo.On("GetBatch", int64(50010), batchSize).Return([]domain.Product{{ID: 50011}}, nil).Once()
+
+	var buf bytes.Buffer
+	err := service.WriteSitemapPage(context.Background(), &buf, 2)
+
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "<loc>https://shop.example.com/products/50011</loc>")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSitemapPages(t *testing.T) {
+	assert.Equal(t, 1, SitemapPages(0))
+	assert.Equal(t, 1, SitemapPages(MaxURLsPerSitemap))
+	assert.Equal(t, 2, SitemapPages(MaxURLsPerSitemap+1))
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+
+	"github.com/Jacobo0312/go-web/internal/account"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/rbac"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+)
+
+// AccountHandler interface
+type AccountHandler interface {
+	SendVerificationEmail(w http.ResponseWriter, r *http.Request)
+	ConfirmEmail(w http.ResponseWriter, r *http.Request)
+	RequestPasswordReset(w http.ResponseWriter, r *http.Request)
+	ResetPassword(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type accountHandler struct {
+	service    account.AccountService
+	authorizer rbac.Authorizer
+}
+
+func NewAccountHandler(service account.AccountService, authorizer rbac.Authorizer) AccountHandler {
+	return &accountHandler{service: service, authorizer: authorizer}
+}
+
+// Register routes
+func (h *accountHandler) RegisterRoutes(r *http.ServeMux) {
+	r.HandleFunc("POST /email-verification/confirm", h.ConfirmEmail)
+	r.HandleFunc("POST /password-reset", h.RequestPasswordReset)
+	r.HandleFunc("POST /password-reset/confirm", h.ResetPassword)
+	//Owner or admin routes
+	r.HandleFunc("POST /users/{id}/email-verification", h.authorizer.RequireSelfOrPermission(rbac.PermissionUsersWrite)(h.SendVerificationEmail))
+}
+
+// Send the verification email to a User
+func (h *accountHandler) SendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	err := h.service.SendVerificationEmail(r.Context(), r.PathValue("id"))
+	if stdErrors.Is(err, account.ErrEmailAlreadyVerified) {
+		helpers.RespondWithError(w, r, errors.NewConflict("Email already verified", err))
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error sending verification email"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusAccepted, nil)
+}
+
+// Confirm an email address with the emailed token
+func (h *accountHandler) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	var request domain.ConfirmEmailRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	user, err := h.service.ConfirmEmail(r.Context(), request.Token)
+	if stdErrors.Is(err, account.ErrInvalidToken) {
+		helpers.RespondWithError(w, r, errors.NewBadRequest("Invalid or expired token", err))
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error verifying email"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, user)
+}
+
+// Send a password reset email. The response does not reveal whether the
+// email belongs to a user.
+func (h *accountHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var request domain.PasswordResetRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	if err := h.service.RequestPasswordReset(r.Context(), request.Email); err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error sending password reset email"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusAccepted, nil)
+}
+
+// Set a new password with the emailed token
+func (h *accountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var request domain.ConfirmPasswordResetRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	err := h.service.ResetPassword(r.Context(), request.Token, request.Password)
+	switch {
+	case stdErrors.Is(err, account.ErrPasswordTooShort):
+		helpers.RespondWithError(w, r, errors.NewBadRequest(err.Error(), err))
+	case stdErrors.Is(err, account.ErrInvalidToken):
+		helpers.RespondWithError(w, r, errors.NewBadRequest("Invalid or expired token", err))
+	case err != nil:
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error resetting password"))
+	default:
+		helpers.RespondWithJSON(w, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/apikey"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/rbac"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+)
+
+// APIKeyHandler interface
+type APIKeyHandler interface {
+	GetAPIKeys(w http.ResponseWriter, r *http.Request)
+	CreateAPIKey(w http.ResponseWriter, r *http.Request)
+	RevokeAPIKey(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type apiKeyHandler struct {
+	service    apikey.APIKeyService
+	authorizer rbac.Authorizer
+}
+
+func NewAPIKeyHandler(service apikey.APIKeyService, authorizer rbac.Authorizer) APIKeyHandler {
+	return &apiKeyHandler{service: service, authorizer: authorizer}
+}
+
+// Register routes
+func (h *apiKeyHandler) RegisterRoutes(r *http.ServeMux) {
+	//Owner or admin routes
+	canManage := h.authorizer.RequireSelfOrPermission(rbac.PermissionUsersWrite)
+
+	r.HandleFunc("GET /users/{id}/api-keys", canManage(h.GetAPIKeys))
+	r.HandleFunc("POST /users/{id}/api-keys", canManage(h.CreateAPIKey))
+	r.HandleFunc("DELETE /users/{id}/api-keys/{keyID}", canManage(h.RevokeAPIKey))
+}
+
+// Get the active API keys of a User
+func (h *apiKeyHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.GetAPIKeys(r.Context(), r.PathValue("id"))
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "API key", "Error getting API keys"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, keys)
+}
+
+// Create an API key. The key is only shown in this response.
+func (h *apiKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request domain.CreateAPIKeyRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	if request.ExpiresAt != nil && request.ExpiresAt.Before(time.Now()) {
+		helpers.RespondWithError(w, r, errors.NewValidation("Invalid request payload",
+			errors.Violation{Field: "expires_at", Message: "must be in the future"}))
+		return
+	}
+
+	key, err := h.service.CreateAPIKey(r.Context(), r.PathValue("id"), &request)
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "API key", "Error creating API key"))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	helpers.RespondWithJSON(w, http.StatusCreated, key)
+}
+
+// Revoke an API key
+func (h *apiKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	err := h.service.RevokeAPIKey(r.Context(), r.PathValue("id"), r.PathValue("keyID"))
+	if stdErrors.Is(err, apikey.ErrAPIKeyNotFound) {
+		helpers.RespondWithError(w, r, errors.NewNotFound("API key not found", err))
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "API key", "Error revoking API key"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/test"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *mockAPIKeyService) CreateAPIKey(ctx context.Context, userID string, request *domain.CreateAPIKeyRequest) (*domain.CreatedAPIKey, error) {
+	args := m.Called(userID, request)
+	return args.Get(0).(*domain.CreatedAPIKey), args.Error(1)
+}
+
+func (m *mockAPIKeyService) GetAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	args := m.Called(userID)
+	return args.Get(0).([]domain.APIKey), args.Error(1)
+}
+
+func (m *mockAPIKeyService) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *mockAPIKeyService) VerifyAPIKey(ctx context.Context, key string) (string, []string, error) {
+	args := m.Called(key)
+	return args.String(0), args.Get(1).([]string), args.Error(2)
+}
+
+func TestHandlerAPIKeyErrors(t *testing.T) {
+	mockService := new(mockAPIKeyService)
+	handler := NewAPIKeyHandler(mockService, allowAllAuthorizer{})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	testCases := []test.HandlerTestCase{
+		{
+			Name:           "create for an unknown user",
+			Method:         "POST",
+			URL:            "/users/missing/api-keys",
+			Body:           `{"name":"CI","scopes":["products:write"]}`,
+			ExpectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			Name:           "database unavailable",
+			Method:         "GET",
+			URL:            "/users/abc/api-keys",
+			ExpectedStatus: http.StatusServiceUnavailable,
+		},
+		{
+			Name:           "unexpected error",
+			Method:         "DELETE",
+			URL:            "/users/abc/api-keys/k1",
+			ExpectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	request := &domain.CreateAPIKeyRequest{Name: "CI", Scopes: []string{"products:write"}}
+	mockService.On("CreateAPIKey", "missing", request).Return((*domain.CreatedAPIKey)(nil), fmt.Errorf("inserting key: %w", errors.ErrForeignKey)).Once()
+	mockService.On("GetAPIKeys", "abc").Return([]domain.APIKey(nil), fmt.Errorf("listing keys: %w", errors.ErrUnavailable)).Once()
+	mockService.On("RevokeAPIKey", "abc", "k1").Return(fmt.Errorf("boom")).Once()
+
+	for _, tc := range testCases {
+		test.ExecuteHandlerTestCase(t, mux, tc)
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+
+	"github.com/Jacobo0312/go-web/internal/auth"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/rbac"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+)
+
+// AuthHandler interface
+type AuthHandler interface {
+	Login(w http.ResponseWriter, r *http.Request)
+	Refresh(w http.ResponseWriter, r *http.Request)
+	Logout(w http.ResponseWriter, r *http.Request)
+	GetJWKS(w http.ResponseWriter, r *http.Request)
+	RotateKeys(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type authHandler struct {
+	service    auth.AuthService
+	authorizer rbac.Authorizer
+}
+
+func NewAuthHandler(service auth.AuthService, authorizer rbac.Authorizer) AuthHandler {
+	return &authHandler{service: service, authorizer: authorizer}
+}
+
+// Register routes
+func (h *authHandler) RegisterRoutes(r *http.ServeMux) {
+	r.HandleFunc("POST /auth/login", h.Login)
+	r.HandleFunc("POST /auth/refresh", h.Refresh)
+	r.HandleFunc("POST /auth/logout", h.Logout)
+	r.HandleFunc("GET /.well-known/jwks.json", h.GetJWKS)
+	//Admin routes
+	r.HandleFunc("POST /auth/keys/rotate", h.authorizer.RequirePermission(rbac.PermissionAuthManage)(h.RotateKeys))
+}
+
+// Login with email and password
+func (h *authHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var request domain.LoginRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	tokens, err := h.service.Login(r.Context(), &request)
+	if err != nil {
+		respondWithAuthError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	helpers.RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// Refresh the token pair
+func (h *authHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var request domain.RefreshRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	tokens, err := h.service.Refresh(r.Context(), request.RefreshToken)
+	if err != nil {
+		respondWithAuthError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	helpers.RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// Logout revokes the refresh token
+func (h *authHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var request domain.RefreshRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	if err := h.service.Logout(r.Context(), request.RefreshToken); err != nil {
+		respondWithAuthError(w, r, err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// Get the JSON Web Key Set
+func (h *authHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	jwks, err := h.service.JWKS(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Signing key", "Error getting signing keys"))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	helpers.RespondWithJSON(w, http.StatusOK, jwks)
+}
+
+// Rotate the signing key
+func (h *authHandler) RotateKeys(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.RotateKeys(r.Context()); err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Signing key", "Error rotating signing keys"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+func respondWithAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case stdErrors.Is(err, identity.ErrInvalidCredentials):
+		helpers.RespondWithError(w, r, errors.NewUnauthorized("Invalid email or password"))
+	case stdErrors.Is(err, identity.ErrUserDisabled):
+		helpers.RespondWithError(w, r, errors.NewForbidden("User is disabled"))
+	case stdErrors.Is(err, auth.ErrInvalidRefreshToken), stdErrors.Is(err, auth.ErrRefreshTokenReused):
+		helpers.RespondWithError(w, r, errors.NewUnauthorized("Invalid refresh token"))
+	default:
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error authenticating"))
+	}
+}
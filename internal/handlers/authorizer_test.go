@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/middlewares"
+)
+
+// allowAllAuthorizer lets every request through
+type allowAllAuthorizer struct{}
+
+func (allowAllAuthorizer) RequirePermission(permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return next
+	}
+}
+
+func (a allowAllAuthorizer) RequireSelfOrPermission(permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return a.RequirePermission(permission)
+}
+
+// denyAllAuthorizer rejects every protected request
+type denyAllAuthorizer struct{}
+
+func (denyAllAuthorizer) RequirePermission(permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			helpers.RespondWithError(w, r, errors.NewForbidden("Forbidden"))
+		}
+	}
+}
+
+func (a denyAllAuthorizer) RequireSelfOrPermission(permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return a.RequirePermission(permission)
+}
+
+// selfOnlyAuthorizer authenticates with fakeAuthenticate and grants no
+// permission, so callers only get through to their own {id}
+type selfOnlyAuthorizer struct{}
+
+func (selfOnlyAuthorizer) RequirePermission(permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return fakeAuthenticate(func(w http.ResponseWriter, r *http.Request) {
+			helpers.RespondWithError(w, r, errors.NewForbidden("Forbidden"))
+		})
+	}
+}
+
+func (selfOnlyAuthorizer) RequireSelfOrPermission(permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return fakeAuthenticate(func(w http.ResponseWriter, r *http.Request) {
+			if userID, _ := middlewares.UserIDFromContext(r.Context()); r.PathValue("id") != userID {
+				helpers.RespondWithError(w, r, errors.NewForbidden("Forbidden"))
+				return
+			}
+			next(w, r)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Jacobo0312/go-web/config"
+	"github.com/Jacobo0312/go-web/internal/rbac"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+)
+
+// ConfigHandler interface
+type ConfigHandler interface {
+	GetConfigStatus(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type configHandler struct {
+	status     func() config.Status
+	authorizer rbac.Authorizer
+}
+
+// NewConfigHandler return a new ConfigHandler reporting the configuration
+// status returned by status
+func NewConfigHandler(status func() config.Status, authorizer rbac.Authorizer) ConfigHandler {
+	return &configHandler{status: status, authorizer: authorizer}
+}
+
+// Register routes
+func (h *configHandler) RegisterRoutes(r *http.ServeMux) {
+	//Admin routes
+	r.HandleFunc("GET /admin/config", h.authorizer.RequirePermission(rbac.PermissionConfigRead)(h.GetConfigStatus))
+}
+
+// Get the version of the active configuration and the outcome of the last
+// reload
+func (h *configHandler) GetConfigStatus(w http.ResponseWriter, r *http.Request) {
+	helpers.RespondWithJSON(w, http.StatusOK, h.status())
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/config"
+	"github.com/Jacobo0312/go-web/pkg/test"
+)
+
+func TestConfigHandler(t *testing.T) {
+	failedAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	status := func() config.Status {
+		return config.Status{
+			Version:        3,
+			LoadedAt:       time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+			Checksum:       "9f86d08",
+			PendingRestart: []string{"server.addr"},
+			LastError:      "log.level: must be \"debug\", \"info\", \"warn\" or \"error\", got \"loud\"",
+			LastErrorAt:    &failedAt,
+		}
+	}
+
+	mux := http.NewServeMux()
+	NewConfigHandler(status, allowAllAuthorizer{}).RegisterRoutes(mux)
+	test.ExecuteHandlerTestCase(t, mux, test.HandlerTestCase{
+		Name:             "Get config status",
+		Method:           http.MethodGet,
+		URL:              "/admin/config",
+		ExpectedStatus:   http.StatusOK,
+		ExpectedResponse: `{"version":3,"loaded_at":"2024-05-01T12:00:00Z","checksum":"9f86d08","pending_restart":["server.addr"],"last_error":"log.level: must be \"debug\", \"info\", \"warn\" or \"error\", got \"loud\"","last_error_at":"2024-05-01T12:30:00Z"}`,
+	})
+
+	mux = http.NewServeMux()
+	NewConfigHandler(status, denyAllAuthorizer{}).RegisterRoutes(mux)
+	test.ExecuteHandlerTestCase(t, mux, test.HandlerTestCase{
+		Name:             "Get config status without permission",
+		Method:           http.MethodGet,
+		URL:              "/admin/config",
+		ExpectedStatus:   http.StatusForbidden,
+		ExpectedResponse: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"Forbidden","code":"forbidden","instance":"/admin/config"}`,
+	})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/push"
+	"github.com/Jacobo0312/go-web/internal/rbac"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+)
+
+// DeviceHandler interface
+type DeviceHandler interface {
+	GetDevices(w http.ResponseWriter, r *http.Request)
+	RegisterDevice(w http.ResponseWriter, r *http.Request)
+	DeleteDevice(w http.ResponseWriter, r *http.Request)
+	GetPreferences(w http.ResponseWriter, r *http.Request)
+	UpdatePreferences(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type deviceHandler struct {
+	service    push.PushService
+	authorizer rbac.Authorizer
+}
+
+func NewDeviceHandler(service push.PushService, authorizer rbac.Authorizer) DeviceHandler {
+	return &deviceHandler{service: service, authorizer: authorizer}
+}
+
+// Register routes
+func (h *deviceHandler) RegisterRoutes(r *http.ServeMux) {
+	//Owner or admin routes
+	canManage := h.authorizer.RequireSelfOrPermission(rbac.PermissionUsersWrite)
+
+	r.HandleFunc("GET /users/{id}/devices", canManage(h.GetDevices))
+	r.HandleFunc("POST /users/{id}/devices", canManage(h.RegisterDevice))
+	r.HandleFunc("DELETE /users/{id}/devices/{deviceID}", canManage(h.DeleteDevice))
+	r.HandleFunc("GET /users/{id}/notification-preferences", canManage(h.GetPreferences))
+	r.HandleFunc("PUT /users/{id}/notification-preferences", canManage(h.UpdatePreferences))
+}
+
+// Get the devices of a User
+func (h *deviceHandler) GetDevices(w http.ResponseWriter, r *http.Request) {
+	devices, err := h.service.GetDevices(r.Context(), r.PathValue("id"))
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Device", "Error getting devices"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, devices)
+}
+
+// Register a push token for a User
+func (h *deviceHandler) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	var request domain.RegisterDeviceRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	device, err := h.service.RegisterDevice(r.Context(), r.PathValue("id"), &request)
+	if stdErrors.Is(err, push.ErrInvalidPlatform) {
+		helpers.RespondWithError(w, r, errors.NewBadRequest(err.Error(), err))
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Device", "Error registering device"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, device)
+}
+
+// Unregister a device
+func (h *deviceHandler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	deviceID, err := strconv.ParseInt(r.PathValue("deviceID"), 10, 64)
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.NewBadRequest("Invalid device id", err))
+		return
+	}
+
+	err = h.service.DeleteDevice(r.Context(), r.PathValue("id"), deviceID)
+	if stdErrors.Is(err, push.ErrDeviceNotFound) {
+		helpers.RespondWithError(w, r, errors.NewNotFound("Device not found", err))
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Device", "Error deleting device"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// Get the push notification preferences of a User
+func (h *deviceHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	preferences, err := h.service.GetPreferences(r.Context(), r.PathValue("id"))
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Notification preferences", "Error getting notification preferences"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, preferences)
+}
+
+// Replace the push notification preferences of a User
+func (h *deviceHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var preferences domain.NotificationPreferences
+	if err := helpers.DecodeJSON(w, r, &preferences); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	if err := h.service.UpdatePreferences(r.Context(), r.PathValue("id"), &preferences); err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Notification preferences", "Error updating notification preferences"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, preferences)
+}
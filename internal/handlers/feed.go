@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/feed"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+)
+
+const feedCacheControl = "public, max-age=3600"
+
+// FeedHandler interface
+type FeedHandler interface {
+	GetProductFeed(w http.ResponseWriter, r *http.Request)
+	GetSitemap(w http.ResponseWriter, r *http.Request)
+	GetSitemapPage(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type feedHandler struct {
+	service feed.FeedService
+}
+
+func NewFeedHandler(service feed.FeedService) FeedHandler {
+	return &feedHandler{service: service}
+}
+
+// Register routes
+func (h *feedHandler) RegisterRoutes(r *http.ServeMux) {
+	r.HandleFunc("GET /feeds/products.xml", h.GetProductFeed)
+	r.HandleFunc("GET /sitemap.xml", h.GetSitemap)
+	r.HandleFunc("GET /sitemaps/{file}", h.GetSitemapPage)
+}
+
+// Get Google Merchant product feed
+func (h *feedHandler) GetProductFeed(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetStats(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Product", "Error getting product feed"))
+		return
+	}
+
+	if notModified(w, r, stats) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := h.service.WriteProductFeed(r.Context(), w); err != nil {
+		logging.FromContext(r.Context()).Error("Error writing product feed", "error", err)
+	}
+}
+
+// Get sitemap or sitemap index
+func (h *feedHandler) GetSitemap(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.service.GetStats(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Product", "Error getting sitemap"))
+		return
+	}
+
+	if notModified(w, r, stats) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := h.service.WriteSitemap(r.Context(), w, stats); err != nil {
+		logging.FromContext(r.Context()).Error("Error writing sitemap", "error", err)
+	}
+}
+
+// Get a paginated sitemap file referenced from the sitemap index
+func (h *feedHandler) GetSitemapPage(w http.ResponseWriter, r *http.Request) {
+	var page int
+	if _, err := fmt.Sscanf(r.PathValue("file"), "products-%d.xml", &page); err != nil || page < 1 {
+		helpers.RespondWithError(w, r, errors.NewNotFound("Sitemap not found", err))
+		return
+	}
+
+	stats, err := h.service.GetStats(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Product", "Error getting sitemap"))
+		return
+	}
+
+	if page > feed.SitemapPages(stats.Count) {
+		helpers.RespondWithError(w, r, errors.NewNotFound("Sitemap not found", nil))
+		return
+	}
+
+	if notModified(w, r, stats) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := h.service.WriteSitemapPage(r.Context(), w, page); err != nil {
+		logging.FromContext(r.Context()).Error("Error writing sitemap page", "page", page, "error", err)
+	}
+}
+
+// notModified sets the caching headers and answers 304 when the client copy
+// is still fresh. Deleting a product leaves the newest update time as is, so
+// freshness is judged by the ETag, which covers the count too, and
+// If-Modified-Since is ignored.
+func notModified(w http.ResponseWriter, r *http.Request, stats *domain.ProductFeedStats) bool {
+	w.Header().Set("Cache-Control", feedCacheControl)
+	if stats.Count == 0 && stats.LastModified.IsZero() {
+		return false
+	}
+
+	lastModified := stats.LastModified.UTC().Truncate(time.Second)
+	etag := fmt.Sprintf(`W/"%d-%d"`, stats.Count, lastModified.Unix())
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// etagMatches reports whether the If-None-Match header lists etag. The
+// comparison is weak, as RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
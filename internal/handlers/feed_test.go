@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockFeedService struct {
+	mock.Mock
+}
+
+func (m *mockFeedService) GetStats(ctx context.Context) (*domain.ProductFeedStats, error) {
+	args := m.Called()
+	return args.Get(0).(*domain.ProductFeedStats), args.Error(1)
+}
+
+func (m *mockFeedService) WriteProductFeed(ctx context.Context, w io.Writer) error {
+	args := m.Called()
+	if _, err := io.WriteString(w, "<rss></rss>"); err != nil {
+		return err
+	}
+	return args.Error(0)
+}
+
+func (m *mockFeedService) WriteSitemap(ctx context.Context, w io.Writer, stats *domain.ProductFeedStats) error {
+	args := m.Called(stats)
+	return args.Error(0)
+}
+
+func (m *mockFeedService) WriteSitemapPage(ctx context.Context, w io.Writer, page int) error {
+	args := m.Called(page)
+	return args.Error(0)
+}
+
+func TestHandlerProductFeedConditionalGet(t *testing.T) {
+	mockService := new(mockFeedService)
+	mux := http.NewServeMux()
+	NewFeedHandler(mockService).RegisterRoutes(mux)
+
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/feeds/products.xml", nil)
+		if header != nil {
+			req.Header = header
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	lastModified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mockService.On("GetStats").Return(&domain.ProductFeedStats{Count: 3, LastModified: lastModified}, nil).Twice()
+	mockService.On("WriteProductFeed").Return(nil).Once()
+
+	first := get(nil)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "<rss></rss>", first.Body.String())
+	etag := first.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", first.Header().Get("Last-Modified"))
+
+	conditional := http.Header{
+		"If-None-Match":     {etag},
+		"If-Modified-Since": {first.Header().Get("Last-Modified")},
+	}
+	assert.Equal(t, http.StatusNotModified, get(conditional).Code)
+
+	// A product is deleted: the newest update time stays the same
+	mockService.On("GetStats").Return(&domain.ProductFeedStats{Count: 2, LastModified: lastModified}, nil).Once()
+	mockService.On("WriteProductFeed").Return(nil).Once()
+
+	afterDelete := get(conditional)
+	assert.Equal(t, http.StatusOK, afterDelete.Code)
+	assert.NotEqual(t, etag, afterDelete.Header().Get("ETag"))
+
+	mockService.AssertExpectations(t)
+}
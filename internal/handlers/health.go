@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Jacobo0312/go-web/pkg/health"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+)
+
+// HealthHandler interface
+type HealthHandler interface {
+	Liveness(w http.ResponseWriter, r *http.Request)
+	Readiness(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type healthHandler struct {
+	liveness  *health.Registry
+	readiness *health.Registry
+	draining  func() bool
+}
+
+// NewHealthHandler return a new HealthHandler. Readiness fails without
+// running the checks while draining returns true.
+func NewHealthHandler(liveness, readiness *health.Registry, draining func() bool) HealthHandler {
+	return &healthHandler{liveness: liveness, readiness: readiness, draining: draining}
+}
+
+// Register routes
+func (h *healthHandler) RegisterRoutes(r *http.ServeMux) {
+	r.HandleFunc("GET /healthz", h.Liveness)
+	r.HandleFunc("GET /readyz", h.Readiness)
+}
+
+// Report whether the process should be restarted
+func (h *healthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	respondWithHealthReport(w, r, h.liveness.Run(r.Context()))
+}
+
+// Report whether the server can take traffic
+func (h *healthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	if h.draining() {
+		respondWithHealthReport(w, r, health.Report{
+			Status: health.StatusFail,
+			Checks: []health.Result{{Name: "shutdown", Status: health.StatusFail, Error: "server is shutting down"}},
+		})
+		return
+	}
+
+	respondWithHealthReport(w, r, h.readiness.Run(r.Context()))
+}
+
+// respondWithHealthReport writes only the status for load balancers, or the
+// whole report with ?verbose
+func respondWithHealthReport(w http.ResponseWriter, r *http.Request, report health.Report) {
+	code := http.StatusOK
+	if !report.OK() {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if r.URL.Query().Has("verbose") {
+		helpers.RespondWithJSON(w, code, report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	fmt.Fprintln(w, report.Status)
+}
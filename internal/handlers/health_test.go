@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/pkg/health"
+	"github.com/Jacobo0312/go-web/pkg/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthHandler(t *testing.T) {
+	options := health.RegistryOptions{Timeout: time.Second}
+	liveness := health.NewRegistry(options)
+	readiness := health.NewRegistry(options)
+	readiness.Register("database", health.CheckerFunc(func(ctx context.Context) error { return nil }))
+	readiness.Register("identity_provider", health.CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }))
+
+	draining := false
+	mux := http.NewServeMux()
+	NewHealthHandler(liveness, readiness, func() bool { return draining }).RegisterRoutes(mux)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Liveness", "/healthz", http.StatusOK, "ok\n"},
+		{"Readiness with a failing check", "/readyz", http.StatusServiceUnavailable, "fail\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+			assert.Equal(t, tc.expectedBody, rr.Body.String())
+		})
+	}
+
+	t.Run("Verbose readiness", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz?verbose", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"name":"database","status":"ok"`)
+		assert.Contains(t, rr.Body.String(), `"name":"identity_provider","status":"fail","error":"connection refused"`)
+	})
+
+	draining = true
+	test.ExecuteHandlerTestCase(t, mux, test.HandlerTestCase{
+		Name:             "Readiness while shutting down",
+		Method:           http.MethodGet,
+		URL:              "/readyz?verbose",
+		ExpectedStatus:   http.StatusServiceUnavailable,
+		ExpectedResponse: `{"status":"fail","checks":[{"name":"shutdown","status":"fail","error":"server is shutting down","latency_ms":0,"checked_at":"0001-01-01T00:00:00Z","cached":false}]}`,
+	})
+}
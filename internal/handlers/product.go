@@ -1,13 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/Jacobo0312/go-web/internal/domain"
 	"github.com/Jacobo0312/go-web/internal/product"
-
-	//"github.com/Jacobo0312/go-web/pkg/middlewares"
+	"github.com/Jacobo0312/go-web/internal/rbac"
 	"github.com/Jacobo0312/go-web/pkg/errors"
 	"github.com/Jacobo0312/go-web/pkg/helpers"
 )
@@ -23,35 +21,36 @@ type ProductHandler interface {
 }
 
 type productHandler struct {
-	service product.ProductService
+	service    product.ProductService
+	authorizer rbac.Authorizer
 }
 
-func NewProductHandler(service product.ProductService) ProductHandler {
-	return &productHandler{service: service}
+func NewProductHandler(service product.ProductService, authorizer rbac.Authorizer) ProductHandler {
+	return &productHandler{service: service, authorizer: authorizer}
 }
 
 // Register routes
 func (h *productHandler) RegisterRoutes(r *http.ServeMux) {
-	r.HandleFunc("POST /products", h.CreateProduct)
-	//Protected route
-	//r.HandleFunc("GET /products", middlewares.FirebaseAuthMiddleware(h.GetAllProducts))
+	//Protected routes
+	canWrite := h.authorizer.RequirePermission(rbac.PermissionProductsWrite)
+
+	r.HandleFunc("POST /products", canWrite(h.CreateProduct))
 	r.HandleFunc("GET /products", h.GetAllProducts)
 	r.HandleFunc("GET /products/{id}", h.GetProductByID)
-	r.HandleFunc("PUT /products", h.UpdateProduct)
-	r.HandleFunc("DELETE /products/{id}", h.DeleteProduct)
+	r.HandleFunc("PUT /products", canWrite(h.UpdateProduct))
+	r.HandleFunc("DELETE /products/{id}", canWrite(h.DeleteProduct))
 }
 
 func (h *productHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	var product domain.Product
-	err := json.NewDecoder(r.Body).Decode(&product)
-	if err != nil {
-		helpers.RespondWithError(w, errors.NewBadRequest("Invalid request payload", err))
+	if err := helpers.DecodeJSON(w, r, &product); err != nil {
+		helpers.RespondWithError(w, r, err)
 		return
 	}
 
-	err = h.service.CreateProduct(&product)
+	err := h.service.CreateProduct(r.Context(), &product)
 	if err != nil {
-		helpers.RespondWithError(w, errors.NewInternalServerError("Error creating product", err))
+		helpers.RespondWithError(w, r, errors.Classify(err, "Product", "Error creating product"))
 		return
 	}
 
@@ -60,16 +59,9 @@ func (h *productHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 
 // Get All Products
 func (h *productHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
-	// Get userID from context
-	// userID, ok := r.Context().Value("userID").(string)
-	// if !ok {
-	// 	http.Error(w, "Invalid user", http.StatusUnauthorized)
-	// 	return
-	// }
-	//------------------------
-	products, err := h.service.GetAllProducts()
+	products, err := h.service.GetAllProducts(r.Context())
 	if err != nil {
-		helpers.RespondWithError(w, errors.NewInternalServerError("Error getting products", err))
+		helpers.RespondWithError(w, r, errors.Classify(err, "Product", "Error getting products"))
 		return
 	}
 
@@ -82,14 +74,14 @@ func (h *productHandler) GetProductByID(w http.ResponseWriter, r *http.Request)
 	id, err := helpers.ReadIdParam(r)
 
 	if err != nil {
-		helpers.RespondWithError(w, errors.NewBadRequest("Invalid product ID", err))
+		helpers.RespondWithError(w, r, errors.NewBadRequest("Invalid product ID", err))
 		return
 	}
 
-	product, err := h.service.GetProductByID(id)
+	product, err := h.service.GetProductByID(r.Context(), id)
 
 	if err != nil {
-		helpers.RespondWithError(w, errors.NewNotFound("Product not found", err))
+		helpers.RespondWithError(w, r, errors.Classify(err, "Product", "Error getting product"))
 		return
 	}
 
@@ -100,15 +92,14 @@ func (h *productHandler) GetProductByID(w http.ResponseWriter, r *http.Request)
 // Update Product
 func (h *productHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 	var product domain.Product
-	err := json.NewDecoder(r.Body).Decode(&product)
-	if err != nil {
-		helpers.RespondWithError(w, errors.NewBadRequest("Invalid request payload", err))
+	if err := helpers.DecodeJSON(w, r, &product); err != nil {
+		helpers.RespondWithError(w, r, err)
 		return
 	}
 
-	err = h.service.UpdateProduct(&product)
+	err := h.service.UpdateProduct(r.Context(), &product)
 	if err != nil {
-		helpers.RespondWithError(w, errors.NewInternalServerError("Error updating product", err))
+		helpers.RespondWithError(w, r, errors.Classify(err, "Product", "Error updating product"))
 		return
 	}
 
@@ -119,13 +110,13 @@ func (h *productHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 func (h *productHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 	id, err := helpers.ReadIdParam(r)
 	if err != nil {
-		helpers.RespondWithError(w, errors.NewBadRequest("Invalid product ID", err))
+		helpers.RespondWithError(w, r, errors.NewBadRequest("Invalid product ID", err))
 		return
 	}
 
-	err = h.service.DeleteProduct(id)
+	err = h.service.DeleteProduct(r.Context(), id)
 	if err != nil {
-		helpers.RespondWithError(w, errors.NewInternalServerError("Error deleting product", err))
+		helpers.RespondWithError(w, r, errors.Classify(err, "Product", "Error deleting product"))
 		return
 	}
 
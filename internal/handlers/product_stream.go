@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+)
+
+// streamResetEvent tells the client that missed events cannot be replayed,
+// so it has to reload the products
+const streamResetEvent = "reset"
+
+// ProductStreamHandler interface
+type ProductStreamHandler interface {
+	StreamProducts(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type productStreamHandler struct {
+	broker    *events.Broker
+	heartbeat time.Duration
+}
+
+func NewProductStreamHandler(broker *events.Broker, heartbeat time.Duration) ProductStreamHandler {
+	return &productStreamHandler{broker: broker, heartbeat: heartbeat}
+}
+
+// Register routes
+func (h *productStreamHandler) RegisterRoutes(r *http.ServeMux) {
+	r.HandleFunc("GET /products/stream", h.StreamProducts)
+}
+
+// Stream product changes as server-sent events. Clients reconnecting with
+// Last-Event-ID get the events they missed, or a reset event when those are
+// no longer available.
+func (h *productStreamHandler) StreamProducts(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		helpers.RespondWithError(w, r, errors.NewInternalServerError("Streaming is not supported", nil))
+		return
+	}
+
+	var lastEventID int64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || id < 0 {
+			helpers.RespondWithError(w, r, errors.NewBadRequest("Invalid Last-Event-ID", err))
+			return
+		}
+		lastEventID = id
+	}
+
+	sub, backlog, resumed, err := h.broker.Subscribe(lastEventID)
+	if stdErrors.Is(err, events.ErrTooManySubscribers) {
+		w.Header().Set("Retry-After", "5")
+		helpers.RespondWithError(w, r, errors.New(http.StatusServiceUnavailable, "Too many open streams", err))
+		return
+	}
+	if stdErrors.Is(err, events.ErrBrokerClosed) {
+		helpers.RespondWithError(w, r, errors.New(http.StatusServiceUnavailable, "Server is shutting down", err))
+		return
+	}
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.NewInternalServerError("Error opening stream", err))
+		return
+	}
+	defer h.broker.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Disable proxy buffering, e.g. in nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !resumed {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", streamResetEvent)
+	}
+	for _, e := range backlog {
+		writeStreamEvent(w, e)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				// Evicted for falling behind. The client reconnects with
+				// Last-Event-ID and gets the backlog.
+				return
+			}
+			writeStreamEvent(w, e)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, e events.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bufio"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/pkg/middlewares"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupProductStreamTest(options events.BrokerOptions) (*events.Broker, *httptest.Server) {
+	broker := events.NewBroker(options)
+	mux := http.NewServeMux()
+	NewProductStreamHandler(broker, 20*time.Millisecond).RegisterRoutes(mux)
+
+	// Streams go through the logging middleware like in the server
+	server := httptest.NewServer(middlewares.LoggingMiddleware(slog.Default(), mux)(mux))
+	return broker, server
+}
+
+func openProductStream(t *testing.T, server *httptest.Server, lastEventID string) (*http.Response, *bufio.Reader) {
+	req, err := http.NewRequest("GET", server.URL+"/products/stream", nil)
+	assert.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// readStreamBlock returns the next event or comment block
+func readStreamBlock(t *testing.T, reader *bufio.Reader) string {
+	var lines []string
+	for {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return ""
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestHandlerStreamProducts(t *testing.T) {
+	broker, server := setupProductStreamTest(events.BrokerOptions{BufferSize: 10, HistorySize: 10})
+	defer server.Close()
+
+	broker.Publish(events.ProductCreated, map[string]int{"id": 1})
+	broker.Publish(events.ProductUpdated, map[string]int{"id": 1})
+
+	t.Run("resumes after Last-Event-ID and streams live events", func(t *testing.T) {
+		resp, reader := openProductStream(t, server, "1")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "id: 2\nevent: product.updated\ndata: {\"id\":1}", readStreamBlock(t, reader))
+
+		broker.Publish(events.ProductDeleted, map[string]int{"id": 1})
+		block := readStreamBlock(t, reader)
+		for block == ": heartbeat" {
+			block = readStreamBlock(t, reader)
+		}
+		assert.Equal(t, "id: 3\nevent: product.deleted\ndata: {\"id\":1}", block)
+	})
+
+	t.Run("sends heartbeats", func(t *testing.T) {
+		resp, reader := openProductStream(t, server, "")
+		defer resp.Body.Close()
+
+		assert.Equal(t, ": heartbeat", readStreamBlock(t, reader))
+	})
+
+	t.Run("unknown Last-Event-ID resets the client", func(t *testing.T) {
+		resp, reader := openProductStream(t, server, "99")
+		defer resp.Body.Close()
+
+		assert.Equal(t, "event: reset\ndata: {}", readStreamBlock(t, reader))
+	})
+
+	t.Run("invalid Last-Event-ID", func(t *testing.T) {
+		resp, _ := openProductStream(t, server, "abc")
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestHandlerStreamProductsTooManyStreams(t *testing.T) {
+	_, server := setupProductStreamTest(events.BrokerOptions{BufferSize: 1, MaxSubscribers: 1})
+	defer server.Close()
+
+	first, reader := openProductStream(t, server, "")
+	defer first.Body.Close()
+	readStreamBlock(t, reader)
+
+	second, _ := openProductStream(t, server, "")
+	defer second.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, second.StatusCode)
+	assert.Equal(t, "5", second.Header.Get("Retry-After"))
+}
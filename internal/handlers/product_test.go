@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -16,34 +17,34 @@ type mockProductService struct {
 	mock.Mock
 }
 
-func (m *mockProductService) CreateProduct(product *domain.Product) error {
+func (m *mockProductService) CreateProduct(ctx context.Context, product *domain.Product) error {
 	args := m.Called(product)
 	return args.Error(0)
 }
 
-func (m *mockProductService) GetAllProducts() ([]domain.Product, error) {
+func (m *mockProductService) GetAllProducts(ctx context.Context) ([]domain.Product, error) {
 	args := m.Called()
 	return args.Get(0).([]domain.Product), args.Error(1)
 }
 
-func (m *mockProductService) GetProductByID(id int64) (*domain.Product, error) {
+func (m *mockProductService) GetProductByID(ctx context.Context, id int64) (*domain.Product, error) {
 	args := m.Called(id)
 	return args.Get(0).(*domain.Product), args.Error(1)
 }
 
-func (m *mockProductService) UpdateProduct(product *domain.Product) error {
+func (m *mockProductService) UpdateProduct(ctx context.Context, product *domain.Product) error {
 	args := m.Called(product)
 	return args.Error(0)
 }
 
-func (m *mockProductService) DeleteProduct(id int64) error {
+func (m *mockProductService) DeleteProduct(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
 func setupProductHandlerTest() (*mockProductService, *http.ServeMux) {
 	mockService := new(mockProductService)
-	handler := NewProductHandler(mockService)
+	handler := NewProductHandler(mockService, allowAllAuthorizer{})
 	mux := http.NewServeMux()
 	handler.RegisterRoutes(mux)
 	return mockService, mux
@@ -67,6 +68,15 @@ func TestHandlerCreateProduct(t *testing.T) {
 			Body:           "invalid json",
 			ExpectedStatus: http.StatusBadRequest,
 		},
+		{
+			Name:           "invalid product",
+			Method:         "POST",
+			URL:            "/products",
+			Body:           `{"name":" ","price":-1}`,
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			ExpectedResponse: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"Invalid request payload","instance":"/products","code":"validation_failed",
+				"errors":[{"field":"name","message":"is required"},{"field":"price","message":"must be at least 0"}]}`,
+		},
 		{
 			Name:           "service error",
 			Method:         "POST",
@@ -82,7 +92,7 @@ func TestHandlerCreateProduct(t *testing.T) {
 			mockService.On("CreateProduct", product).Return(nil).Once()
 		} else if tc.Name == "service error" {
 			product := &domain.Product{Name: "Error Product", Price: 19.99}
-			mockService.On("CreateProduct", product).Return(errors.NewBadRequest("Invalid request payload", nil)).Once()
+			mockService.On("CreateProduct", product).Return(fmt.Errorf("connection reset")).Once()
 		}
 
 		test.ExecuteHandlerTestCase(t, mux, tc)
@@ -146,12 +156,12 @@ func TestHandlerGetProductByID(t *testing.T) {
 			ExpectedStatus:   http.StatusOK,
 			ExpectedResponse: `{"id":1,"name":"Audifonos","price":19.99,"description":"Marca KZ","category":"Audio"}`,
 		},
-		//{
-		// 	Name:           "product not found",
-		// 	Method:         "GET",
-		// 	URL:            "/products/999",
-		// 	ExpectedStatus: http.StatusNotFound,
-		// },
+		{
+			Name:           "product not found",
+			Method:         "GET",
+			URL:            "/products/999",
+			ExpectedStatus: http.StatusNotFound,
+		},
 		{
 			Name:           "invalid id",
 			Method:         "GET",
@@ -162,7 +172,7 @@ func TestHandlerGetProductByID(t *testing.T) {
 
 	for _, tc := range testCases {
 		if tc.Name == "product not found" {
-			mockService.On("GetProductByID", int64(999)).Return(nil, fmt.Errorf("Not found")).Once()
+			mockService.On("GetProductByID", int64(999)).Return((*domain.Product)(nil), fmt.Errorf("product %w", errors.ErrNotFound)).Once()
 		} else if tc.Name == "successful retrieval" {
 			mockService.On("GetProductByID", int64(1)).Return(product, nil).Once()
 		}
@@ -184,12 +194,18 @@ func TestHandlerDeleteProduct(t *testing.T) {
 			URL:            "/products/1",
 			ExpectedStatus: http.StatusNoContent,
 		},
-		// {
-		// 	Name:           "product not found",
-		// 	Method:         "DELETE",
-		// 	URL:            "/products/999",
-		// 	ExpectedStatus: http.StatusNotFound,
-		// },
+		{
+			Name:           "product not found",
+			Method:         "DELETE",
+			URL:            "/products/999",
+			ExpectedStatus: http.StatusNotFound,
+		},
+		{
+			Name:           "database unavailable",
+			Method:         "DELETE",
+			URL:            "/products/2",
+			ExpectedStatus: http.StatusServiceUnavailable,
+		},
 		// {
 		// 	Name:           "invalid id",
 		// 	Method:         "DELETE",
@@ -202,7 +218,9 @@ func TestHandlerDeleteProduct(t *testing.T) {
 		if tc.Name == "successful deletion" {
 			mockService.On("DeleteProduct", int64(1)).Return(nil).Once()
 		} else if tc.Name == "product not found" {
-			mockService.On("DeleteProduct", int64(999)).Return(errors.NewNotFound("Product not found", nil)).Once()
+			mockService.On("DeleteProduct", int64(999)).Return(fmt.Errorf("%w: no rows affected", errors.ErrNotFound)).Once()
+		} else if tc.Name == "database unavailable" {
+			mockService.On("DeleteProduct", int64(2)).Return(fmt.Errorf("%w: bad connection", errors.ErrUnavailable)).Once()
 		} else if tc.Name == "invalid id" {
 			mockService.On("DeleteProduct", int64(0)).Return(errors.NewBadRequest("Invalid product ID", nil)).Once()
 		}
@@ -212,3 +230,48 @@ func TestHandlerDeleteProduct(t *testing.T) {
 
 	mockService.AssertExpectations(t)
 }
+
+func TestHandlerProductPermissions(t *testing.T) {
+	mockService := new(mockProductService)
+	handler := NewProductHandler(mockService, denyAllAuthorizer{})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	testCases := []test.HandlerTestCase{
+		{
+			Name:           "create forbidden",
+			Method:         "POST",
+			URL:            "/products",
+			Body:           `{"name":"Test Product","price":9.99}`,
+			ExpectedStatus: http.StatusForbidden,
+		},
+		{
+			Name:           "update forbidden",
+			Method:         "PUT",
+			URL:            "/products",
+			Body:           `{"id":1,"name":"Test Product","price":9.99}`,
+			ExpectedStatus: http.StatusForbidden,
+		},
+		{
+			Name:           "delete forbidden",
+			Method:         "DELETE",
+			URL:            "/products/1",
+			ExpectedStatus: http.StatusForbidden,
+		},
+		{
+			Name:             "list is public",
+			Method:           "GET",
+			URL:              "/products",
+			ExpectedStatus:   http.StatusOK,
+			ExpectedResponse: `[]`,
+		},
+	}
+
+	mockService.On("GetAllProducts").Return([]domain.Product{}, nil).Once()
+
+	for _, tc := range testCases {
+		test.ExecuteHandlerTestCase(t, mux, tc)
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/rbac"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+)
+
+// RoleHandler interface
+type RoleHandler interface {
+	GetRoles(w http.ResponseWriter, r *http.Request)
+	GetRole(w http.ResponseWriter, r *http.Request)
+	CreateRole(w http.ResponseWriter, r *http.Request)
+	SetRolePermissions(w http.ResponseWriter, r *http.Request)
+	DeleteRole(w http.ResponseWriter, r *http.Request)
+	GetPermissions(w http.ResponseWriter, r *http.Request)
+	AssignRole(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type roleHandler struct {
+	service    rbac.RBACService
+	authorizer rbac.Authorizer
+}
+
+func NewRoleHandler(service rbac.RBACService, authorizer rbac.Authorizer) RoleHandler {
+	return &roleHandler{service: service, authorizer: authorizer}
+}
+
+// Register routes
+func (h *roleHandler) RegisterRoutes(r *http.ServeMux) {
+	//Admin routes
+	canManage := h.authorizer.RequirePermission(rbac.PermissionRolesManage)
+
+	r.HandleFunc("GET /roles", canManage(h.GetRoles))
+	r.HandleFunc("POST /roles", canManage(h.CreateRole))
+	r.HandleFunc("GET /roles/{name}", canManage(h.GetRole))
+	r.HandleFunc("PUT /roles/{name}/permissions", canManage(h.SetRolePermissions))
+	r.HandleFunc("DELETE /roles/{name}", canManage(h.DeleteRole))
+	r.HandleFunc("GET /permissions", canManage(h.GetPermissions))
+	r.HandleFunc("PUT /users/{id}/role", canManage(h.AssignRole))
+}
+
+// Get All Roles
+func (h *roleHandler) GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.service.GetRoles(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Role", "Error getting roles"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, roles)
+}
+
+// Get Role by name
+func (h *roleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	role, err := h.service.GetRole(r.Context(), r.PathValue("name"))
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Role", "Error getting role"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, role)
+}
+
+// Create Role
+func (h *roleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var role domain.Role
+	if err := helpers.DecodeJSON(w, r, &role); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	err := h.service.CreateRole(r.Context(), &role)
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Role", "Error creating role"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusCreated, role)
+}
+
+// Replace Role permissions
+func (h *roleHandler) SetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	var request domain.UpdateRolePermissionsRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	role, err := h.service.SetRolePermissions(r.Context(), r.PathValue("name"), request.Permissions)
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Role", "Error updating role"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, role)
+}
+
+// Delete Role
+func (h *roleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	err := h.service.DeleteRole(r.Context(), r.PathValue("name"))
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Role", "Error deleting role"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// Get All Permissions
+func (h *roleHandler) GetPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.service.GetPermissions(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Permission", "Error getting permissions"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, permissions)
+}
+
+// Assign a Role to a User
+func (h *roleHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	var request domain.AssignRoleRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	user, err := h.service.AssignRole(r.Context(), r.PathValue("id"), request.Role)
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "User or role", "Error assigning role"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, user)
+}
@@ -1,10 +1,11 @@
 package handlers
 
 import (
-	"encoding/json"
+	stdErrors "errors"
 	"net/http"
 
 	models "github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/rbac"
 	"github.com/Jacobo0312/go-web/internal/user"
 	"github.com/Jacobo0312/go-web/pkg/errors"
 	"github.com/Jacobo0312/go-web/pkg/helpers"
@@ -14,35 +15,50 @@ import (
 type UserHandler interface {
 	CreateUser(w http.ResponseWriter, r *http.Request)
 	GetUsers(w http.ResponseWriter, r *http.Request)
+	GetUserByID(w http.ResponseWriter, r *http.Request)
+	UpdateUser(w http.ResponseWriter, r *http.Request)
+	DeleteUser(w http.ResponseWriter, r *http.Request)
 	RegisterRoutes(r *http.ServeMux)
 }
 
 type userHandler struct {
-	service user.UserService
+	service    user.UserService
+	authorizer rbac.Authorizer
 }
 
-func NewUserHandler(service user.UserService) UserHandler {
-	return &userHandler{service: service}
+func NewUserHandler(service user.UserService, authorizer rbac.Authorizer) UserHandler {
+	return &userHandler{service: service, authorizer: authorizer}
 }
 
 // Register routes
 func (h *userHandler) RegisterRoutes(r *http.ServeMux) {
-	r.HandleFunc("POST /users", h.CreateUser)
-	r.HandleFunc("GET /users", h.GetUsers)
+	//Protected routes
+	canRead := h.authorizer.RequirePermission(rbac.PermissionUsersRead)
+	canReadSelf := h.authorizer.RequireSelfOrPermission(rbac.PermissionUsersRead)
+	canWrite := h.authorizer.RequirePermission(rbac.PermissionUsersWrite)
 
+	r.HandleFunc("POST /users", canWrite(h.CreateUser))
+	r.HandleFunc("GET /users", canRead(h.GetUsers))
+	r.HandleFunc("GET /users/{id}", canReadSelf(h.GetUserByID))
+	r.HandleFunc("PUT /users/{id}", canWrite(h.UpdateUser))
+	r.HandleFunc("DELETE /users/{id}", canWrite(h.DeleteUser))
 }
 
 func (h *userHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
-	var user models.CreateUserRequest
-	err := json.NewDecoder(r.Body).Decode(&user)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var request models.CreateUserRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
 		return
 	}
 
-	createUser, err := h.service.CreateUser(r.Context(), &user)
+	createUser, err := h.service.CreateUser(r.Context(), &request)
+	if stdErrors.Is(err, user.ErrUnknownRole) {
+		helpers.RespondWithError(w, r, errors.NewValidation("Invalid request payload",
+			errors.Violation{Field: "role", Message: "does not exist"}))
+		return
+	}
 	if err != nil {
-		helpers.RespondWithError(w, errors.NewInternalServerError("Error creating user", err))
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error creating user"))
 		return
 	}
 
@@ -50,11 +66,52 @@ func (h *userHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *userHandler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.service.GetUsers()
+	users, err := h.service.GetUsers(r.Context())
 	if err != nil {
-		helpers.RespondWithError(w, errors.NewInternalServerError("Error getting users", err))
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error getting users"))
 		return
 	}
 
 	helpers.RespondWithJSON(w, http.StatusOK, users)
 }
+
+// Get User by ID
+func (h *userHandler) GetUserByID(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	user, err := h.service.GetUserByID(r.Context(), id)
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error getting user"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, user)
+}
+
+// Update User
+func (h *userHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	var userRequest models.UpdateUserRequest
+	if err := helpers.DecodeJSON(w, r, &userRequest); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	user, err := h.service.UpdateUser(r.Context(), r.PathValue("id"), &userRequest)
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error updating user"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, user)
+}
+
+// Delete User
+func (h *userHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	err := h.service.DeleteUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "User", "Error deleting user"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusNoContent, nil)
+}
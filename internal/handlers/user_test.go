@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/user"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/test"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockUserService struct {
+	mock.Mock
+}
+
+func (m *mockUserService) CreateUser(ctx context.Context, userRequest *domain.CreateUserRequest) (*domain.User, error) {
+	args := m.Called(userRequest)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *mockUserService) GetUsers(ctx context.Context) ([]domain.User, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *mockUserService) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *mockUserService) UpdateUser(ctx context.Context, id string, userRequest *domain.UpdateUserRequest) (*domain.User, error) {
+	args := m.Called(id, userRequest)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *mockUserService) DeleteUser(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func setupUserHandlerTest() (*mockUserService, *http.ServeMux) {
+	mockService := new(mockUserService)
+	handler := NewUserHandler(mockService, allowAllAuthorizer{})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	return mockService, mux
+}
+
+func TestHandlerGetUserByID(t *testing.T) {
+	mockService, mux := setupUserHandlerTest()
+
+	testCases := []test.HandlerTestCase{
+		{
+			Name:             "successful retrieval",
+			Method:           "GET",
+			URL:              "/users/abc",
+			ExpectedStatus:   http.StatusOK,
+			ExpectedResponse: `{"id":"abc","name":"John Doe","email":"john@example.com","role":"user","locale":"","email_verified":false,"disabled":false}`,
+		},
+		{
+			Name:           "user not found",
+			Method:         "GET",
+			URL:            "/users/missing",
+			ExpectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		if tc.Name == "successful retrieval" {
+			user := &domain.User{ID: "abc", Name: "John Doe", Email: "john@example.com", Role: "user"}
+			mockService.On("GetUserByID", "abc").Return(user, nil).Once()
+		} else if tc.Name == "user not found" {
+			mockService.On("GetUserByID", "missing").Return((*domain.User)(nil), fmt.Errorf("user %w", errors.ErrNotFound)).Once()
+		}
+
+		test.ExecuteHandlerTestCase(t, mux, tc)
+	}
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlerCreateUserUnknownRole(t *testing.T) {
+	mockService, mux := setupUserHandlerTest()
+
+	request := &domain.CreateUserRequest{Name: "John Doe", Email: "john@example.com", Password: "secret123", Role: "superuser"}
+	mockService.On("CreateUser", request).Return((*domain.User)(nil), fmt.Errorf("creating user: %w", user.ErrUnknownRole)).Once()
+
+	test.ExecuteHandlerTestCase(t, mux, test.HandlerTestCase{
+		Name:           "unknown role",
+		Method:         "POST",
+		URL:            "/users",
+		Body:           `{"name":"John Doe","email":"john@example.com","password":"secret123","role":"superuser"}`,
+		ExpectedStatus: http.StatusUnprocessableEntity,
+		ExpectedResponse: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"Invalid request payload","instance":"/users","code":"validation_failed",
+			"errors":[{"field":"role","message":"does not exist"}]}`,
+	})
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlerUpdateUser(t *testing.T) {
+	mockService, mux := setupUserHandlerTest()
+
+	name := "John Smith"
+	disabled := true
+
+	testCases := []test.HandlerTestCase{
+		{
+			Name:             "successful update",
+			Method:           "PUT",
+			URL:              "/users/abc",
+			Body:             `{"name":"John Smith","disabled":true}`,
+			ExpectedStatus:   http.StatusOK,
+			ExpectedResponse: `{"id":"abc","name":"John Smith","email":"john@example.com","role":"user","locale":"","email_verified":false,"disabled":true}`,
+		},
+		{
+			Name:           "invalid payload",
+			Method:         "PUT",
+			URL:            "/users/abc",
+			Body:           "invalid json",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "empty email",
+			Method:         "PUT",
+			URL:            "/users/abc",
+			Body:           `{"email":""}`,
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			ExpectedResponse: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"Invalid request payload","instance":"/users/abc","code":"validation_failed",
+				"errors":[{"field":"email","message":"must not be blank"}]}`,
+		},
+		{
+			Name:           "blank name",
+			Method:         "PUT",
+			URL:            "/users/abc",
+			Body:           `{"name":"   "}`,
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			ExpectedResponse: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"Invalid request payload","instance":"/users/abc","code":"validation_failed",
+				"errors":[{"field":"name","message":"must not be blank"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		if tc.Name == "successful update" {
+			request := &domain.UpdateUserRequest{Name: &name, Disabled: &disabled}
+			user := &domain.User{ID: "abc", Name: name, Email: "john@example.com", Role: "user", Disabled: true}
+			mockService.On("UpdateUser", "abc", request).Return(user, nil).Once()
+		}
+
+		test.ExecuteHandlerTestCase(t, mux, tc)
+	}
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlerDeleteUser(t *testing.T) {
+	mockService, mux := setupUserHandlerTest()
+
+	testCases := []test.HandlerTestCase{
+		{
+			Name:           "successful deletion",
+			Method:         "DELETE",
+			URL:            "/users/abc",
+			ExpectedStatus: http.StatusNoContent,
+		},
+		{
+			Name:           "service error",
+			Method:         "DELETE",
+			URL:            "/users/def",
+			ExpectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		if tc.Name == "successful deletion" {
+			mockService.On("DeleteUser", "abc").Return(nil).Once()
+		} else if tc.Name == "service error" {
+			mockService.On("DeleteUser", "def").Return(fmt.Errorf("firebase error")).Once()
+		}
+
+		test.ExecuteHandlerTestCase(t, mux, tc)
+	}
+
+	mockService.AssertExpectations(t)
+}
+
+func TestHandlerUserReadPermissions(t *testing.T) {
+	mockService := new(mockUserService)
+	handler := NewUserHandler(mockService, selfOnlyAuthorizer{})
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+
+	testCases := []test.HandlerTestCase{
+		{
+			Name:           "list anonymous",
+			Method:         "GET",
+			URL:            "/users",
+			ExpectedStatus: http.StatusUnauthorized,
+		},
+		{
+			Name:             "list forbidden",
+			Method:           "GET",
+			URL:              "/users",
+			Header:           http.Header{"Authorization": {"Bearer abc"}},
+			ExpectedStatus:   http.StatusForbidden,
+			ExpectedResponse: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"Forbidden","code":"forbidden","instance":"/users"}`,
+		},
+		{
+			Name:           "get anonymous",
+			Method:         "GET",
+			URL:            "/users/abc",
+			ExpectedStatus: http.StatusUnauthorized,
+		},
+		{
+			Name:             "get another user forbidden",
+			Method:           "GET",
+			URL:              "/users/def",
+			Header:           http.Header{"Authorization": {"Bearer abc"}},
+			ExpectedStatus:   http.StatusForbidden,
+			ExpectedResponse: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"Forbidden","code":"forbidden","instance":"/users/def"}`,
+		},
+		{
+			Name:             "get self",
+			Method:           "GET",
+			URL:              "/users/abc",
+			Header:           http.Header{"Authorization": {"Bearer abc"}},
+			ExpectedStatus:   http.StatusOK,
+			ExpectedResponse: `{"id":"abc","name":"John Doe","email":"john@example.com","role":"user","locale":"","email_verified":false,"disabled":false}`,
+		},
+	}
+
+	user := &domain.User{ID: "abc", Name: "John Doe", Email: "john@example.com", Role: "user"}
+	mockService.On("GetUserByID", "abc").Return(user, nil).Once()
+
+	for _, tc := range testCases {
+		test.ExecuteHandlerTestCase(t, mux, tc)
+	}
+
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/rbac"
+	"github.com/Jacobo0312/go-web/internal/webhook"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+)
+
+// WebhookHandler interface
+type WebhookHandler interface {
+	GetWebhooks(w http.ResponseWriter, r *http.Request)
+	CreateWebhook(w http.ResponseWriter, r *http.Request)
+	GetWebhook(w http.ResponseWriter, r *http.Request)
+	UpdateWebhook(w http.ResponseWriter, r *http.Request)
+	DeleteWebhook(w http.ResponseWriter, r *http.Request)
+	GetDeliveries(w http.ResponseWriter, r *http.Request)
+	ReplayDelivery(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+}
+
+type webhookHandler struct {
+	service    webhook.WebhookService
+	authorizer rbac.Authorizer
+}
+
+func NewWebhookHandler(service webhook.WebhookService, authorizer rbac.Authorizer) WebhookHandler {
+	return &webhookHandler{service: service, authorizer: authorizer}
+}
+
+// Register routes
+func (h *webhookHandler) RegisterRoutes(r *http.ServeMux) {
+	//Admin routes
+	canManage := h.authorizer.RequirePermission(rbac.PermissionWebhooksManage)
+
+	r.HandleFunc("GET /webhooks", canManage(h.GetWebhooks))
+	r.HandleFunc("POST /webhooks", canManage(h.CreateWebhook))
+	r.HandleFunc("GET /webhooks/{id}", canManage(h.GetWebhook))
+	r.HandleFunc("PUT /webhooks/{id}", canManage(h.UpdateWebhook))
+	r.HandleFunc("DELETE /webhooks/{id}", canManage(h.DeleteWebhook))
+	r.HandleFunc("GET /webhooks/{id}/deliveries", canManage(h.GetDeliveries))
+	r.HandleFunc("POST /webhooks/{id}/deliveries/{deliveryID}/replay", canManage(h.ReplayDelivery))
+}
+
+// Get all webhooks
+func (h *webhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.service.GetWebhooks(r.Context())
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.Classify(err, "Webhook", "Error getting webhooks"))
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, webhooks)
+}
+
+// Create a webhook. The secret is only shown in this response.
+func (h *webhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var request domain.CreateWebhookRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	created, err := h.service.CreateWebhook(r.Context(), &request)
+	if err != nil {
+		respondWithWebhookError(w, r, "Error creating webhook", err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	helpers.RespondWithJSON(w, http.StatusCreated, created)
+}
+
+// Get a webhook by id
+func (h *webhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	hook, err := h.service.GetWebhook(r.Context(), r.PathValue("id"))
+	if err != nil {
+		respondWithWebhookError(w, r, "Error getting webhook", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, hook)
+}
+
+// Update a webhook
+func (h *webhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	var request domain.UpdateWebhookRequest
+	if err := helpers.DecodeJSON(w, r, &request); err != nil {
+		helpers.RespondWithError(w, r, err)
+		return
+	}
+
+	hook, err := h.service.UpdateWebhook(r.Context(), r.PathValue("id"), &request)
+	if err != nil {
+		respondWithWebhookError(w, r, "Error updating webhook", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, hook)
+}
+
+// Delete a webhook and its deliveries
+func (h *webhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteWebhook(r.Context(), r.PathValue("id")); err != nil {
+		respondWithWebhookError(w, r, "Error deleting webhook", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusNoContent, nil)
+}
+
+// Get the latest deliveries of a webhook, filtered by ?status=
+func (h *webhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := h.service.GetDeliveries(r.Context(), r.PathValue("id"), r.URL.Query().Get("status"))
+	if err != nil {
+		respondWithWebhookError(w, r, "Error getting deliveries", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusOK, deliveries)
+}
+
+// Queue a delivery again, including dead ones
+func (h *webhookHandler) ReplayDelivery(w http.ResponseWriter, r *http.Request) {
+	deliveryID, err := strconv.ParseInt(r.PathValue("deliveryID"), 10, 64)
+	if err != nil {
+		helpers.RespondWithError(w, r, errors.NewBadRequest("Invalid delivery id", err))
+		return
+	}
+
+	if err := h.service.ReplayDelivery(r.Context(), r.PathValue("id"), deliveryID); err != nil {
+		respondWithWebhookError(w, r, "Error replaying delivery", err)
+		return
+	}
+
+	helpers.RespondWithJSON(w, http.StatusAccepted, nil)
+}
+
+func respondWithWebhookError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	switch {
+	case stdErrors.Is(err, webhook.ErrWebhookNotFound):
+		helpers.RespondWithError(w, r, errors.NewNotFound("Webhook not found", err))
+	case stdErrors.Is(err, webhook.ErrDeliveryNotFound):
+		helpers.RespondWithError(w, r, errors.NewNotFound("Delivery not found", err))
+	case stdErrors.Is(err, webhook.ErrInvalidURL), stdErrors.Is(err, webhook.ErrInvalidEvents), stdErrors.Is(err, webhook.ErrInvalidStatus):
+		helpers.RespondWithError(w, r, errors.NewBadRequest(err.Error(), err))
+	default:
+		helpers.RespondWithError(w, r, errors.Classify(err, "Webhook", message))
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Jacobo0312/go-web/internal/realtime"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/middlewares"
+	"github.com/gorilla/websocket"
+)
+
+// tokenSubprotocol lets browsers, which cannot set headers on WebSocket
+// requests, send the token as "Sec-WebSocket-Protocol: access_token, <token>"
+const tokenSubprotocol = "access_token"
+
+// WebSocketHandler interface
+type WebSocketHandler interface {
+	Connect(w http.ResponseWriter, r *http.Request)
+	RegisterRoutes(r *http.ServeMux)
+	// SetAllowedOrigins replaces the allowed origins of new connections
+	SetAllowedOrigins(origins []string)
+}
+
+type webSocketHandler struct {
+	hub            *realtime.Hub
+	authenticate   func(http.HandlerFunc) http.HandlerFunc
+	upgrader       websocket.Upgrader
+	allowedOrigins atomic.Pointer[[]string]
+}
+
+// NewWebSocketHandler return a new WebSocketHandler. Connections are only
+// accepted from the server origin and allowedOrigins.
+func NewWebSocketHandler(hub *realtime.Hub, authenticate func(http.HandlerFunc) http.HandlerFunc, allowedOrigins []string) WebSocketHandler {
+	h := &webSocketHandler{
+		hub:          hub,
+		authenticate: authenticate,
+	}
+	h.upgrader = websocket.Upgrader{
+		Subprotocols: []string{tokenSubprotocol},
+		CheckOrigin:  h.checkOrigin,
+	}
+	h.SetAllowedOrigins(allowedOrigins)
+	return h
+}
+
+func (h *webSocketHandler) SetAllowedOrigins(origins []string) {
+	h.allowedOrigins.Store(&origins)
+}
+
+// checkOrigin accepts requests without an Origin, like those of non-browser
+// clients, and from the server origin and the allowed origins
+func (h *webSocketHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host) || slices.Contains(*h.allowedOrigins.Load(), origin)
+}
+
+// Register routes
+func (h *webSocketHandler) RegisterRoutes(r *http.ServeMux) {
+	//Authenticated routes
+	r.HandleFunc("GET /ws", tokenFromSubprotocol(h.authenticate(h.Connect)))
+}
+
+// Upgrade to a WebSocket that pushes the changes of the subscribed topics
+func (h *webSocketHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlewares.UserIDFromContext(r.Context())
+	if !ok {
+		helpers.RespondWithError(w, r, errors.NewUnauthorized("Unauthorized"))
+		return
+	}
+
+	if err := h.hub.Acquire(userID); err != nil {
+		if stdErrors.Is(err, realtime.ErrTooManyConnections) {
+			helpers.RespondWithError(w, r, errors.New(http.StatusTooManyRequests, "Too many open connections", err))
+		} else {
+			helpers.RespondWithError(w, r, errors.New(http.StatusServiceUnavailable, "Server is shutting down", err))
+		}
+		return
+	}
+	defer h.hub.Release(userID)
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already responded
+		return
+	}
+
+	h.hub.Serve(conn, userID)
+}
+
+// tokenFromSubprotocol copies the token offered as a subprotocol to the
+// Authorization header
+func tokenFromSubprotocol(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			protocols := websocket.Subprotocols(r)
+			if len(protocols) == 2 && protocols[0] == tokenSubprotocol {
+				r.Header.Set("Authorization", "Bearer "+protocols[1])
+			}
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/realtime"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/middlewares"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAuthenticate accepts "Bearer <uid>"
+func fakeAuthenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if userID == "" {
+			helpers.RespondWithError(w, r, errors.NewUnauthorized("Unauthorized"))
+			return
+		}
+		next(w, r.WithContext(middlewares.ContextWithUserID(r.Context(), userID)))
+	}
+}
+
+func setupWebSocketTest() (*realtime.Hub, *httptest.Server) {
+	hub := realtime.NewHub(realtime.HubOptions{
+		SendBuffer:            8,
+		MaxConnectionsPerUser: 1,
+		MaxTopics:             2,
+		MaxMessageSize:        1024,
+		PingInterval:          time.Second,
+		PongWait:              2 * time.Second,
+		WriteWait:             time.Second,
+	})
+	mux := http.NewServeMux()
+	NewWebSocketHandler(hub, fakeAuthenticate, nil).RegisterRoutes(mux)
+
+	// Upgrades go through the logging middleware like in the server
+	server := httptest.NewServer(middlewares.LoggingMiddleware(slog.Default(), mux)(mux))
+	return hub, server
+}
+
+func dialWebSocket(server *httptest.Server, header http.Header) (*websocket.Conn, *http.Response, error) {
+	return websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", header)
+}
+
+func readWebSocketMessage(t *testing.T, conn *websocket.Conn) realtime.Message {
+	var m realtime.Message
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	assert.NoError(t, conn.ReadJSON(&m))
+	return m
+}
+
+func TestHandlerWebSocket(t *testing.T) {
+	hub, server := setupWebSocketTest()
+	defer server.Close()
+
+	conn, resp, err := dialWebSocket(server, http.Header{"Authorization": {"Bearer uid-1"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	t.Run("subscribe and receive product changes", func(t *testing.T) {
+		assert.NoError(t, conn.WriteJSON(realtime.Message{Type: realtime.MessageSubscribe, Topic: "products:7"}))
+		assert.Equal(t, realtime.Message{Type: realtime.MessageSubscribed, Topic: "products:7"}, readWebSocketMessage(t, conn))
+
+		hub.Publish("product.updated", &domain.Product{ID: 8, Name: "Other"})
+		hub.Publish("product.updated", &domain.Product{ID: 7, Name: "Laptop"})
+
+		m := readWebSocketMessage(t, conn)
+		assert.Equal(t, realtime.MessageEvent, m.Type)
+		assert.Equal(t, "products:7", m.Topic)
+		assert.Equal(t, "product.updated", m.Event)
+		assert.Contains(t, string(m.Data), `"name":"Laptop"`)
+	})
+
+	t.Run("user topics are scoped to the connection", func(t *testing.T) {
+		assert.NoError(t, conn.WriteJSON(realtime.Message{Type: realtime.MessageSubscribe, Topic: realtime.TopicCart}))
+		readWebSocketMessage(t, conn)
+
+		hub.PublishToUser("uid-2", realtime.TopicCart, "cart.updated", map[string]int{"items": 1})
+		hub.PublishToUser("uid-1", realtime.TopicCart, "cart.updated", map[string]int{"items": 2})
+
+		m := readWebSocketMessage(t, conn)
+		assert.Equal(t, realtime.TopicCart, m.Topic)
+		assert.JSONEq(t, `{"items":2}`, string(m.Data))
+	})
+
+	t.Run("invalid subscriptions", func(t *testing.T) {
+		assert.NoError(t, conn.WriteJSON(realtime.Message{Type: realtime.MessageSubscribe, Topic: "users"}))
+		assert.Equal(t, realtime.MessageError, readWebSocketMessage(t, conn).Type)
+
+		assert.NoError(t, conn.WriteJSON(realtime.Message{Type: realtime.MessageSubscribe, Topic: realtime.TopicOrders}))
+		m := readWebSocketMessage(t, conn)
+		assert.Equal(t, realtime.MessageError, m.Type)
+		assert.Equal(t, realtime.ErrTooManyTopics.Error(), m.Message)
+
+		assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("not json")))
+		assert.Equal(t, realtime.MessageError, readWebSocketMessage(t, conn).Type)
+	})
+
+	t.Run("connection limit per user", func(t *testing.T) {
+		_, resp, err := dialWebSocket(server, http.Header{"Authorization": {"Bearer uid-1"}})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	})
+}
+
+func TestHandlerWebSocketAuthentication(t *testing.T) {
+	_, server := setupWebSocketTest()
+	defer server.Close()
+
+	t.Run("token as subprotocol", func(t *testing.T) {
+		conn, resp, err := dialWebSocket(server, http.Header{"Sec-WebSocket-Protocol": {"access_token, uid-3"}})
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		assert.Equal(t, "access_token", resp.Header.Get("Sec-WebSocket-Protocol"))
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		_, resp, err := dialWebSocket(server, nil)
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("foreign origin", func(t *testing.T) {
+		_, resp, err := dialWebSocket(server, http.Header{"Authorization": {"Bearer uid-4"}, "Origin": {"https://evil.example.com"}})
+		assert.Error(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestWebSocketAllowedOrigins(t *testing.T) {
+	h := NewWebSocketHandler(nil, fakeAuthenticate, []string{"https://app.example.com"}).(*webSocketHandler)
+	fromOrigin := func(origin string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "http://api.example.com/ws", nil)
+		r.Header.Set("Origin", origin)
+		return r
+	}
+
+	assert.True(t, h.checkOrigin(fromOrigin("https://api.example.com")))
+	assert.True(t, h.checkOrigin(fromOrigin("https://app.example.com")))
+	assert.False(t, h.checkOrigin(fromOrigin("https://admin.example.com")))
+
+	h.SetAllowedOrigins([]string{"https://admin.example.com"})
+	assert.False(t, h.checkOrigin(fromOrigin("https://app.example.com")))
+	assert.True(t, h.checkOrigin(fromOrigin("https://admin.example.com")))
+}
@@ -0,0 +1,122 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Jacobo0312/go-web/pkg/database"
+)
+
+// OutboxMessage is a message waiting in the outbox
+type OutboxMessage struct {
+	ID       int64
+	Message  Message
+	Attempts int
+}
+
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, m *Message) error
+	// ClaimDue returns up to limit pending messages due at now and hides
+	// them from other dispatchers for lease
+	ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]OutboxMessage, error)
+	MarkSent(ctx context.Context, id int64, at time.Time) error
+	MarkRetry(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error
+	MarkFailed(ctx context.Context, id int64, attempts int, lastError string) error
+}
+
+type outboxRepository struct {
+	DB       *sql.DB
+	timeouts database.Timeouts
+}
+
+func NewOutboxRepository(db *sql.DB, timeouts database.Timeouts) OutboxRepository {
+	return &outboxRepository{DB: db, timeouts: timeouts}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, m *Message) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "INSERT INTO email_outbox (recipient, subject, text_body, html_body, status, next_attempt_at) VALUES (?, ?, ?, ?, 'pending', ?)"
+	_, err := r.DB.ExecContext(ctx, query, m.To, m.Subject, m.Text, m.HTML, time.Now().UTC())
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer tx.Rollback()
+
+	query := "SELECT id, recipient, subject, text_body, html_body, attempts FROM email_outbox WHERE status = 'pending' AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED"
+	rows, err := tx.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		if err := rows.Scan(&m.ID, &m.Message.To, &m.Message.Subject, &m.Message.Text, &m.Message.HTML, &m.Attempts); err != nil {
+			rows.Close()
+			return nil, database.Translate(err)
+		}
+		messages = append(messages, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, database.Translate(err)
+	}
+
+	for _, m := range messages {
+		if _, err := tx.ExecContext(ctx, "UPDATE email_outbox SET next_attempt_at = ? WHERE id = ?", now.Add(lease), m.ID); err != nil {
+			return nil, database.Translate(err)
+		}
+	}
+
+	return messages, tx.Commit()
+}
+
+func (r *outboxRepository) MarkSent(ctx context.Context, id int64, at time.Time) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE email_outbox SET status = 'sent', attempts = attempts + 1, sent_at = ?, last_error = NULL WHERE id = ?"
+	_, err := r.DB.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkRetry(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE email_outbox SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?"
+	_, err := r.DB.ExecContext(ctx, query, attempts, next, lastError, id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, attempts int, lastError string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE email_outbox SET status = 'failed', attempts = ?, last_error = ? WHERE id = ?"
+	_, err := r.DB.ExecContext(ctx, query, attempts, lastError, id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryEnqueue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewOutboxRepository(db, database.Timeouts{})
+	m := &Message{To: "john@example.com", Subject: "Hi", Text: "Hi", HTML: "<p>Hi</p>"}
+
+	mock.ExpectExec("INSERT INTO email_outbox").WithArgs(m.To, m.Subject, m.Text, m.HTML, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, repo.Enqueue(context.Background(), m))
+}
+
+func TestRepositoryClaimDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewOutboxRepository(db, database.Timeouts{})
+	now := time.Now()
+
+	rows := sqlmock.NewRows([]string{"id", "recipient", "subject", "text_body", "html_body", "attempts"}).
+		AddRow(1, "john@example.com", "Hi", "Hi", "<p>Hi</p>", 2)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM email_outbox WHERE status = 'pending' AND next_attempt_at <= \\? (.+) FOR UPDATE SKIP LOCKED").
+		WithArgs(now, 10).WillReturnRows(rows)
+	mock.ExpectExec("UPDATE email_outbox SET next_attempt_at = \\? WHERE id = \\?").
+		WithArgs(now.Add(time.Minute), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	messages, err := repo.ClaimDue(context.Background(), now, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "john@example.com", messages[0].Message.To)
+	assert.Equal(t, 2, messages[0].Attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,138 @@
+package notification
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WelcomeData is the data of TemplateWelcome
+type WelcomeData struct {
+	Name  string
+	Email string
+}
+
+// LinkData is the data of TemplateVerifyEmail and TemplateResetPassword
+type LinkData struct {
+	Name           string
+	Link           string
+	ExpiresInHours int
+}
+
+// Notifier renders a template and queues the email in the outbox
+type Notifier interface {
+	Notify(ctx context.Context, to, locale, template string, data interface{}) error
+}
+
+type notifier struct {
+	renderer *Renderer
+	repo     OutboxRepository
+}
+
+// NewNotifier return a new Notifier
+func NewNotifier(renderer *Renderer, repo OutboxRepository) Notifier {
+	return &notifier{renderer: renderer, repo: repo}
+}
+
+func (n *notifier) Notify(ctx context.Context, to, locale, template string, data interface{}) error {
+	m, err := n.renderer.Render(locale, template, data)
+	if err != nil {
+		return err
+	}
+	m.To = to
+
+	return n.repo.Enqueue(ctx, m)
+}
+
+// DispatcherOptions configures the outbox polling and retries
+type DispatcherOptions struct {
+	Interval    time.Duration
+	BatchSize   int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Dispatcher sends the outbox messages, retrying failures with exponential
+// backoff until MaxAttempts
+type Dispatcher struct {
+	repo    OutboxRepository
+	mailer  Mailer
+	options DispatcherOptions
+}
+
+// NewDispatcher return a new Dispatcher
+func NewDispatcher(repo OutboxRepository, mailer Mailer, options DispatcherOptions) *Dispatcher {
+	return &Dispatcher{repo: repo, mailer: mailer, options: options}
+}
+
+// Run dispatches the outbox every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchDue(ctx); err != nil {
+			slog.Error("Error dispatching email outbox", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends one batch of due messages and returns how many were sent
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// The lease outlives a slow SMTP exchange so no other instance takes over
+	messages, err := d.repo.ClaimDue(ctx, time.Now().UTC(), d.options.BatchSize, 5*time.Minute)
+	if err != nil {
+		return 0, err
+	}
+
+	// Outcomes are recorded even when ctx is canceled mid-send, so a sent
+	// email is not sent again after a restart
+	record := context.WithoutCancel(ctx)
+
+	sent := 0
+	for _, m := range messages {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+
+		attempts := m.Attempts + 1
+		if err := d.mailer.Send(ctx, &m.Message); err != nil {
+			slog.Warn("Error sending email", "message_id", m.ID, "to", m.Message.To, "attempt", attempts, "error", err)
+			if attempts >= d.options.MaxAttempts {
+				err = d.repo.MarkFailed(record, m.ID, attempts, err.Error())
+			} else {
+				err = d.repo.MarkRetry(record, m.ID, attempts, time.Now().UTC().Add(d.backoff(attempts)), err.Error())
+			}
+			if err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		if err := d.repo.MarkSent(record, m.ID, time.Now().UTC()); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// backoff doubles the wait after every failed attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.options.BaseBackoff
+	for i := 1; i < attempts && wait < d.options.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.options.MaxBackoff {
+		wait = d.options.MaxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,162 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryOutboxRepository keeps the outbox in memory
+type memoryOutboxRepository struct {
+	messages []*memoryOutboxMessage
+}
+
+type memoryOutboxMessage struct {
+	OutboxMessage
+	status    string
+	nextAt    time.Time
+	lastError string
+}
+
+func (r *memoryOutboxRepository) Enqueue(ctx context.Context, m *Message) error {
+	r.messages = append(r.messages, &memoryOutboxMessage{
+		OutboxMessage: OutboxMessage{ID: int64(len(r.messages) + 1), Message: *m},
+		status:        "pending",
+	})
+	return nil
+}
+
+func (r *memoryOutboxRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	var due []OutboxMessage
+	for _, m := range r.messages {
+		if m.status == "pending" && !m.nextAt.After(now) && len(due) < limit {
+			m.nextAt = now.Add(lease)
+			due = append(due, m.OutboxMessage)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryOutboxRepository) MarkSent(ctx context.Context, id int64, at time.Time) error {
+	r.messages[id-1].status = "sent"
+	r.messages[id-1].Attempts++
+	return nil
+}
+
+func (r *memoryOutboxRepository) MarkRetry(ctx context.Context, id int64, attempts int, next time.Time, lastError string) error {
+	m := r.messages[id-1]
+	m.Attempts, m.nextAt, m.lastError = attempts, next, lastError
+	return nil
+}
+
+func (r *memoryOutboxRepository) MarkFailed(ctx context.Context, id int64, attempts int, lastError string) error {
+	m := r.messages[id-1]
+	m.status, m.Attempts, m.lastError = "failed", attempts, lastError
+	return nil
+}
+
+func TestNotifierQueuesRenderedMessage(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	assert.NoError(t, err)
+	repo := &memoryOutboxRepository{}
+	notifier := NewNotifier(renderer, repo)
+
+	err = notifier.Notify(context.Background(), "john@example.com", "es", TemplateWelcome, WelcomeData{Name: "John", Email: "john@example.com"})
+	assert.NoError(t, err)
+
+	assert.Len(t, repo.messages, 1)
+	assert.Equal(t, "john@example.com", repo.messages[0].Message.To)
+	assert.Equal(t, "Bienvenido a go-web, John", repo.messages[0].Message.Subject)
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryOutboxRepository{}
+	mailer := &MemoryMailer{Err: errors.New("connection refused")}
+	dispatcher := NewDispatcher(repo, mailer, DispatcherOptions{
+		BatchSize:   10,
+		MaxAttempts: 3,
+		BaseBackoff: time.Minute,
+		MaxBackoff:  time.Hour,
+	})
+
+	assert.NoError(t, repo.Enqueue(context.Background(), &Message{To: "john@example.com", Subject: "Hi", Text: "Hi"}))
+	m := repo.messages[0]
+
+	sent, err := dispatcher.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Equal(t, 1, m.Attempts)
+	assert.Equal(t, "pending", m.status)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), m.nextAt, 5*time.Second)
+
+	// Not due yet
+	sent, err = dispatcher.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Equal(t, 1, m.Attempts)
+
+	m.nextAt = time.Time{}
+	_, err = dispatcher.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, m.Attempts)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), m.nextAt, 5*time.Second)
+
+	mailer.Err = nil
+	m.nextAt = time.Time{}
+	sent, err = dispatcher.DispatchDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, "sent", m.status)
+	assert.Len(t, mailer.Messages(), 1)
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	repo := &memoryOutboxRepository{}
+	dispatcher := NewDispatcher(repo, &MemoryMailer{Err: errors.New("mailbox unavailable")}, DispatcherOptions{
+		BatchSize:   10,
+		MaxAttempts: 1,
+		BaseBackoff: time.Minute,
+		MaxBackoff:  time.Hour,
+	})
+
+	assert.NoError(t, repo.Enqueue(context.Background(), &Message{To: "john@example.com", Subject: "Hi", Text: "Hi"}))
+
+	_, err := dispatcher.DispatchDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "failed", repo.messages[0].status)
+	assert.Equal(t, "mailbox unavailable", repo.messages[0].lastError)
+}
+
+func TestDispatcherBackoffIsCapped(t *testing.T) {
+	dispatcher := NewDispatcher(nil, nil, DispatcherOptions{BaseBackoff: time.Minute, MaxBackoff: 10 * time.Minute})
+
+	assert.Equal(t, time.Minute, dispatcher.backoff(1))
+	assert.Equal(t, 8*time.Minute, dispatcher.backoff(4))
+	assert.Equal(t, 10*time.Minute, dispatcher.backoff(20))
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewFileMailer(dir, "go-web <no-reply@localhost>")
+
+	err := mailer.Send(context.Background(), &Message{To: "john@example.com", Subject: "Restablece tu contraseña", Text: "Hola", HTML: "<p>Hola</p>"})
+	assert.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.True(t, strings.HasSuffix(files[0].Name(), ".eml"))
+
+	content, err := os.ReadFile(dir + "/" + files[0].Name())
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "To: john@example.com")
+	assert.Contains(t, string(content), "Subject: =?UTF-8?q?Restablece_tu_contrase=C3=B1a?=")
+	assert.Contains(t, string(content), "multipart/alternative")
+	assert.Contains(t, string(content), "<p>Hola</p>")
+}
@@ -0,0 +1,69 @@
+package notification
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type fileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer return a Mailer that writes every message as an .eml file
+// in dir, for local development without a mail server
+func NewFileMailer(dir, from string) Mailer {
+	return &fileMailer{dir: dir, from: from}
+}
+
+func (f *fileMailer) Send(ctx context.Context, m *Message) error {
+	msg, err := buildMIME(f.from, m)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000"), hex.EncodeToString(suffix))
+
+	return os.WriteFile(filepath.Join(f.dir, name), msg, 0o644)
+}
+
+// MemoryMailer keeps the sent messages in memory, for tests
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+	// Err, when set, is returned by Send instead of storing the message
+	Err error
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages returns a copy of the sent messages
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
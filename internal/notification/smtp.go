@@ -0,0 +1,89 @@
+package notification
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig holds the connection settings of an SMTP server
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+type smtpMailer struct {
+	config SMTPConfig
+	// rootCAs verifies the server certificate, the system pool when nil
+	rootCAs *x509.CertPool
+}
+
+// NewSMTPMailer return a Mailer that delivers through an SMTP server.
+// STARTTLS is used when the server offers it.
+func NewSMTPMailer(config SMTPConfig) Mailer {
+	return &smtpMailer{config: config}
+}
+
+func (s *smtpMailer) Send(ctx context.Context, m *Message) error {
+	msg, err := buildMIME(s.config.From, m)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(time.Minute))
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host, RootCAs: s.rootCAs}); err != nil {
+			return err
+		}
+	}
+	if s.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.config.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(m.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
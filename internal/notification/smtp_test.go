@@ -0,0 +1,107 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTLSServer is an SMTP server that only accepts mail after STARTTLS
+type startTLSServer struct {
+	listener net.Listener
+	config   *tls.Config
+	data     chan string
+}
+
+func (s *startTLSServer) serve(t *testing.T) {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var encrypted bool
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, err := conn.Write([]byte(line + "\r\n"))
+		assert.NoError(t, err)
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+		case "EHLO":
+			if encrypted {
+				reply("250 localhost")
+			} else {
+				reply("250-localhost\r\n250 STARTTLS")
+			}
+		case "STARTTLS":
+			reply("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.config)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, reader, encrypted = tlsConn, bufio.NewReader(tlsConn), true
+		case "MAIL", "RCPT":
+			if !encrypted {
+				reply("530 Must issue a STARTTLS command first")
+				continue
+			}
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data <- data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPMailerStartTLS(t *testing.T) {
+	// httptest provides a certificate valid for 127.0.0.1 and its pool
+	certs := httptest.NewTLSServer(http.NotFoundHandler())
+	defer certs.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	server := &startTLSServer{listener: listener, config: certs.TLS, data: make(chan string, 1)}
+	go server.serve(t)
+
+	port, err := strconv.Atoi(strings.TrimPrefix(listener.Addr().String(), "127.0.0.1:"))
+	require.NoError(t, err)
+	mailer := &smtpMailer{
+		config:  SMTPConfig{Host: "127.0.0.1", Port: port, From: "no-reply@example.com"},
+		rootCAs: certs.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs,
+	}
+
+	err = mailer.Send(context.Background(), &Message{To: "john@example.com", Subject: "Hello", Text: "Hi", HTML: "<p>Hi</p>"})
+	require.NoError(t, err)
+	assert.Contains(t, <-server.data, "To: john@example.com")
+}
@@ -0,0 +1,128 @@
+package notification
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Templates sent by the API
+const (
+	TemplateWelcome       = "welcome"
+	TemplateVerifyEmail   = "verify_email"
+	TemplateResetPassword = "reset_password"
+)
+
+// DefaultLocale is used when the recipient locale has no templates
+const DefaultLocale = "en"
+
+//go:embed templates
+var templateFS embed.FS
+
+// emailTemplate is a localized template. The text file defines the
+// "subject" block besides the body.
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Renderer renders the embedded templates
+type Renderer struct {
+	defaultLocale string
+	templates     map[string]map[string]*emailTemplate
+}
+
+// NewRenderer parses the embedded templates. defaultLocale is the fallback
+// for recipients whose locale has no translation.
+func NewRenderer(defaultLocale string) (*Renderer, error) {
+	r := &Renderer{defaultLocale: normalizeLocale(defaultLocale), templates: map[string]map[string]*emailTemplate{}}
+
+	layout, err := fs.ReadFile(templateFS, "templates/layout.html")
+	if err != nil {
+		return nil, err
+	}
+
+	textFiles, err := fs.Glob(templateFS, "templates/*/*.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range textFiles {
+		locale := path.Base(path.Dir(file))
+		name := strings.TrimSuffix(path.Base(file), ".txt")
+
+		text, err := texttemplate.ParseFS(templateFS, file)
+		if err != nil {
+			return nil, err
+		}
+		if text.Lookup("subject") == nil {
+			return nil, fmt.Errorf("template %s does not define a subject", file)
+		}
+
+		html, err := htmltemplate.New("layout").Parse(string(layout))
+		if err != nil {
+			return nil, err
+		}
+		if html, err = html.ParseFS(templateFS, strings.TrimSuffix(file, ".txt")+".html"); err != nil {
+			return nil, err
+		}
+
+		if r.templates[locale] == nil {
+			r.templates[locale] = map[string]*emailTemplate{}
+		}
+		r.templates[locale][name] = &emailTemplate{text: text, html: html}
+	}
+
+	if _, ok := r.templates[r.defaultLocale]; !ok {
+		return nil, fmt.Errorf("no templates for default locale %q", defaultLocale)
+	}
+
+	return r, nil
+}
+
+// Render renders the template in the closest available locale
+func (r *Renderer) Render(locale, name string, data interface{}) (*Message, error) {
+	t := r.lookup(locale, name)
+	if t == nil {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := t.text.ExecuteTemplate(&text, path.Base(t.text.Name()), data); err != nil {
+		return nil, err
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// lookup tries the exact locale, its language and then the default locale
+func (r *Renderer) lookup(locale, name string) *emailTemplate {
+	locale = normalizeLocale(locale)
+	language, _, _ := strings.Cut(locale, "-")
+
+	for _, candidate := range []string{locale, language, r.defaultLocale} {
+		if t, ok := r.templates[candidate][name]; ok {
+			return t
+		}
+	}
+	return nil
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
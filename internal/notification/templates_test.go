@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRendererRender(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	assert.NoError(t, err)
+
+	data := WelcomeData{Name: "<b>John</b>", Email: "john@example.com"}
+
+	t.Run("default locale", func(t *testing.T) {
+		m, err := renderer.Render("en", TemplateWelcome, data)
+		assert.NoError(t, err)
+		assert.Equal(t, "Welcome to go-web, <b>John</b>", m.Subject)
+		assert.Contains(t, m.Text, "Hi <b>John</b>,")
+		assert.Contains(t, m.HTML, "Welcome, &lt;b&gt;John&lt;/b&gt;!")
+		assert.Contains(t, m.HTML, "<!DOCTYPE html>")
+	})
+
+	t.Run("regional locale falls back to its language", func(t *testing.T) {
+		m, err := renderer.Render("es_MX", TemplateWelcome, data)
+		assert.NoError(t, err)
+		assert.Equal(t, "Bienvenido a go-web, <b>John</b>", m.Subject)
+	})
+
+	t.Run("unknown locale falls back to the default", func(t *testing.T) {
+		m, err := renderer.Render("fr", TemplateWelcome, data)
+		assert.NoError(t, err)
+		assert.Equal(t, "Welcome to go-web, <b>John</b>", m.Subject)
+	})
+
+	t.Run("unknown template", func(t *testing.T) {
+		_, err := renderer.Render("en", "missing", data)
+		assert.Error(t, err)
+	})
+}
+
+func TestRendererTemplatesAreComplete(t *testing.T) {
+	renderer, err := NewRenderer("en")
+	assert.NoError(t, err)
+
+	samples := map[string]interface{}{
+		TemplateWelcome:       WelcomeData{Name: "John", Email: "john@example.com"},
+		TemplateVerifyEmail:   LinkData{Name: "John", Link: "https://example.com/verify-email?token=abc", ExpiresInHours: 1},
+		TemplateResetPassword: LinkData{Name: "John", Link: "https://example.com/reset-password?token=abc", ExpiresInHours: 2},
+	}
+
+	for locale := range renderer.templates {
+		for name, data := range samples {
+			_, ok := renderer.templates[locale][name]
+			assert.True(t, ok, "locale %s misses template %s", locale, name)
+
+			m, err := renderer.Render(locale, name, data)
+			assert.NoError(t, err, "locale %s template %s", locale, name)
+			assert.NotEmpty(t, m.Subject)
+		}
+	}
+}
@@ -1,48 +1,67 @@
 package product
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
 )
 
 type ProductRepository interface {
-	Create(p *domain.Product) error
-	GetAll() ([]domain.Product, error)
-	GetByID(id int64) (*domain.Product, error)
-	Update(p *domain.Product) error
-	Delete(id int64) error
+	Create(ctx context.Context, p *domain.Product) error
+	GetAll(ctx context.Context) ([]domain.Product, error)
+	GetByID(ctx context.Context, id int64) (*domain.Product, error)
+	Update(ctx context.Context, p *domain.Product) error
+	Delete(ctx context.Context, id int64) error
+	GetBatch(ctx context.Context, afterID int64, limit int) ([]domain.Product, error)
+	GetCursorAt(ctx context.Context, offset int64) (int64, error)
+	GetFeedStats(ctx context.Context) (*domain.ProductFeedStats, error)
+	// WithTx returns a repository that runs its queries in tx
+	WithTx(tx database.DBTX) ProductRepository
 }
 
 type productRepository struct {
-	DB *sql.DB
+	DB       database.DBTX
+	timeouts database.Timeouts
 }
 
-func NewProductRepository(db *sql.DB) ProductRepository {
-	return &productRepository{DB: db}
+func NewProductRepository(db *sql.DB, timeouts database.Timeouts) ProductRepository {
+	return &productRepository{DB: database.Trace(db), timeouts: timeouts}
 }
 
-func (r *productRepository) Create(p *domain.Product) error {
+func (r *productRepository) WithTx(tx database.DBTX) ProductRepository {
+	return &productRepository{DB: database.Trace(tx), timeouts: r.timeouts}
+}
+
+func (r *productRepository) Create(ctx context.Context, p *domain.Product) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
 	query := "INSERT INTO products (name, price, description, category) VALUES (?, ?, ?, ?)"
-	result, err := r.DB.Exec(query, p.Name, p.Price, p.Description, p.Category)
+	result, err := r.DB.ExecContext(ctx, query, p.Name, p.Price, p.Description, p.Category)
 	if err != nil {
-		return err
+		return database.Translate(err)
 	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
-		return err
+		return database.Translate(err)
 	}
 
 	p.ID = int(id)
 	return nil
 }
 
-func (r *productRepository) GetAll() ([]domain.Product, error) {
+func (r *productRepository) GetAll(ctx context.Context) ([]domain.Product, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
 	query := "SELECT id, name, price, description, category FROM products"
-	rows, err := r.DB.Query(query)
+	rows, err := r.DB.QueryContext(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, database.Translate(err)
 	}
 	defer rows.Close()
 
@@ -51,7 +70,7 @@ func (r *productRepository) GetAll() ([]domain.Product, error) {
 		var p domain.Product
 		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Description, &p.Category)
 		if err != nil {
-			return nil, err
+			return nil, database.Translate(err)
 		}
 		products = append(products, p)
 	}
@@ -59,35 +78,111 @@ func (r *productRepository) GetAll() ([]domain.Product, error) {
 	return products, nil
 }
 
-func (r *productRepository) GetByID(id int64) (*domain.Product, error) {
+func (r *productRepository) GetByID(ctx context.Context, id int64) (*domain.Product, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
 	query := "SELECT id, name, price, description, category FROM products WHERE id = ?"
-	row := r.DB.QueryRow(query, id)
+	row := r.DB.QueryRowContext(ctx, query, id)
 
 	var p domain.Product
 	err := row.Scan(&p.ID, &p.Name, &p.Price, &p.Description, &p.Category)
 	if err != nil {
-		return nil, err
+		return nil, database.Translate(err)
 	}
 
 	return &p, nil
 }
 
-func (r *productRepository) Update(p *domain.Product) error {
+func (r *productRepository) Update(ctx context.Context, p *domain.Product) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
 	query := "UPDATE products SET name = ?, price = ?, description = ?, category = ? WHERE id = ?"
-	_, err := r.DB.Exec(query, p.Name, p.Price, p.Description, p.Category, p.ID)
+	result, err := r.DB.ExecContext(ctx, query, p.Name, p.Price, p.Description, p.Category, p.ID)
 	if err != nil {
-		return err
+		return database.Translate(err)
 	}
 
-	return nil
+	return database.RequireRows(result)
 }
 
-func (r *productRepository) Delete(id int64) error {
+func (r *productRepository) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
 	query := "DELETE FROM products WHERE id = ?"
-	_, err := r.DB.Exec(query, id)
+	result, err := r.DB.ExecContext(ctx, query, id)
 	if err != nil {
-		return err
+		return database.Translate(err)
 	}
 
-	return nil
+	return database.RequireRows(result)
+}
+
+// GetBatch returns up to limit products with an id greater than afterID, ordered by id
+func (r *productRepository) GetBatch(ctx context.Context, afterID int64, limit int) ([]domain.Product, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, name, price, description, category, updated_at FROM products WHERE id > ? ORDER BY id LIMIT ?"
+	rows, err := r.DB.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	var products []domain.Product
+	for rows.Next() {
+		var p domain.Product
+		var updatedAt time.Time
+		err := rows.Scan(&p.ID, &p.Name, &p.Price, &p.Description, &p.Category, &updatedAt)
+		if err != nil {
+			return nil, database.Translate(err)
+		}
+		p.UpdatedAt = &updatedAt
+		products = append(products, p)
+	}
+
+	return products, rows.Err()
+}
+
+// GetCursorAt returns the id of the product placed just before offset, so
+// GetBatch can start reading from that position. It returns 0 for offset 0.
+func (r *productRepository) GetCursorAt(ctx context.Context, offset int64) (int64, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	if offset <= 0 {
+		return 0, nil
+	}
+
+	query := "SELECT id FROM products ORDER BY id LIMIT 1 OFFSET ?"
+	var id int64
+	err := r.DB.QueryRowContext(ctx, query, offset-1).Scan(&id)
+	if err != nil {
+		return 0, database.Translate(err)
+	}
+
+	return id, nil
+}
+
+// GetFeedStats returns the number of products and the newest update time
+func (r *productRepository) GetFeedStats(ctx context.Context) (*domain.ProductFeedStats, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT COUNT(*), MAX(updated_at) FROM products"
+	var stats domain.ProductFeedStats
+	var lastModified sql.NullTime
+	err := r.DB.QueryRowContext(ctx, query).Scan(&stats.Count, &lastModified)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	if lastModified.Valid {
+		stats.LastModified = lastModified.Time
+	}
+
+	return &stats, nil
 }
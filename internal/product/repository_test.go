@@ -1,12 +1,16 @@
 package product
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,7 +21,7 @@ func TestNewProductRepository(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewProductRepository(db)
+	repo := NewProductRepository(db, database.Timeouts{})
 	assert.NotNil(t, repo)
 }
 
@@ -28,13 +32,13 @@ func TestRepositoryCreate(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewProductRepository(db)
+	repo := NewProductRepository(db, database.Timeouts{})
 
 	t.Run("successful creation", func(t *testing.T) {
 		product := &domain.Product{Name: "Test Product", Price: 9.99, Description: "Test Description", Category: "Test Category"}
 		mock.ExpectExec("INSERT INTO products").WithArgs(product.Name, product.Price, product.Description, product.Category).WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := repo.Create(product)
+		err := repo.Create(context.Background(), product)
 		assert.NoError(t, err)
 		assert.Equal(t, 1, product.ID)
 	})
@@ -43,7 +47,7 @@ func TestRepositoryCreate(t *testing.T) {
 		product := &domain.Product{Name: "Error Product", Price: 19.99, Description: "Error Description", Category: "Error Category"}
 		mock.ExpectExec("INSERT INTO products").WithArgs(product.Name, product.Price, product.Description, product.Category).WillReturnError(errors.New("database error"))
 
-		err := repo.Create(product)
+		err := repo.Create(context.Background(), product)
 		assert.Error(t, err)
 	})
 }
@@ -55,7 +59,7 @@ func TestRepositoryGetAll(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewProductRepository(db)
+	repo := NewProductRepository(db, database.Timeouts{})
 
 	t.Run("get all products", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"id", "name", "price", "description", "category"}).
@@ -63,7 +67,7 @@ func TestRepositoryGetAll(t *testing.T) {
 			AddRow(2, "Product 2", 19.99, "Description 2", "Category 2")
 		mock.ExpectQuery("SELECT (.+) FROM products").WillReturnRows(rows)
 
-		products, err := repo.GetAll()
+		products, err := repo.GetAll(context.Background())
 		assert.NoError(t, err)
 		assert.Len(t, products, 2)
 		assert.Equal(t, "Product 1", products[0].Name)
@@ -73,12 +77,32 @@ func TestRepositoryGetAll(t *testing.T) {
 	t.Run("database error", func(t *testing.T) {
 		mock.ExpectQuery("SELECT (.+) FROM products").WillReturnError(errors.New("database error"))
 
-		products, err := repo.GetAll()
+		products, err := repo.GetAll(context.Background())
 		assert.Error(t, err)
 		assert.Nil(t, products)
 	})
 }
 
+func TestRepositoryReadTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewProductRepository(db, database.Timeouts{Read: 10 * time.Millisecond})
+
+	mock.ExpectQuery("SELECT (.+) FROM products").
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "price", "description", "category"}))
+
+	start := time.Now()
+	products, err := repo.GetAll(context.Background())
+	assert.Error(t, err)
+	assert.Nil(t, products)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
 func TestRepositoryGetByID(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -86,14 +110,14 @@ func TestRepositoryGetByID(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewProductRepository(db)
+	repo := NewProductRepository(db, database.Timeouts{})
 
 	t.Run("product found", func(t *testing.T) {
 		rows := sqlmock.NewRows([]string{"id", "name", "price", "description", "category"}).
 			AddRow(1, "Test Product", 9.99, "Test Description", "Test Category")
 		mock.ExpectQuery("SELECT (.+) FROM products WHERE id = ?").WithArgs(1).WillReturnRows(rows)
 
-		product, err := repo.GetByID(1)
+		product, err := repo.GetByID(context.Background(), 1)
 		assert.NoError(t, err)
 		assert.NotNil(t, product)
 		assert.Equal(t, "Test Product", product.Name)
@@ -102,8 +126,8 @@ func TestRepositoryGetByID(t *testing.T) {
 	t.Run("product not found", func(t *testing.T) {
 		mock.ExpectQuery("SELECT (.+) FROM products WHERE id = ?").WithArgs(2).WillReturnError(sql.ErrNoRows)
 
-		product, err := repo.GetByID(2)
-		assert.Error(t, err)
+		product, err := repo.GetByID(context.Background(), 2)
+		assert.ErrorIs(t, err, appErrors.ErrNotFound)
 		assert.Nil(t, product)
 	})
 }
@@ -115,13 +139,13 @@ func TestRepositoryUpdate(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewProductRepository(db)
+	repo := NewProductRepository(db, database.Timeouts{})
 
 	t.Run("successful update", func(t *testing.T) {
 		product := &domain.Product{ID: 1, Name: "Updated Product", Price: 29.99, Description: "Updated Description", Category: "Updated Category"}
 		mock.ExpectExec("UPDATE products SET").WithArgs(product.Name, product.Price, product.Description, product.Category, product.ID).WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.Update(product)
+		err := repo.Update(context.Background(), product)
 		assert.NoError(t, err)
 	})
 
@@ -129,9 +153,17 @@ func TestRepositoryUpdate(t *testing.T) {
 		product := &domain.Product{ID: 2, Name: "Error Product", Price: 39.99, Description: "Error Description", Category: "Error Category"}
 		mock.ExpectExec("UPDATE products SET").WithArgs(product.Name, product.Price, product.Description, product.Category, product.ID).WillReturnError(errors.New("database error"))
 
-		err := repo.Update(product)
+		err := repo.Update(context.Background(), product)
 		assert.Error(t, err)
 	})
+
+	t.Run("product not found", func(t *testing.T) {
+		product := &domain.Product{ID: 3, Name: "Missing Product", Price: 1, Description: "", Category: ""}
+		mock.ExpectExec("UPDATE products SET").WithArgs(product.Name, product.Price, product.Description, product.Category, product.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Update(context.Background(), product)
+		assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	})
 }
 
 func TestRepositoryDelete(t *testing.T) {
@@ -141,19 +173,111 @@ func TestRepositoryDelete(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewProductRepository(db)
+	repo := NewProductRepository(db, database.Timeouts{})
 
 	t.Run("successful delete", func(t *testing.T) {
 		mock.ExpectExec("DELETE FROM products WHERE id = ?").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
 
-		err := repo.Delete(1)
+		err := repo.Delete(context.Background(), 1)
 		assert.NoError(t, err)
 	})
 
 	t.Run("delete error", func(t *testing.T) {
 		mock.ExpectExec("DELETE FROM products WHERE id = ?").WithArgs(2).WillReturnError(errors.New("database error"))
 
-		err := repo.Delete(2)
+		err := repo.Delete(context.Background(), 2)
+		assert.Error(t, err)
+	})
+
+	t.Run("product not found", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM products WHERE id = ?").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Delete(context.Background(), 3)
+		assert.ErrorIs(t, err, appErrors.ErrNotFound)
+	})
+}
+
+func TestRepositoryGetBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewProductRepository(db, database.Timeouts{})
+	updatedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	t.Run("get batch", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "name", "price", "description", "category", "updated_at"}).
+			AddRow(11, "Product 11", 9.99, "Description 11", "Category 1", updatedAt).
+			AddRow(12, "Product 12", 19.99, "Description 12", "Category 2", updatedAt)
+		mock.ExpectQuery("SELECT (.+) FROM products WHERE id > \\? ORDER BY id LIMIT \\?").WithArgs(10, 2).WillReturnRows(rows)
+
+		products, err := repo.GetBatch(context.Background(), 10, 2)
+		assert.NoError(t, err)
+		assert.Len(t, products, 2)
+		assert.Equal(t, 11, products[0].ID)
+		assert.Equal(t, updatedAt, *products[1].UpdatedAt)
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM products WHERE id >").WithArgs(0, 500).WillReturnError(errors.New("database error"))
+
+		products, err := repo.GetBatch(context.Background(), 0, 500)
 		assert.Error(t, err)
+		assert.Nil(t, products)
+	})
+}
+
+func TestRepositoryGetCursorAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewProductRepository(db, database.Timeouts{})
+
+	t.Run("first position", func(t *testing.T) {
+		id, err := repo.GetCursorAt(context.Background(), 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), id)
+	})
+
+	t.Run("cursor found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT id FROM products ORDER BY id LIMIT 1 OFFSET").WithArgs(49999).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(50003))
+
+		id, err := repo.GetCursorAt(context.Background(), 50000)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(50003), id)
 	})
-}
\ No newline at end of file
+}
+
+func TestRepositoryGetFeedStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewProductRepository(db, database.Timeouts{})
+
+	t.Run("stats with products", func(t *testing.T) {
+		lastModified := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\), MAX\\(updated_at\\) FROM products").WillReturnRows(sqlmock.NewRows([]string{"count", "max"}).AddRow(3, lastModified))
+
+		stats, err := repo.GetFeedStats(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), stats.Count)
+		assert.Equal(t, lastModified, stats.LastModified)
+	})
+
+	t.Run("empty table", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\), MAX\\(updated_at\\) FROM products").WillReturnRows(sqlmock.NewRows([]string{"count", "max"}).AddRow(0, nil))
+
+		stats, err := repo.GetFeedStats(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), stats.Count)
+		assert.True(t, stats.LastModified.IsZero())
+	})
+}
@@ -1,49 +1,109 @@
 package product
 
 import (
+	"context"
+
 	models "github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/logging"
 )
 
+// PriceDropListener is told when an update lowers the price of a product
+type PriceDropListener interface {
+	PriceDropped(product *models.Product, previousPrice float64)
+}
+
 // ProductService interface
 type ProductService interface {
-	CreateProduct(product *models.Product) error
-	GetAllProducts() ([]models.Product, error)
-	GetProductByID(id int64) (*models.Product, error)
-	UpdateProduct(product *models.Product) error
-	DeleteProduct(id int64) error
+	CreateProduct(ctx context.Context, product *models.Product) error
+	GetAllProducts(ctx context.Context) ([]models.Product, error)
+	GetProductByID(ctx context.Context, id int64) (*models.Product, error)
+	UpdateProduct(ctx context.Context, product *models.Product) error
+	DeleteProduct(ctx context.Context, id int64) error
 }
 
 // ProductService struct
 type productService struct {
-	repo ProductRepository
+	repo       ProductRepository
+	tx         database.Transactor
+	outbox     events.Outbox
+	changes    events.Publisher
+	priceDrops PriceDropListener
 }
 
-// NewProductService return a new ProductService
-func NewProductService(repo ProductRepository) ProductService {
-	return &productService{repo: repo}
+// NewProductService return a new ProductService. changes and priceDrops may be nil.
+func NewProductService(repo ProductRepository, tx database.Transactor, outbox events.Outbox, changes events.Publisher, priceDrops PriceDropListener) ProductService {
+	return &productService{repo: repo, tx: tx, outbox: outbox, changes: changes, priceDrops: priceDrops}
 }
 
 // CreateProduct create a new product
-func (s *productService) CreateProduct(product *models.Product) error {
-	return s.repo.Create(product)
+func (s *productService) CreateProduct(ctx context.Context, product *models.Product) error {
+	return s.save(ctx, events.ProductCreated, product, func(repo ProductRepository) error {
+		return repo.Create(ctx, product)
+	})
 }
 
 // GetAllProducts return all products
-func (s *productService) GetAllProducts() ([]models.Product, error) {
-	return s.repo.GetAll()
+func (s *productService) GetAllProducts(ctx context.Context) ([]models.Product, error) {
+	return s.repo.GetAll(ctx)
 }
 
 // GetProductByID return a product by id
-func (s *productService) GetProductByID(id int64) (*models.Product, error) {
-	return s.repo.GetByID(id)
+func (s *productService) GetProductByID(ctx context.Context, id int64) (*models.Product, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
 // UpdateProduct update a product
-func (s *productService) UpdateProduct(product *models.Product) error {
-	return s.repo.Update(product)
+func (s *productService) UpdateProduct(ctx context.Context, product *models.Product) error {
+	if s.priceDrops == nil {
+		return s.update(ctx, product)
+	}
+
+	previous, err := s.repo.GetByID(ctx, int64(product.ID))
+	if err != nil {
+		logging.FromContext(ctx).Error("Error reading product before update", "product_id", product.ID, "error", err)
+		return s.update(ctx, product)
+	}
+
+	if err := s.update(ctx, product); err != nil {
+		return err
+	}
+
+	if product.Price < previous.Price {
+		s.priceDrops.PriceDropped(product, previous.Price)
+	}
+	return nil
+}
+
+func (s *productService) update(ctx context.Context, product *models.Product) error {
+	return s.save(ctx, events.ProductUpdated, product, func(repo ProductRepository) error {
+		return repo.Update(ctx, product)
+	})
 }
 
 // DeleteProduct delete a product
-func (s *productService) DeleteProduct(id int64) error {
-	return s.repo.Delete(id)
+func (s *productService) DeleteProduct(ctx context.Context, id int64) error {
+	return s.save(ctx, events.ProductDeleted, map[string]int64{"id": id}, func(repo ProductRepository) error {
+		return repo.Delete(ctx, id)
+	})
+}
+
+// save runs write and records the event in the same transaction. Live
+// subscribers are only told once the transaction is committed.
+func (s *productService) save(ctx context.Context, eventType string, data interface{}, write func(repo ProductRepository) error) error {
+	err := s.tx.WithinTx(ctx, func(tx database.DBTX) error {
+		if err := write(s.repo.WithTx(tx)); err != nil {
+			return err
+		}
+		return s.outbox.Add(ctx, tx, eventType, data)
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.changes != nil {
+		s.changes.Publish(eventType, data)
+	}
+	return nil
 }
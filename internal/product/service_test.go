@@ -1,10 +1,14 @@
 package product
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -13,40 +17,59 @@ type mockProductRepository struct {
 	mock.Mock
 }
 
-func (m *mockProductRepository) Create(p *domain.Product) error {
+func (m *mockProductRepository) Create(ctx context.Context, p *domain.Product) error {
 	args := m.Called(p)
 	return args.Error(0)
 }
 
-func (m *mockProductRepository) GetAll() ([]domain.Product, error) {
+func (m *mockProductRepository) GetAll(ctx context.Context) ([]domain.Product, error) {
 	args := m.Called()
 	return args.Get(0).([]domain.Product), args.Error(1)
 }
 
-func (m *mockProductRepository) GetByID(id int64) (*domain.Product, error) {
+func (m *mockProductRepository) GetByID(ctx context.Context, id int64) (*domain.Product, error) {
 	args := m.Called(id)
 	return args.Get(0).(*domain.Product), args.Error(1)
 }
 
-func (m *mockProductRepository) Update(p *domain.Product) error {
+func (m *mockProductRepository) Update(ctx context.Context, p *domain.Product) error {
 	args := m.Called(p)
 	return args.Error(0)
 }
 
-func (m *mockProductRepository) Delete(id int64) error {
+func (m *mockProductRepository) Delete(ctx context.Context, id int64) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
+func (m *mockProductRepository) GetBatch(ctx context.Context, afterID int64, limit int) ([]domain.Product, error) {
+	args := m.Called(afterID, limit)
+	return args.Get(0).([]domain.Product), args.Error(1)
+}
+
+func (m *mockProductRepository) GetCursorAt(ctx context.Context, offset int64) (int64, error) {
+	args := m.Called(offset)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockProductRepository) GetFeedStats(ctx context.Context) (*domain.ProductFeedStats, error) {
+	args := m.Called()
+	return args.Get(0).(*domain.ProductFeedStats), args.Error(1)
+}
+
+func (m *mockProductRepository) WithTx(tx database.DBTX) ProductRepository {
+	return m
+}
+
 func TestServiceCreateProduct(t *testing.T) {
 	mockRepo := new(mockProductRepository)
-	service := NewProductService(mockRepo)
+	service := NewProductService(mockRepo, &test.FakeTransactor{}, &events.MemoryOutbox{}, nil, nil)
 
 	t.Run("successful product creation", func(t *testing.T) {
 		product := &domain.Product{Name: "Test Product", Price: 9.99}
 		mockRepo.On("Create", product).Return(nil)
 
-		err := service.CreateProduct(product)
+		err := service.CreateProduct(context.Background(), product)
 
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
@@ -56,7 +79,7 @@ func TestServiceCreateProduct(t *testing.T) {
 		product := &domain.Product{Name: "Error Product", Price: 19.99}
 		mockRepo.On("Create", product).Return(errors.New("database error"))
 
-		err := service.CreateProduct(product)
+		err := service.CreateProduct(context.Background(), product)
 
 		assert.Error(t, err)
 		mockRepo.AssertExpectations(t)
@@ -65,7 +88,7 @@ func TestServiceCreateProduct(t *testing.T) {
 
 func TestServiceGetAllProducts(t *testing.T) {
 	mockRepo := new(mockProductRepository)
-	service := NewProductService(mockRepo)
+	service := NewProductService(mockRepo, &test.FakeTransactor{}, &events.MemoryOutbox{}, nil, nil)
 
 	t.Run("successful get all products", func(t *testing.T) {
 		expectedProducts := []domain.Product{
@@ -74,7 +97,7 @@ func TestServiceGetAllProducts(t *testing.T) {
 		}
 		mockRepo.On("GetAll").Return(expectedProducts, nil)
 
-		products, err := service.GetAllProducts()
+		products, err := service.GetAllProducts(context.Background())
 
 		assert.NoError(t, err)
 		assert.Equal(t, expectedProducts, products)
@@ -85,13 +108,13 @@ func TestServiceGetAllProducts(t *testing.T) {
 
 func TestServiceGetProductByID(t *testing.T) {
 	mockRepo := new(mockProductRepository)
-	service := NewProductService(mockRepo)
+	service := NewProductService(mockRepo, &test.FakeTransactor{}, &events.MemoryOutbox{}, nil, nil)
 
 	t.Run("product found", func(t *testing.T) {
 		expectedProduct := &domain.Product{ID: 1, Name: "Test Product", Price: 9.99}
 		mockRepo.On("GetByID", int64(1)).Return(expectedProduct, nil)
 
-		product, err := service.GetProductByID(1)
+		product, err := service.GetProductByID(context.Background(), 1)
 
 		assert.NoError(t, err)
 		assert.Equal(t, expectedProduct, product)
@@ -101,7 +124,7 @@ func TestServiceGetProductByID(t *testing.T) {
 	t.Run("product not found", func(t *testing.T) {
 		mockRepo.On("GetByID", int64(2)).Return((*domain.Product)(nil), errors.New("product not found"))
 
-		product, err := service.GetProductByID(2)
+		product, err := service.GetProductByID(context.Background(), 2)
 
 		assert.Error(t, err)
 		assert.Nil(t, product)
@@ -111,13 +134,13 @@ func TestServiceGetProductByID(t *testing.T) {
 
 func TestServiceUpdateProduct(t *testing.T) {
 	mockRepo := new(mockProductRepository)
-	service := NewProductService(mockRepo)
+	service := NewProductService(mockRepo, &test.FakeTransactor{}, &events.MemoryOutbox{}, nil, nil)
 
 	t.Run("successful update", func(t *testing.T) {
 		product := &domain.Product{ID: 1, Name: "Updated Product", Price: 29.99}
 		mockRepo.On("Update", product).Return(nil)
 
-		err := service.UpdateProduct(product)
+		err := service.UpdateProduct(context.Background(), product)
 
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
@@ -127,7 +150,7 @@ func TestServiceUpdateProduct(t *testing.T) {
 		product := &domain.Product{ID: 2, Name: "Error Product", Price: 39.99}
 		mockRepo.On("Update", product).Return(errors.New("database error"))
 
-		err := service.UpdateProduct(product)
+		err := service.UpdateProduct(context.Background(), product)
 
 		assert.Error(t, err)
 		mockRepo.AssertExpectations(t)
@@ -136,12 +159,12 @@ func TestServiceUpdateProduct(t *testing.T) {
 
 func TestServiceDeleteProduct(t *testing.T) {
 	mockRepo := new(mockProductRepository)
-	service := NewProductService(mockRepo)
+	service := NewProductService(mockRepo, &test.FakeTransactor{}, &events.MemoryOutbox{}, nil, nil)
 
 	t.Run("successful delete", func(t *testing.T) {
 		mockRepo.On("Delete", int64(1)).Return(nil)
 
-		err := service.DeleteProduct(1)
+		err := service.DeleteProduct(context.Background(), 1)
 
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
@@ -150,9 +173,102 @@ func TestServiceDeleteProduct(t *testing.T) {
 	t.Run("delete error", func(t *testing.T) {
 		mockRepo.On("Delete", int64(2)).Return(errors.New("database error"))
 
-		err := service.DeleteProduct(2)
+		err := service.DeleteProduct(context.Background(), 2)
 
 		assert.Error(t, err)
 		mockRepo.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+type recordingPriceDropListener struct {
+	drops []float64
+}
+
+func (l *recordingPriceDropListener) PriceDropped(product *domain.Product, previousPrice float64) {
+	l.drops = append(l.drops, previousPrice)
+}
+
+func TestServiceUpdateProductPriceDrop(t *testing.T) {
+	mockRepo := new(mockProductRepository)
+	listener := &recordingPriceDropListener{}
+	service := NewProductService(mockRepo, &test.FakeTransactor{}, &events.MemoryOutbox{}, nil, listener)
+
+	mockRepo.On("GetByID", int64(1)).Return(&domain.Product{ID: 1, Name: "Laptop", Price: 1000}, nil)
+
+	cheaper := &domain.Product{ID: 1, Name: "Laptop", Price: 900}
+	mockRepo.On("Update", cheaper).Return(nil)
+	assert.NoError(t, service.UpdateProduct(context.Background(), cheaper))
+	assert.Equal(t, []float64{1000}, listener.drops)
+
+	pricier := &domain.Product{ID: 1, Name: "Laptop", Price: 1100}
+	mockRepo.On("Update", pricier).Return(nil)
+	assert.NoError(t, service.UpdateProduct(context.Background(), pricier))
+	assert.Len(t, listener.drops, 1)
+}
+
+func TestServiceProductEvents(t *testing.T) {
+	t.Run("writes emit events", func(t *testing.T) {
+		mockRepo := new(mockProductRepository)
+		outbox := &events.MemoryOutbox{}
+		service := NewProductService(mockRepo, &test.FakeTransactor{}, outbox, nil, nil)
+
+		product := &domain.Product{ID: 1, Name: "Laptop", Price: 1000}
+		mockRepo.On("Create", product).Return(nil)
+		mockRepo.On("Update", product).Return(nil)
+		mockRepo.On("Delete", int64(1)).Return(nil)
+
+		assert.NoError(t, service.CreateProduct(context.Background(), product))
+		assert.NoError(t, service.UpdateProduct(context.Background(), product))
+		assert.NoError(t, service.DeleteProduct(context.Background(), 1))
+
+		emitted := outbox.Events()
+		assert.Len(t, emitted, 3)
+		assert.Equal(t, events.ProductCreated, emitted[0].Type)
+		assert.Equal(t, events.ProductUpdated, emitted[1].Type)
+		assert.Equal(t, events.ProductDeleted, emitted[2].Type)
+		assert.JSONEq(t, `{"id":1}`, string(emitted[2].Data))
+	})
+
+	t.Run("outbox error rolls back the write", func(t *testing.T) {
+		mockRepo := new(mockProductRepository)
+		tx := &test.FakeTransactor{}
+		service := NewProductService(mockRepo, tx, &events.MemoryOutbox{Err: errors.New("database error")}, nil, nil)
+
+		product := &domain.Product{ID: 1, Name: "Laptop", Price: 1000}
+		mockRepo.On("Create", product).Return(nil)
+
+		assert.Error(t, service.CreateProduct(context.Background(), product))
+		assert.Equal(t, 1, tx.Rollbacks)
+		assert.Equal(t, 0, tx.Commits)
+	})
+
+	t.Run("repository error emits nothing", func(t *testing.T) {
+		mockRepo := new(mockProductRepository)
+		outbox := &events.MemoryOutbox{}
+		service := NewProductService(mockRepo, &test.FakeTransactor{}, outbox, nil, nil)
+
+		mockRepo.On("Delete", int64(2)).Return(errors.New("database error"))
+
+		assert.Error(t, service.DeleteProduct(context.Background(), 2))
+		assert.Empty(t, outbox.Events())
+	})
+}
+
+func TestServiceProductChanges(t *testing.T) {
+	mockRepo := new(mockProductRepository)
+	broker := events.NewBroker(events.BrokerOptions{BufferSize: 10, HistorySize: 10})
+	service := NewProductService(mockRepo, &test.FakeTransactor{}, &events.MemoryOutbox{}, broker, nil)
+
+	sub, _, _, err := broker.Subscribe(0)
+	assert.NoError(t, err)
+
+	product := &domain.Product{ID: 1, Name: "Laptop", Price: 1000}
+	mockRepo.On("Create", product).Return(nil)
+	mockRepo.On("Delete", int64(2)).Return(errors.New("database error"))
+
+	assert.NoError(t, service.CreateProduct(context.Background(), product))
+	assert.Error(t, service.DeleteProduct(context.Background(), 2))
+
+	e := <-sub.Events()
+	assert.Equal(t, events.ProductCreated, e.Type)
+	assert.Empty(t, sub.Events(), "failed writes must not be published")
+}
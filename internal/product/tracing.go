@@ -0,0 +1,68 @@
+package product
+
+import (
+	"context"
+
+	models "github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Jacobo0312/go-web/internal/product")
+
+type tracedProductService struct {
+	next ProductService
+}
+
+// NewTracedProductService return a ProductService that records a span
+// around each call to next
+func NewTracedProductService(next ProductService) ProductService {
+	return &tracedProductService{next: next}
+}
+
+func (s *tracedProductService) CreateProduct(ctx context.Context, product *models.Product) (err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.CreateProduct")
+	defer func() {
+		span.SetAttributes(attribute.Int("product.id", product.ID))
+		tracing.End(span, err)
+	}()
+
+	return s.next.CreateProduct(ctx, product)
+}
+
+func (s *tracedProductService) GetAllProducts(ctx context.Context) (products []models.Product, err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetAllProducts")
+	defer func() {
+		span.SetAttributes(attribute.Int("product.count", len(products)))
+		tracing.End(span, err)
+	}()
+
+	return s.next.GetAllProducts(ctx)
+}
+
+func (s *tracedProductService) GetProductByID(ctx context.Context, id int64) (product *models.Product, err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.GetProductByID", traceProductID(id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.GetProductByID(ctx, id)
+}
+
+func (s *tracedProductService) UpdateProduct(ctx context.Context, product *models.Product) (err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.UpdateProduct", traceProductID(int64(product.ID)))
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.UpdateProduct(ctx, product)
+}
+
+func (s *tracedProductService) DeleteProduct(ctx context.Context, id int64) (err error) {
+	ctx, span := tracer.Start(ctx, "ProductService.DeleteProduct", traceProductID(id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.DeleteProduct(ctx, id)
+}
+
+func traceProductID(id int64) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.Int64("product.id", id))
+}
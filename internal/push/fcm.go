@@ -0,0 +1,132 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMConfig configures the FCM HTTP v1 sender
+type FCMConfig struct {
+	// Endpoint is https://fcm.googleapis.com, or the URL of a local stub
+	Endpoint  string
+	ProjectID string
+	// CredentialsFile is the service account used to authenticate. Leave it
+	// empty to send unauthenticated requests to a local stub.
+	CredentialsFile string
+}
+
+type fcmSender struct {
+	url    string
+	client *http.Client
+}
+
+// NewFCMSender return a PushSender for the FCM HTTP v1 API
+func NewFCMSender(ctx context.Context, config FCMConfig) (PushSender, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	if config.CredentialsFile != "" {
+		credentialsJSON, err := os.ReadFile(config.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		credentials, err := google.CredentialsFromJSON(ctx, credentialsJSON, fcmScope)
+		if err != nil {
+			return nil, err
+		}
+		client = oauth2Client(credentials, client.Timeout)
+	}
+
+	return &fcmSender{
+		url:    fmt.Sprintf("%s/v1/projects/%s/messages:send", strings.TrimRight(config.Endpoint, "/"), config.ProjectID),
+		client: client,
+	}, nil
+}
+
+type fcmRequest struct {
+	Message fcmMessage `json:"message"`
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmErrorResponse struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+func (s *fcmSender) Send(ctx context.Context, token string, m *Message) error {
+	body, err := json.Marshal(fcmRequest{Message: fcmMessage{
+		Token:        token,
+		Notification: fcmNotification{Title: m.Title, Body: m.Body},
+		Data:         m.Data,
+	}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var fcmErr fcmErrorResponse
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&fcmErr)
+
+	// Tokens are only pruned on answers that are about the token itself. A
+	// bare 404 may come from a wrong project, endpoint or proxy.
+	for _, detail := range fcmErr.Error.Details {
+		if detail.ErrorCode == "UNREGISTERED" || detail.ErrorCode == "SENDER_ID_MISMATCH" {
+			return fmt.Errorf("%w: %s", ErrInvalidToken, detail.ErrorCode)
+		}
+	}
+
+	return fmt.Errorf("fcm responded %d %s: %s", resp.StatusCode, fcmErr.Error.Status, fcmErr.Error.Message)
+}
+
+// oauth2Client returns an HTTP client that authenticates with credentials
+func oauth2Client(credentials *google.Credentials, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &oauth2.Transport{
+			Source: credentials.TokenSource,
+			Base:   http.DefaultTransport,
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFCMSender(t *testing.T) {
+	var received fcmRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/projects/demo/messages:send", r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&received)
+
+		switch received.Message.Token {
+		case "unregistered":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+		case "wrong-project":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"status":"NOT_FOUND","message":"Requested entity was not found."}}`))
+		case "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":{"status":"UNAVAILABLE","message":"try later"}}`))
+		default:
+			w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+		}
+	}))
+	defer server.Close()
+
+	sender, err := NewFCMSender(context.Background(), FCMConfig{Endpoint: server.URL, ProjectID: "demo"})
+	assert.NoError(t, err)
+	m := &Message{Title: "Price drop", Body: "Laptop is now 900.00", Data: map[string]string{"product_id": "1"}}
+
+	assert.NoError(t, sender.Send(context.Background(), "valid", m))
+	assert.Equal(t, "Price drop", received.Message.Notification.Title)
+	assert.Equal(t, "1", received.Message.Data["product_id"])
+
+	assert.ErrorIs(t, sender.Send(context.Background(), "unregistered", m), ErrInvalidToken)
+
+	err = sender.Send(context.Background(), "wrong-project", m)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidToken, "a 404 without details keeps the token")
+
+	err = sender.Send(context.Background(), "unavailable", m)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidToken)
+}
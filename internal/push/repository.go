@@ -0,0 +1,186 @@
+package push
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+)
+
+// topicColumns maps every topic to its notification_preferences column
+var topicColumns = map[string]string{
+	TopicOrders:     "orders",
+	TopicPriceDrops: "price_drops",
+}
+
+type DeviceRepository interface {
+	// Register stores the device, moving the token to the user when another
+	// account registered it before
+	Register(ctx context.Context, d *domain.Device) error
+	GetByUser(ctx context.Context, userID string) ([]domain.Device, error)
+	Delete(ctx context.Context, userID string, id int64) (bool, error)
+	DeleteTokens(ctx context.Context, tokens []string) error
+	// GetTargets pages through the devices whose owners accept topic. When
+	// userIDs is empty every user is considered.
+	GetTargets(ctx context.Context, topic string, userIDs []string, afterID int64, limit int) ([]domain.Device, error)
+	GetPreferences(ctx context.Context, userID string) (*domain.NotificationPreferences, error)
+	SetPreferences(ctx context.Context, userID string, p *domain.NotificationPreferences) error
+}
+
+type deviceRepository struct {
+	DB       *sql.DB
+	timeouts database.Timeouts
+}
+
+func NewDeviceRepository(db *sql.DB, timeouts database.Timeouts) DeviceRepository {
+	return &deviceRepository{DB: db, timeouts: timeouts}
+}
+
+func (r *deviceRepository) Register(ctx context.Context, d *domain.Device) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "INSERT INTO devices (user_id, platform, token, created_at, last_seen_at) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), user_id = VALUES(user_id), platform = VALUES(platform), last_seen_at = VALUES(last_seen_at)"
+	result, err := r.DB.ExecContext(ctx, query, d.UserID, d.Platform, d.Token, d.CreatedAt, d.LastSeenAt)
+	if err != nil {
+		return database.Translate(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return database.Translate(err)
+	}
+	d.ID = id
+	return nil
+}
+
+func (r *deviceRepository) GetByUser(ctx context.Context, userID string) ([]domain.Device, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, user_id, platform, token, created_at, last_seen_at FROM devices WHERE user_id = ? ORDER BY id"
+	rows, err := r.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	devices := []domain.Device{}
+	for rows.Next() {
+		var d domain.Device
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Platform, &d.Token, &d.CreatedAt, &d.LastSeenAt); err != nil {
+			return nil, database.Translate(err)
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+func (r *deviceRepository) Delete(ctx context.Context, userID string, id int64) (bool, error) {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "DELETE FROM devices WHERE id = ? AND user_id = ?"
+	result, err := r.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return false, database.Translate(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, database.Translate(err)
+	}
+	return rows == 1, nil
+}
+
+func (r *deviceRepository) DeleteTokens(ctx context.Context, tokens []string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	query := "DELETE FROM devices WHERE token IN (" + placeholders(len(tokens)) + ")"
+	args := make([]interface{}, len(tokens))
+	for i, token := range tokens {
+		args[i] = token
+	}
+
+	_, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *deviceRepository) GetTargets(ctx context.Context, topic string, userIDs []string, afterID int64, limit int) ([]domain.Device, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	column, ok := topicColumns[topic]
+	if !ok {
+		return nil, fmt.Errorf("unknown push topic %q", topic)
+	}
+
+	query := "SELECT d.id, d.user_id, d.platform, d.token FROM devices d LEFT JOIN notification_preferences np ON np.user_id = d.user_id WHERE d.id > ? AND COALESCE(np." + column + ", TRUE)"
+	args := []interface{}{afterID}
+	if len(userIDs) > 0 {
+		query += " AND d.user_id IN (" + placeholders(len(userIDs)) + ")"
+		for _, id := range userIDs {
+			args = append(args, id)
+		}
+	}
+	query += " ORDER BY d.id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	var devices []domain.Device
+	for rows.Next() {
+		var d domain.Device
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Platform, &d.Token); err != nil {
+			return nil, database.Translate(err)
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+func (r *deviceRepository) GetPreferences(ctx context.Context, userID string) (*domain.NotificationPreferences, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT orders, price_drops FROM notification_preferences WHERE user_id = ?"
+	p := domain.NotificationPreferences{Orders: true, PriceDrops: true}
+	err := r.DB.QueryRowContext(ctx, query, userID).Scan(&p.Orders, &p.PriceDrops)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, database.Translate(err)
+	}
+	return &p, nil
+}
+
+func (r *deviceRepository) SetPreferences(ctx context.Context, userID string, p *domain.NotificationPreferences) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "INSERT INTO notification_preferences (user_id, orders, price_drops, updated_at) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE orders = VALUES(orders), price_drops = VALUES(price_drops), updated_at = VALUES(updated_at)"
+	_, err := r.DB.ExecContext(ctx, query, userID, p.Orders, p.PriceDrops, time.Now().UTC())
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
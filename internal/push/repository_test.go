@@ -0,0 +1,61 @@
+package push
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryGetTargets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewDeviceRepository(db, database.Timeouts{})
+
+	t.Run("every user", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "user_id", "platform", "token"}).AddRow(1, "u1", "ios", "t1")
+		mock.ExpectQuery("SELECT (.+) FROM devices d LEFT JOIN notification_preferences np ON np.user_id = d.user_id WHERE d.id > \\? AND COALESCE\\(np.price_drops, TRUE\\) ORDER BY d.id LIMIT \\?").
+			WithArgs(int64(0), 100).WillReturnRows(rows)
+
+		devices, err := repo.GetTargets(context.Background(), TopicPriceDrops, nil, 0, 100)
+		assert.NoError(t, err)
+		assert.Len(t, devices, 1)
+	})
+
+	t.Run("some users", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "user_id", "platform", "token"})
+		mock.ExpectQuery("WHERE d.id > \\? AND COALESCE\\(np.orders, TRUE\\) AND d.user_id IN \\(\\?, \\?\\) ORDER BY d.id LIMIT \\?").
+			WithArgs(int64(5), "u1", "u2", 100).WillReturnRows(rows)
+
+		devices, err := repo.GetTargets(context.Background(), TopicOrders, []string{"u1", "u2"}, 5, 100)
+		assert.NoError(t, err)
+		assert.Empty(t, devices)
+	})
+
+	t.Run("unknown topic", func(t *testing.T) {
+		_, err := repo.GetTargets(context.Background(), "spam", nil, 0, 100)
+		assert.Error(t, err)
+	})
+}
+
+func TestRepositoryDeleteTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewDeviceRepository(db, database.Timeouts{})
+
+	mock.ExpectExec("DELETE FROM devices WHERE token IN \\(\\?, \\?\\)").WithArgs("t1", "t2").WillReturnResult(sqlmock.NewResult(0, 2))
+
+	assert.NoError(t, repo.DeleteTokens(context.Background(), []string{"t1", "t2"}))
+	assert.NoError(t, repo.DeleteTokens(context.Background(), nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
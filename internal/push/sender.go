@@ -0,0 +1,53 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrInvalidToken is returned by a PushSender when the device token is no
+// longer valid and should be removed
+var ErrInvalidToken = errors.New("invalid device token")
+
+// Message is the content of a push notification
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// PushSender delivers a push notification to one device
+type PushSender interface {
+	Send(ctx context.Context, token string, m *Message) error
+}
+
+// FakeSender records the pushes in memory, for tests
+type FakeSender struct {
+	mu   sync.Mutex
+	sent map[string][]Message
+	// InvalidTokens are answered with ErrInvalidToken
+	InvalidTokens map[string]bool
+}
+
+func (f *FakeSender) Send(ctx context.Context, token string, m *Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.InvalidTokens[token] {
+		return ErrInvalidToken
+	}
+	if f.sent == nil {
+		f.sent = map[string][]Message{}
+	}
+	f.sent[token] = append(f.sent[token], *m)
+	return nil
+}
+
+// Sent returns the messages sent to token
+func (f *FakeSender) Sent(token string) []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Message(nil), f.sent[token]...)
+}
@@ -0,0 +1,247 @@
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+)
+
+// Topics a user can opt out of
+const (
+	TopicOrders     = "orders"
+	TopicPriceDrops = "price_drops"
+)
+
+var (
+	// ErrInvalidPlatform is returned when registering an unknown platform
+	ErrInvalidPlatform = errors.New("platform must be android, ios or web")
+	// ErrDeviceNotFound is returned when deleting a device the user does not own
+	ErrDeviceNotFound = errors.New("device not found")
+)
+
+// PushService interface
+type PushService interface {
+	RegisterDevice(ctx context.Context, userID string, request *domain.RegisterDeviceRequest) (*domain.Device, error)
+	GetDevices(ctx context.Context, userID string) ([]domain.Device, error)
+	DeleteDevice(ctx context.Context, userID string, id int64) error
+	GetPreferences(ctx context.Context, userID string) (*domain.NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, userID string, preferences *domain.NotificationPreferences) error
+}
+
+type pushService struct {
+	repo DeviceRepository
+}
+
+// NewPushService return a new PushService
+func NewPushService(repo DeviceRepository) PushService {
+	return &pushService{repo: repo}
+}
+
+// RegisterDevice store a device token of the user
+func (s *pushService) RegisterDevice(ctx context.Context, userID string, request *domain.RegisterDeviceRequest) (*domain.Device, error) {
+	if !slices.Contains([]string{domain.PlatformAndroid, domain.PlatformIOS, domain.PlatformWeb}, request.Platform) {
+		return nil, ErrInvalidPlatform
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	d := &domain.Device{
+		UserID:     userID,
+		Platform:   request.Platform,
+		Token:      request.Token,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := s.repo.Register(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetDevices return the devices of the user
+func (s *pushService) GetDevices(ctx context.Context, userID string) ([]domain.Device, error) {
+	return s.repo.GetByUser(ctx, userID)
+}
+
+// DeleteDevice unregister a device of the user
+func (s *pushService) DeleteDevice(ctx context.Context, userID string, id int64) error {
+	deleted, err := s.repo.Delete(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+// GetPreferences return the push topics the user accepts
+func (s *pushService) GetPreferences(ctx context.Context, userID string) (*domain.NotificationPreferences, error) {
+	return s.repo.GetPreferences(ctx, userID)
+}
+
+// UpdatePreferences replace the push topics the user accepts
+func (s *pushService) UpdatePreferences(ctx context.Context, userID string, preferences *domain.NotificationPreferences) error {
+	return s.repo.SetPreferences(ctx, userID, preferences)
+}
+
+// Notification is a push to fan out to every device of the target users
+// that accept the topic. No UserIDs means every user.
+type Notification struct {
+	Topic   string
+	UserIDs []string
+	Message Message
+}
+
+// DispatcherOptions configures the fan-out
+type DispatcherOptions struct {
+	// QueueSize bounds the notifications waiting to be fanned out
+	QueueSize int
+	// BatchSize is the number of devices loaded and sent at once
+	BatchSize int
+	// Concurrency is the number of sends in flight within a batch
+	Concurrency int
+}
+
+// Dispatcher fans notifications out to devices in batches and prunes the
+// tokens the push service reports as invalid. Notifications are queued in
+// memory and lost on restart.
+type Dispatcher struct {
+	repo    DeviceRepository
+	sender  PushSender
+	options DispatcherOptions
+	queue   chan Notification
+}
+
+// NewDispatcher return a new Dispatcher
+func NewDispatcher(repo DeviceRepository, sender PushSender, options DispatcherOptions) *Dispatcher {
+	return &Dispatcher{
+		repo:    repo,
+		sender:  sender,
+		options: options,
+		queue:   make(chan Notification, options.QueueSize),
+	}
+}
+
+// Publish queues n without blocking. It reports false when the queue is full.
+func (d *Dispatcher) Publish(n Notification) bool {
+	select {
+	case d.queue <- n:
+		return true
+	default:
+		slog.Warn("Push queue full, dropping notification", "topic", n.Topic)
+		return false
+	}
+}
+
+// PriceDropped publishes a price drop of the product to every user that
+// accepts the topic
+func (d *Dispatcher) PriceDropped(p *domain.Product, previousPrice float64) {
+	d.Publish(Notification{
+		Topic: TopicPriceDrops,
+		Message: Message{
+			Title: "Price drop",
+			Body:  fmt.Sprintf("%s is now %.2f (was %.2f)", p.Name, p.Price, previousPrice),
+			Data:  map[string]string{"type": TopicPriceDrops, "product_id": strconv.Itoa(p.ID)},
+		},
+	})
+}
+
+// Run fans out the queued notifications until ctx is done. The notifications
+// still queued at that point are delivered before returning.
+func (d *Dispatcher) Run(ctx context.Context) {
+	// A delivery that has started is finished even when ctx is done
+	deliverCtx := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			d.drain(deliverCtx)
+			return
+		case n := <-d.queue:
+			if err := d.Deliver(deliverCtx, n); err != nil {
+				slog.Error("Error delivering push", "topic", n.Topic, "error", err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) drain(ctx context.Context) {
+	for {
+		select {
+		case n := <-d.queue:
+			if err := d.Deliver(ctx, n); err != nil {
+				slog.Error("Error delivering push", "topic", n.Topic, "error", err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Deliver sends n to every target device, batch by batch
+func (d *Dispatcher) Deliver(ctx context.Context, n Notification) error {
+	var afterID int64
+	for {
+		devices, err := d.repo.GetTargets(ctx, n.Topic, n.UserIDs, afterID, d.options.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(devices) == 0 {
+			return nil
+		}
+
+		invalid := d.sendBatch(ctx, devices, &n.Message)
+		if err := d.repo.DeleteTokens(ctx, invalid); err != nil {
+			slog.Error("Error pruning invalid push tokens", "count", len(invalid), "error", err)
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if len(devices) < d.options.BatchSize {
+			return nil
+		}
+		afterID = devices[len(devices)-1].ID
+	}
+}
+
+// sendBatch sends m to the devices and returns the invalid tokens
+func (d *Dispatcher) sendBatch(ctx context.Context, devices []domain.Device, m *Message) []string {
+	var (
+		mu      sync.Mutex
+		invalid []string
+		wg      sync.WaitGroup
+	)
+	slots := make(chan struct{}, max(d.options.Concurrency, 1))
+
+	for _, device := range devices {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(device domain.Device) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			err := d.sender.Send(ctx, device.Token, m)
+			if errors.Is(err, ErrInvalidToken) {
+				mu.Lock()
+				invalid = append(invalid, device.Token)
+				mu.Unlock()
+				return
+			}
+			if err != nil {
+				slog.Warn("Error sending push", "device_id", device.ID, "error", err)
+			}
+		}(device)
+	}
+	wg.Wait()
+
+	return invalid
+}
@@ -0,0 +1,155 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryDeviceRepository keeps devices and preferences in memory
+type memoryDeviceRepository struct {
+	devices     []domain.Device
+	preferences map[string]domain.NotificationPreferences
+	pages       int
+}
+
+func (r *memoryDeviceRepository) Register(ctx context.Context, d *domain.Device) error {
+	d.ID = int64(len(r.devices) + 1)
+	r.devices = append(r.devices, *d)
+	return nil
+}
+
+func (r *memoryDeviceRepository) GetByUser(ctx context.Context, userID string) ([]domain.Device, error) {
+	devices := []domain.Device{}
+	for _, d := range r.devices {
+		if d.UserID == userID {
+			devices = append(devices, d)
+		}
+	}
+	return devices, nil
+}
+
+func (r *memoryDeviceRepository) Delete(ctx context.Context, userID string, id int64) (bool, error) {
+	for i, d := range r.devices {
+		if d.ID == id && d.UserID == userID {
+			r.devices = append(r.devices[:i], r.devices[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryDeviceRepository) DeleteTokens(ctx context.Context, tokens []string) error {
+	kept := r.devices[:0]
+	for _, d := range r.devices {
+		if !slices.Contains(tokens, d.Token) {
+			kept = append(kept, d)
+		}
+	}
+	r.devices = kept
+	return nil
+}
+
+func (r *memoryDeviceRepository) GetTargets(ctx context.Context, topic string, userIDs []string, afterID int64, limit int) ([]domain.Device, error) {
+	r.pages++
+	var targets []domain.Device
+	for _, d := range r.devices {
+		if d.ID <= afterID || (len(userIDs) > 0 && !slices.Contains(userIDs, d.UserID)) {
+			continue
+		}
+		if p, ok := r.preferences[d.UserID]; ok && ((topic == TopicOrders && !p.Orders) || (topic == TopicPriceDrops && !p.PriceDrops)) {
+			continue
+		}
+		targets = append(targets, d)
+		if len(targets) == limit {
+			break
+		}
+	}
+	return targets, nil
+}
+
+func (r *memoryDeviceRepository) GetPreferences(ctx context.Context, userID string) (*domain.NotificationPreferences, error) {
+	p, ok := r.preferences[userID]
+	if !ok {
+		p = domain.NotificationPreferences{Orders: true, PriceDrops: true}
+	}
+	return &p, nil
+}
+
+func (r *memoryDeviceRepository) SetPreferences(ctx context.Context, userID string, p *domain.NotificationPreferences) error {
+	r.preferences[userID] = *p
+	return nil
+}
+
+func newMemoryDeviceRepository(devicesPerUser map[string]int) *memoryDeviceRepository {
+	repo := &memoryDeviceRepository{preferences: map[string]domain.NotificationPreferences{}}
+
+	users := make([]string, 0, len(devicesPerUser))
+	for userID := range devicesPerUser {
+		users = append(users, userID)
+	}
+	sort.Strings(users)
+
+	for _, userID := range users {
+		for i := 0; i < devicesPerUser[userID]; i++ {
+			repo.Register(context.Background(), &domain.Device{UserID: userID, Platform: domain.PlatformAndroid, Token: fmt.Sprintf("%s-token-%d", userID, i)})
+		}
+	}
+	return repo
+}
+
+func TestDispatcherDeliver(t *testing.T) {
+	repo := newMemoryDeviceRepository(map[string]int{"u1": 3, "u2": 2, "u3": 1})
+	repo.preferences["u3"] = domain.NotificationPreferences{Orders: true, PriceDrops: false}
+	sender := &FakeSender{InvalidTokens: map[string]bool{"u1-token-1": true}}
+	dispatcher := NewDispatcher(repo, sender, DispatcherOptions{BatchSize: 2, Concurrency: 2})
+
+	err := dispatcher.Deliver(context.Background(), Notification{Topic: TopicPriceDrops, Message: Message{Title: "Price drop"}})
+	assert.NoError(t, err)
+
+	assert.Len(t, sender.Sent("u1-token-0"), 1)
+	assert.Len(t, sender.Sent("u2-token-1"), 1)
+	assert.Empty(t, sender.Sent("u3-token-0"), "u3 opted out of price drops")
+	assert.Equal(t, 3, repo.pages, "5 targets in batches of 2")
+
+	devices, _ := repo.GetByUser(context.Background(), "u1")
+	assert.Len(t, devices, 2, "the invalid token is pruned")
+}
+
+func TestDispatcherDeliverToUsers(t *testing.T) {
+	repo := newMemoryDeviceRepository(map[string]int{"u1": 1, "u2": 1})
+	sender := &FakeSender{}
+	dispatcher := NewDispatcher(repo, sender, DispatcherOptions{BatchSize: 10, Concurrency: 1})
+
+	err := dispatcher.Deliver(context.Background(), Notification{Topic: TopicOrders, UserIDs: []string{"u2"}, Message: Message{Title: "Order shipped"}})
+	assert.NoError(t, err)
+
+	assert.Empty(t, sender.Sent("u1-token-0"))
+	assert.Len(t, sender.Sent("u2-token-0"), 1)
+}
+
+func TestDispatcherPublishDoesNotBlock(t *testing.T) {
+	dispatcher := NewDispatcher(nil, nil, DispatcherOptions{QueueSize: 1})
+
+	assert.True(t, dispatcher.Publish(Notification{Topic: TopicOrders}))
+	assert.False(t, dispatcher.Publish(Notification{Topic: TopicOrders}))
+}
+
+func TestRegisterDevice(t *testing.T) {
+	service := NewPushService(newMemoryDeviceRepository(nil))
+
+	_, err := service.RegisterDevice(context.Background(), "u1", &domain.RegisterDeviceRequest{Platform: "blackberry", Token: "t"})
+	assert.ErrorIs(t, err, ErrInvalidPlatform)
+
+	device, err := service.RegisterDevice(context.Background(), "u1", &domain.RegisterDeviceRequest{Platform: domain.PlatformIOS, Token: "t"})
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", device.UserID)
+
+	assert.ErrorIs(t, service.DeleteDevice(context.Background(), "u2", device.ID), ErrDeviceNotFound)
+	assert.NoError(t, service.DeleteDevice(context.Background(), "u1", device.ID))
+}
@@ -0,0 +1,137 @@
+package rbac
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/user"
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+	"github.com/Jacobo0312/go-web/pkg/middlewares"
+)
+
+// Authorizer builds middlewares that authenticate the caller and check that
+// its role grants a permission
+type Authorizer interface {
+	RequirePermission(permission string) func(http.HandlerFunc) http.HandlerFunc
+	// RequireSelfOrPermission lets callers act on their own {id} path
+	// segment and requires permission for anyone else
+	RequireSelfOrPermission(permission string) func(http.HandlerFunc) http.HandlerFunc
+}
+
+type authorizer struct {
+	service      RBACService
+	userRepo     user.UserRepository
+	authenticate func(http.HandlerFunc) http.HandlerFunc
+}
+
+// NewAuthorizer return a new Authorizer. authenticate must put the caller UID
+// in the request context.
+func NewAuthorizer(service RBACService, userRepo user.UserRepository, authenticate func(http.HandlerFunc) http.HandlerFunc) Authorizer {
+	return &authorizer{
+		service:      service,
+		userRepo:     userRepo,
+		authenticate: authenticate,
+	}
+}
+
+// RequirePermission rejects callers whose role does not grant permission
+func (a *authorizer) RequirePermission(permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return a.authenticate(a.checkPermission(permission, next))
+	}
+}
+
+// RequireSelfOrPermission lets token-authenticated users through when the
+// {id} path value is their own UID. API keys cannot act on themselves without
+// the permission, so a leaked key cannot mint new keys.
+func (a *authorizer) RequireSelfOrPermission(permission string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		withPermission := a.checkPermission(permission, next)
+
+		return a.authenticate(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := middlewares.UserIDFromContext(r.Context())
+			if !ok {
+				helpers.RespondWithError(w, r, errors.NewUnauthorized("Unauthorized"))
+				return
+			}
+
+			if _, isAPIKey := middlewares.ScopesFromContext(r.Context()); isAPIKey || r.PathValue("id") != userID {
+				withPermission(w, r)
+				return
+			}
+
+			if _, ok := a.activeUser(w, r, userID); !ok {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkPermission must run after authenticate
+func (a *authorizer) checkPermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := middlewares.UserIDFromContext(r.Context())
+		if !ok {
+			helpers.RespondWithError(w, r, errors.NewUnauthorized("Unauthorized"))
+			return
+		}
+
+		u, ok := a.activeUser(w, r, userID)
+		if !ok {
+			return
+		}
+
+		// API keys are limited to the scopes they were created with
+		if scopes, ok := middlewares.ScopesFromContext(r.Context()); ok && !slices.Contains(scopes, permission) {
+			helpers.RespondWithError(w, r, errors.NewForbidden("API key scope does not grant "+permission))
+			return
+		}
+
+		allowed, err := a.service.HasPermission(r.Context(), u.Role, permission)
+		if err != nil {
+			helpers.RespondWithError(w, r, errors.NewInternalServerError("Error checking permissions", err))
+			return
+		}
+
+		if !allowed {
+			helpers.RespondWithError(w, r, errors.NewForbidden("Forbidden"))
+			return
+		}
+
+		if !u.EmailVerified {
+			required, err := a.service.RequiresVerifiedEmail(r.Context(), permission)
+			if err != nil {
+				helpers.RespondWithError(w, r, errors.NewInternalServerError("Error checking permissions", err))
+				return
+			}
+			if required {
+				helpers.RespondWithError(w, r, errors.NewForbidden("Email address is not verified"))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	}
+}
+
+// activeUser writes the error response when the caller is unknown or disabled
+func (a *authorizer) activeUser(w http.ResponseWriter, r *http.Request, userID string) (*domain.User, bool) {
+	u, err := a.userRepo.FindByID(r.Context(), userID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error resolving user", "user_id", userID, "error", err)
+		helpers.RespondWithError(w, r, errors.NewForbidden("Forbidden"))
+		return nil, false
+	}
+
+	if u.Disabled {
+		helpers.RespondWithError(w, r, errors.NewForbidden("User is disabled"))
+		return nil, false
+	}
+
+	return u, true
+}
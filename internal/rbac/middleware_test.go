@@ -0,0 +1,215 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/user"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/middlewares"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockRBACService struct {
+	RBACService
+	mock.Mock
+}
+
+func (m *mockRBACService) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	args := m.Called(role, permission)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRBACService) RequiresVerifiedEmail(ctx context.Context, permission string) (bool, error) {
+	args := m.Called(permission)
+	return args.Bool(0), args.Error(1)
+}
+
+type mockUserRepository struct {
+	mock.Mock
+}
+
+func (m *mockUserRepository) Register(ctx context.Context, u *domain.User) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *mockUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *mockUserRepository) GetAll(ctx context.Context) ([]domain.User, error) {
+	args := m.Called()
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *mockUserRepository) Update(ctx context.Context, u *domain.User) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) WithTx(tx database.DBTX) user.UserRepository {
+	return m
+}
+
+// fakeAuthenticate trusts the X-User-ID header as the caller UID
+func fakeAuthenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if uid := r.Header.Get("X-User-ID"); uid != "" {
+			r = r.WithContext(middlewares.ContextWithUserID(r.Context(), uid))
+		}
+		next(w, r)
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	service := new(mockRBACService)
+	userRepo := new(mockUserRepository)
+	authorizer := NewAuthorizer(service, userRepo, fakeAuthenticate)
+
+	handler := authorizer.RequirePermission(PermissionProductsWrite)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	userRepo.On("FindByID", "admin-uid").Return(&domain.User{ID: "admin-uid", Role: "admin"}, nil)
+	userRepo.On("FindByID", "user-uid").Return(&domain.User{ID: "user-uid", Role: "user"}, nil)
+	userRepo.On("FindByID", "disabled-uid").Return(&domain.User{ID: "disabled-uid", Role: "admin", Disabled: true}, nil)
+	userRepo.On("FindByID", "unknown-uid").Return((*domain.User)(nil), errors.New("not found"))
+	service.On("HasPermission", "admin", PermissionProductsWrite).Return(true, nil)
+	service.On("HasPermission", "user", PermissionProductsWrite).Return(false, nil)
+	service.On("RequiresVerifiedEmail", PermissionProductsWrite).Return(false, nil)
+
+	testCases := []struct {
+		name           string
+		userID         string
+		expectedStatus int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"granted", "admin-uid", http.StatusNoContent},
+		{"not granted", "user-uid", http.StatusForbidden},
+		{"disabled user", "disabled-uid", http.StatusForbidden},
+		{"unknown user", "unknown-uid", http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/products", nil)
+			req.Header.Set("X-User-ID", tc.userID)
+			rr := httptest.NewRecorder()
+
+			handler(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+// fakeAPIKeyAuthenticate authenticates like fakeAuthenticate and, when the
+// X-Scopes header is present, marks the request as made with an API key
+func fakeAPIKeyAuthenticate(next http.HandlerFunc) http.HandlerFunc {
+	return fakeAuthenticate(func(w http.ResponseWriter, r *http.Request) {
+		if scopes, ok := r.Header[http.CanonicalHeaderKey("X-Scopes")]; ok {
+			r = r.WithContext(middlewares.ContextWithScopes(r.Context(), scopes))
+		}
+		next(w, r)
+	})
+}
+
+func TestRequireSelfOrPermission(t *testing.T) {
+	service := new(mockRBACService)
+	userRepo := new(mockUserRepository)
+	authorizer := NewAuthorizer(service, userRepo, fakeAPIKeyAuthenticate)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}/api-keys", authorizer.RequireSelfOrPermission(PermissionUsersWrite)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	userRepo.On("FindByID", "admin-uid").Return(&domain.User{ID: "admin-uid", Role: "admin"}, nil)
+	userRepo.On("FindByID", "user-uid").Return(&domain.User{ID: "user-uid", Role: "user"}, nil)
+	service.On("HasPermission", "admin", PermissionUsersWrite).Return(true, nil)
+	service.On("HasPermission", "user", PermissionUsersWrite).Return(false, nil)
+	service.On("RequiresVerifiedEmail", PermissionUsersWrite).Return(false, nil)
+
+	testCases := []struct {
+		name           string
+		userID         string
+		scopes         []string
+		path           string
+		expectedStatus int
+	}{
+		{"self", "user-uid", nil, "/users/user-uid/api-keys", http.StatusNoContent},
+		{"other user", "user-uid", nil, "/users/admin-uid/api-keys", http.StatusForbidden},
+		{"admin on other user", "admin-uid", nil, "/users/user-uid/api-keys", http.StatusNoContent},
+		{"self with api key", "user-uid", []string{"products:write"}, "/users/user-uid/api-keys", http.StatusForbidden},
+		{"admin api key without scope", "admin-uid", []string{"products:write"}, "/users/user-uid/api-keys", http.StatusForbidden},
+		{"admin api key with scope", "admin-uid", []string{PermissionUsersWrite}, "/users/user-uid/api-keys", http.StatusNoContent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("X-User-ID", tc.userID)
+			for _, scope := range tc.scopes {
+				req.Header.Add("X-Scopes", scope)
+			}
+			rr := httptest.NewRecorder()
+
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestRequirePermissionVerifiedEmail(t *testing.T) {
+	service := new(mockRBACService)
+	userRepo := new(mockUserRepository)
+	authorizer := NewAuthorizer(service, userRepo, fakeAuthenticate)
+
+	handler := authorizer.RequirePermission(PermissionCheckout)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	userRepo.On("FindByID", "verified-uid").Return(&domain.User{ID: "verified-uid", Role: "user", EmailVerified: true}, nil)
+	userRepo.On("FindByID", "unverified-uid").Return(&domain.User{ID: "unverified-uid", Role: "user"}, nil)
+	service.On("HasPermission", "user", PermissionCheckout).Return(true, nil)
+	service.On("RequiresVerifiedEmail", PermissionCheckout).Return(true, nil)
+
+	testCases := []struct {
+		name           string
+		userID         string
+		expectedStatus int
+	}{
+		{"verified", "verified-uid", http.StatusNoContent},
+		{"unverified", "unverified-uid", http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/checkout", nil)
+			req.Header.Set("X-User-ID", tc.userID)
+			rr := httptest.NewRecorder()
+
+			handler(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+)
+
+type RBACRepository interface {
+	GetRoles(ctx context.Context) ([]domain.Role, error)
+	GetRole(ctx context.Context, name string) (*domain.Role, error)
+	CreateRole(ctx context.Context, role *domain.Role) error
+	SetRolePermissions(ctx context.Context, name string, permissions []string) error
+	DeleteRole(ctx context.Context, name string) error
+	GetPermissions(ctx context.Context) ([]domain.Permission, error)
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+	RequiresVerifiedEmail(ctx context.Context, permission string) (bool, error)
+}
+
+type rbacRepository struct {
+	DB       *sql.DB
+	timeouts database.Timeouts
+}
+
+func NewRBACRepository(db *sql.DB, timeouts database.Timeouts) RBACRepository {
+	return &rbacRepository{DB: db, timeouts: timeouts}
+}
+
+func (r *rbacRepository) GetRoles(ctx context.Context) ([]domain.Role, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT r.name, r.description, rp.permission FROM roles r LEFT JOIN role_permissions rp ON rp.role = r.name ORDER BY r.name, rp.permission"
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	var roles []domain.Role
+	for rows.Next() {
+		var role domain.Role
+		var permission sql.NullString
+		err := rows.Scan(&role.Name, &role.Description, &permission)
+		if err != nil {
+			return nil, database.Translate(err)
+		}
+
+		if len(roles) == 0 || roles[len(roles)-1].Name != role.Name {
+			role.Permissions = []string{}
+			roles = append(roles, role)
+		}
+		if permission.Valid {
+			last := &roles[len(roles)-1]
+			last.Permissions = append(last.Permissions, permission.String)
+		}
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *rbacRepository) GetRole(ctx context.Context, name string) (*domain.Role, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT name, description FROM roles WHERE name = ?"
+	var role domain.Role
+	err := r.DB.QueryRowContext(ctx, query, name).Scan(&role.Name, &role.Description)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	query = "SELECT permission FROM role_permissions WHERE role = ? ORDER BY permission"
+	rows, err := r.DB.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	role.Permissions = []string{}
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, database.Translate(err)
+		}
+		role.Permissions = append(role.Permissions, permission)
+	}
+
+	return &role, rows.Err()
+}
+
+func (r *rbacRepository) CreateRole(ctx context.Context, role *domain.Role) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Translate(err)
+	}
+	defer tx.Rollback()
+
+	query := "INSERT INTO roles (name, description) VALUES (?, ?)"
+	if _, err := tx.ExecContext(ctx, query, role.Name, role.Description); err != nil {
+		return database.Translate(err)
+	}
+
+	if err := insertRolePermissions(ctx, tx, role.Name, role.Permissions); err != nil {
+		return database.Translate(err)
+	}
+
+	return tx.Commit()
+}
+
+// SetRolePermissions replaces the permissions granted to a role
+func (r *rbacRepository) SetRolePermissions(ctx context.Context, name string, permissions []string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return database.Translate(err)
+	}
+	defer tx.Rollback()
+
+	query := "DELETE FROM role_permissions WHERE role = ?"
+	if _, err := tx.ExecContext(ctx, query, name); err != nil {
+		return database.Translate(err)
+	}
+
+	if err := insertRolePermissions(ctx, tx, name, permissions); err != nil {
+		return database.Translate(err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *rbacRepository) DeleteRole(ctx context.Context, name string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "DELETE FROM roles WHERE name = ?"
+	result, err := r.DB.ExecContext(ctx, query, name)
+	if err != nil {
+		return database.Translate(err)
+	}
+
+	return database.RequireRows(result)
+}
+
+func (r *rbacRepository) GetPermissions(ctx context.Context) ([]domain.Permission, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT name, description, requires_verified_email FROM permissions ORDER BY name"
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	var permissions []domain.Permission
+	for rows.Next() {
+		var p domain.Permission
+		if err := rows.Scan(&p.Name, &p.Description, &p.RequiresVerifiedEmail); err != nil {
+			return nil, database.Translate(err)
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+func (r *rbacRepository) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT COUNT(*) FROM role_permissions WHERE role = ? AND permission = ?"
+	var count int
+	err := r.DB.QueryRowContext(ctx, query, role, permission).Scan(&count)
+	if err != nil {
+		return false, database.Translate(err)
+	}
+
+	return count > 0, nil
+}
+
+func (r *rbacRepository) RequiresVerifiedEmail(ctx context.Context, permission string) (bool, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT requires_verified_email FROM permissions WHERE name = ?"
+	var required bool
+	err := r.DB.QueryRowContext(ctx, query, permission).Scan(&required)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, database.Translate(err)
+	}
+
+	return required, nil
+}
+
+func insertRolePermissions(ctx context.Context, tx *sql.Tx, role string, permissions []string) error {
+	query := "INSERT INTO role_permissions (role, permission) VALUES (?, ?)"
+	for _, permission := range permissions {
+		if _, err := tx.ExecContext(ctx, query, role, permission); err != nil {
+			return database.Translate(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryGetRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRBACRepository(db, database.Timeouts{})
+
+	t.Run("roles with permissions", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"name", "description", "permission"}).
+			AddRow("admin", "Full access", "products:write").
+			AddRow("admin", "Full access", "users:write").
+			AddRow("user", "Regular customer", nil)
+		mock.ExpectQuery("SELECT (.+) FROM roles r LEFT JOIN role_permissions").WillReturnRows(rows)
+
+		roles, err := repo.GetRoles(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, roles, 2)
+		assert.Equal(t, []string{"products:write", "users:write"}, roles[0].Permissions)
+		assert.Equal(t, []string{}, roles[1].Permissions)
+	})
+}
+
+func TestRepositoryGetRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRBACRepository(db, database.Timeouts{})
+
+	t.Run("role found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT name, description FROM roles WHERE name = ?").WithArgs("admin").
+			WillReturnRows(sqlmock.NewRows([]string{"name", "description"}).AddRow("admin", "Full access"))
+		mock.ExpectQuery("SELECT permission FROM role_permissions WHERE role = ?").WithArgs("admin").
+			WillReturnRows(sqlmock.NewRows([]string{"permission"}).AddRow("roles:manage"))
+
+		role, err := repo.GetRole(context.Background(), "admin")
+		assert.NoError(t, err)
+		assert.Equal(t, "admin", role.Name)
+		assert.Equal(t, []string{"roles:manage"}, role.Permissions)
+	})
+
+	t.Run("role not found", func(t *testing.T) {
+		mock.ExpectQuery("SELECT name, description FROM roles WHERE name = ?").WithArgs("ghost").WillReturnError(sql.ErrNoRows)
+
+		role, err := repo.GetRole(context.Background(), "ghost")
+		assert.Error(t, err)
+		assert.Nil(t, role)
+	})
+}
+
+func TestRepositoryCreateRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRBACRepository(db, database.Timeouts{})
+
+	t.Run("successful creation", func(t *testing.T) {
+		role := &domain.Role{Name: "editor", Description: "Catalog editor", Permissions: []string{"products:write"}}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO roles").WithArgs("editor", "Catalog editor").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO role_permissions").WithArgs("editor", "products:write").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.CreateRole(context.Background(), role)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown permission rolls back", func(t *testing.T) {
+		role := &domain.Role{Name: "broken", Permissions: []string{"nope"}}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO roles").WithArgs("broken", "").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO role_permissions").WithArgs("broken", "nope").WillReturnError(errors.New("foreign key error"))
+		mock.ExpectRollback()
+
+		err := repo.CreateRole(context.Background(), role)
+		assert.Error(t, err)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositorySetRolePermissions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRBACRepository(db, database.Timeouts{})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM role_permissions WHERE role = ?").WithArgs("user").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO role_permissions").WithArgs("user", "users:read").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.SetRolePermissions(context.Background(), "user", []string{"users:read"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryHasPermission(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewRBACRepository(db, database.Timeouts{})
+
+	t.Run("granted", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM role_permissions").WithArgs("admin", "products:write").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		ok, err := repo.HasPermission(context.Background(), "admin", "products:write")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("not granted", func(t *testing.T) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM role_permissions").WithArgs("user", "products:write").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		ok, err := repo.HasPermission(context.Background(), "user", "products:write")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,139 @@
+package rbac
+
+import (
+	"context"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/internal/user"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+)
+
+// Permissions checked by the API
+const (
+	PermissionProductsWrite  = "products:write"
+	PermissionUsersRead      = "users:read"
+	PermissionUsersWrite     = "users:write"
+	PermissionRolesManage    = "roles:manage"
+	PermissionAuthManage     = "auth:manage"
+	PermissionWebhooksManage = "webhooks:manage"
+	PermissionConfigRead     = "config:read"
+	// PermissionCheckout is reserved for placing orders and requires a
+	// verified email address
+	PermissionCheckout = "checkout"
+)
+
+// RBACService interface
+type RBACService interface {
+	GetRoles(ctx context.Context) ([]domain.Role, error)
+	GetRole(ctx context.Context, name string) (*domain.Role, error)
+	CreateRole(ctx context.Context, role *domain.Role) error
+	SetRolePermissions(ctx context.Context, name string, permissions []string) (*domain.Role, error)
+	DeleteRole(ctx context.Context, name string) error
+	GetPermissions(ctx context.Context) ([]domain.Permission, error)
+	AssignRole(ctx context.Context, userID, role string) (*domain.User, error)
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+	RequiresVerifiedEmail(ctx context.Context, permission string) (bool, error)
+}
+
+type rbacService struct {
+	repo     RBACRepository
+	userRepo user.UserRepository
+	tx       database.Transactor
+	outbox   events.Outbox
+	provider identity.IdentityProvider
+}
+
+// NewRBACService return a new RBACService
+func NewRBACService(repo RBACRepository, userRepo user.UserRepository, tx database.Transactor, outbox events.Outbox, provider identity.IdentityProvider) RBACService {
+	return &rbacService{repo: repo, userRepo: userRepo, tx: tx, outbox: outbox, provider: provider}
+}
+
+// GetRoles return all roles with their permissions
+func (s *rbacService) GetRoles(ctx context.Context) ([]domain.Role, error) {
+	return s.repo.GetRoles(ctx)
+}
+
+// GetRole return a role by name
+func (s *rbacService) GetRole(ctx context.Context, name string) (*domain.Role, error) {
+	return s.repo.GetRole(ctx, name)
+}
+
+// CreateRole create a new role
+func (s *rbacService) CreateRole(ctx context.Context, role *domain.Role) error {
+	if role.Permissions == nil {
+		role.Permissions = []string{}
+	}
+	return s.repo.CreateRole(ctx, role)
+}
+
+// SetRolePermissions replace the permissions of a role
+func (s *rbacService) SetRolePermissions(ctx context.Context, name string, permissions []string) (*domain.Role, error) {
+	if _, err := s.repo.GetRole(ctx, name); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetRolePermissions(ctx, name, permissions); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetRole(ctx, name)
+}
+
+// DeleteRole delete a role
+func (s *rbacService) DeleteRole(ctx context.Context, name string) error {
+	return s.repo.DeleteRole(ctx, name)
+}
+
+// GetPermissions return all known permissions
+func (s *rbacService) GetPermissions(ctx context.Context) ([]domain.Permission, error) {
+	return s.repo.GetPermissions(ctx)
+}
+
+// AssignRole change the role of a user and publish it as a custom claim.
+// The users row and its user.updated event are written in one transaction;
+// if it fails, the previous claim is restored.
+func (s *rbacService) AssignRole(ctx context.Context, userID, role string) (*domain.User, error) {
+	if _, err := s.repo.GetRole(ctx, role); err != nil {
+		return nil, err
+	}
+
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := u.Role
+	if err := s.provider.SetCustomClaims(ctx, userID, user.RoleClaims(role)); err != nil {
+		return nil, err
+	}
+
+	u.Role = role
+	err = s.tx.WithinTx(ctx, func(tx database.DBTX) error {
+		if err := s.userRepo.WithTx(tx).Update(ctx, u); err != nil {
+			return err
+		}
+		return s.outbox.Add(ctx, tx, events.UserUpdated, u)
+	})
+	if err != nil {
+		if rbErr := s.provider.SetCustomClaims(ctx, userID, user.RoleClaims(previous)); rbErr != nil {
+			logging.FromContext(ctx).Error("Error restoring custom claims", "error", rbErr)
+		}
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// HasPermission report whether the role grants the permission
+func (s *rbacService) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	return s.repo.HasPermission(ctx, role, permission)
+}
+
+// RequiresVerifiedEmail report whether the permission is denied to users
+// with an unverified email address
+func (s *rbacService) RequiresVerifiedEmail(ctx context.Context, permission string) (bool, error) {
+	return s.repo.RequiresVerifiedEmail(ctx, permission)
+}
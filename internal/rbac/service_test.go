@@ -0,0 +1,84 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/internal/user"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/Jacobo0312/go-web/pkg/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// knownRoles is a repository that only knows the roles it lists
+type knownRoles struct {
+	RBACRepository
+	names []string
+}
+
+func (r knownRoles) GetRole(ctx context.Context, name string) (*domain.Role, error) {
+	for _, n := range r.names {
+		if n == name {
+			return &domain.Role{Name: name}, nil
+		}
+	}
+	return nil, errors.New("role not found")
+}
+
+type mockIdentityProvider struct {
+	identity.IdentityProvider
+	mock.Mock
+}
+
+func (m *mockIdentityProvider) SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	args := m.Called(uid, claims)
+	return args.Error(0)
+}
+
+func TestServiceAssignRole(t *testing.T) {
+	t.Run("update emits user.updated", func(t *testing.T) {
+		userRepo := new(mockUserRepository)
+		provider := new(mockIdentityProvider)
+		transactor := &test.FakeTransactor{}
+		outbox := &events.MemoryOutbox{}
+		service := NewRBACService(knownRoles{names: []string{"user", "admin"}}, userRepo, transactor, outbox, provider)
+
+		userRepo.On("FindByID", "uid-1").Return(&domain.User{ID: "uid-1", Role: "user"}, nil)
+		provider.On("SetCustomClaims", "uid-1", user.RoleClaims("admin")).Return(nil)
+		userRepo.On("Update", &domain.User{ID: "uid-1", Role: "admin"}).Return(nil)
+
+		u, err := service.AssignRole(context.Background(), "uid-1", "admin")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "admin", u.Role)
+		assert.Equal(t, 1, transactor.Commits)
+		emitted := outbox.Events()
+		assert.Len(t, emitted, 1)
+		assert.Equal(t, events.UserUpdated, emitted[0].Type)
+		assert.Contains(t, string(emitted[0].Data), `"role":"admin"`)
+	})
+
+	t.Run("update error restores the claims", func(t *testing.T) {
+		userRepo := new(mockUserRepository)
+		provider := new(mockIdentityProvider)
+		transactor := &test.FakeTransactor{}
+		outbox := &events.MemoryOutbox{}
+		service := NewRBACService(knownRoles{names: []string{"user", "admin"}}, userRepo, transactor, outbox, provider)
+
+		userRepo.On("FindByID", "uid-1").Return(&domain.User{ID: "uid-1", Role: "user"}, nil)
+		provider.On("SetCustomClaims", "uid-1", user.RoleClaims("admin")).Return(nil).Once()
+		userRepo.On("Update", mock.Anything).Return(errors.New("connection reset"))
+		provider.On("SetCustomClaims", "uid-1", user.RoleClaims("user")).Return(nil).Once()
+
+		_, err := service.AssignRole(context.Background(), "uid-1", "admin")
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, transactor.Rollbacks)
+		assert.Empty(t, outbox.Events())
+		provider.AssertExpectations(t)
+	})
+}
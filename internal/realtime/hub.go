@@ -0,0 +1,381 @@
+package realtime
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Topics clients can subscribe to. TopicCart and TopicOrders are scoped to
+// the connected user.
+const (
+	TopicProducts = "products"
+	// TopicProductPrefix is followed by a product id, e.g. "products:42"
+	TopicProductPrefix = "products:"
+	TopicCart          = "cart"
+	TopicOrders        = "orders"
+)
+
+// Message types
+const (
+	MessageSubscribe    = "subscribe"
+	MessageUnsubscribe  = "unsubscribe"
+	MessageSubscribed   = "subscribed"
+	MessageUnsubscribed = "unsubscribed"
+	MessageEvent        = "event"
+	MessageError        = "error"
+)
+
+var (
+	ErrUnknownTopic       = errors.New("unknown topic")
+	ErrTooManyTopics      = errors.New("too many topics")
+	ErrTooManyConnections = errors.New("too many connections")
+	ErrHubClosed          = errors.New("hub closed")
+)
+
+// Message is exchanged in both directions as a JSON text frame
+type Message struct {
+	Type    string          `json:"type"`
+	Topic   string          `json:"topic,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// HubOptions configures the connections
+type HubOptions struct {
+	// SendBuffer is the number of messages a connection can fall behind
+	// before it is closed
+	SendBuffer int
+	// MaxConnectionsPerUser caps the open connections of a user
+	MaxConnectionsPerUser int
+	// MaxTopics caps the subscriptions of a connection
+	MaxTopics      int
+	MaxMessageSize int64
+	PingInterval   time.Duration
+	// PongWait must be longer than PingInterval
+	PongWait  time.Duration
+	WriteWait time.Duration
+}
+
+// Hub routes published changes to the WebSocket connections subscribed to
+// their topics
+type Hub struct {
+	options HubOptions
+
+	mu      sync.RWMutex
+	topics  map[string]map[*client]struct{}
+	users   map[string]int
+	clients map[*client]struct{}
+	closed  bool
+}
+
+type client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	userID string
+	send   chan Message
+	// topics is only used by the read pump
+	topics map[string]string
+	once   sync.Once
+	done   chan struct{}
+}
+
+// NewHub return a new Hub
+func NewHub(options HubOptions) *Hub {
+	return &Hub{
+		options: options,
+		topics:  map[string]map[*client]struct{}{},
+		users:   map[string]int{},
+		clients: map[*client]struct{}{},
+	}
+}
+
+// Acquire reserves a connection for the user. It fails when the user
+// already has MaxConnectionsPerUser connections open.
+func (h *Hub) Acquire(userID string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return ErrHubClosed
+	}
+	if h.users[userID] >= h.options.MaxConnectionsPerUser {
+		return ErrTooManyConnections
+	}
+	h.users[userID]++
+	return nil
+}
+
+// Connections returns the number of open connections
+func (h *Hub) Connections() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.clients)
+}
+
+// Close tells every connection that the server is going away
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for c := range h.clients {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	}
+}
+
+// Release frees a connection reserved with Acquire
+func (h *Hub) Release(userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.users[userID]--
+	if h.users[userID] <= 0 {
+		delete(h.users, userID)
+	}
+}
+
+// Publish routes product events to the products topic and to the topic of
+// the product. It implements events.Publisher.
+func (h *Hub) Publish(eventType string, data interface{}) {
+	if !strings.HasPrefix(eventType, "product.") {
+		return
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("Error encoding event", "event_type", eventType, "error", err)
+		return
+	}
+
+	var product struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(payload, &product); err != nil {
+		slog.Error("Error reading product id of event", "event_type", eventType, "error", err)
+		return
+	}
+
+	h.broadcast(TopicProducts, Message{Type: MessageEvent, Topic: TopicProducts, Event: eventType, Data: payload})
+	topic := TopicProductPrefix + strconv.FormatInt(product.ID, 10)
+	h.broadcast(topic, Message{Type: MessageEvent, Topic: topic, Event: eventType, Data: payload})
+}
+
+// PublishToUser sends an event on a user scoped topic, such as TopicCart
+func (h *Hub) PublishToUser(userID, topic, eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		slog.Error("Error encoding event", "event_type", eventType, "error", err)
+		return
+	}
+
+	h.broadcast(userTopic(userID, topic), Message{Type: MessageEvent, Topic: topic, Event: eventType, Data: payload})
+}
+
+// broadcast never blocks: connections whose buffer is full are closed and
+// expected to reconnect
+func (h *Hub) broadcast(key string, m Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.topics[key] {
+		select {
+		case c.send <- m:
+		default:
+			slog.Warn("Closing slow WebSocket connection", "user_id", c.userID)
+			c.close(websocket.CloseTryAgainLater, "too slow")
+		}
+	}
+}
+
+// Serve runs the connection until it is closed. The user must have been
+// acquired.
+func (h *Hub) Serve(conn *websocket.Conn, userID string) {
+	c := &client{
+		hub:    h,
+		conn:   conn,
+		userID: userID,
+		send:   make(chan Message, h.options.SendBuffer),
+		topics: map[string]string{},
+		done:   make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	closed := h.closed
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+
+	if closed {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	} else {
+		go c.writePump()
+		c.readPump()
+		c.close(websocket.CloseNormalClosure, "")
+	}
+
+	h.mu.Lock()
+	delete(h.clients, c)
+	for _, key := range c.topics {
+		h.removeLocked(key, c)
+	}
+	h.mu.Unlock()
+}
+
+func (h *Hub) subscribe(c *client, topic string) error {
+	key, err := topicKey(c.userID, topic)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.topics[topic]; ok {
+		return nil
+	}
+	if len(c.topics) >= h.options.MaxTopics {
+		return ErrTooManyTopics
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.topics[key] == nil {
+		h.topics[key] = map[*client]struct{}{}
+	}
+	h.topics[key][c] = struct{}{}
+	c.topics[topic] = key
+	return nil
+}
+
+func (h *Hub) unsubscribe(c *client, topic string) {
+	key, ok := c.topics[topic]
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeLocked(key, c)
+	delete(c.topics, topic)
+}
+
+func (h *Hub) removeLocked(key string, c *client) {
+	delete(h.topics[key], c)
+	if len(h.topics[key]) == 0 {
+		delete(h.topics, key)
+	}
+}
+
+func (c *client) readPump() {
+	c.conn.SetReadLimit(c.hub.options.MaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.options.PongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.options.PongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.Warn("Error reading WebSocket message", "user_id", c.userID, "error", err)
+			}
+			return
+		}
+
+		var m Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			// Answered as an unknown message type
+			m = Message{}
+		}
+
+		var reply Message
+		switch m.Type {
+		case MessageSubscribe:
+			if err := c.hub.subscribe(c, m.Topic); err != nil {
+				reply = Message{Type: MessageError, Topic: m.Topic, Message: err.Error()}
+			} else {
+				reply = Message{Type: MessageSubscribed, Topic: m.Topic}
+			}
+		case MessageUnsubscribe:
+			c.hub.unsubscribe(c, m.Topic)
+			reply = Message{Type: MessageUnsubscribed, Topic: m.Topic}
+		default:
+			reply = Message{Type: MessageError, Message: "messages must be JSON with a subscribe or unsubscribe type"}
+		}
+
+		select {
+		case c.send <- reply:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *client) writePump() {
+	ticker := time.NewTicker(c.hub.options.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case m := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.options.WriteWait))
+			if err := c.conn.WriteJSON(m); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.options.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// close stops both pumps and sends a close frame. Closing the connection
+// unblocks the read pump.
+func (c *client) close(code int, text string) {
+	c.once.Do(func() {
+		close(c.done)
+		// Writing may block for WriteWait, so it is not done while the hub
+		// is locked
+		go func() {
+			if code != websocket.CloseAbnormalClosure {
+				deadline := time.Now().Add(c.hub.options.WriteWait)
+				c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), deadline)
+			}
+			c.conn.Close()
+		}()
+	})
+}
+
+// topicKey maps a client topic to the key used by the hub
+func topicKey(userID, topic string) (string, error) {
+	switch {
+	case topic == TopicProducts:
+		return topic, nil
+	case strings.HasPrefix(topic, TopicProductPrefix):
+		id, err := strconv.ParseInt(strings.TrimPrefix(topic, TopicProductPrefix), 10, 64)
+		if err != nil || id < 1 {
+			return "", ErrUnknownTopic
+		}
+		return topic, nil
+	case topic == TopicCart, topic == TopicOrders:
+		return userTopic(userID, topic), nil
+	default:
+		return "", ErrUnknownTopic
+	}
+}
+
+func userTopic(userID, topic string) string {
+	return "users:" + userID + ":" + topic
+}
@@ -1,62 +1,122 @@
  package user
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
 )
 
 type UserRepository interface {
-	Register(u *domain.User) error
-	FindByID(id string) (*domain.User, error)
-	GetAll() ([]domain.User, error)
+	Register(ctx context.Context, u *domain.User) error
+	FindByID(ctx context.Context, id string) (*domain.User, error)
+	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetAll(ctx context.Context) ([]domain.User, error)
+	Update(ctx context.Context, u *domain.User) error
+	Delete(ctx context.Context, id string) error
+	// WithTx returns a repository that runs its queries in tx
+	WithTx(tx database.DBTX) UserRepository
 }
 
 type userRepository struct {
-	DB *sql.DB
+	DB database.DBTX
+	timeouts database.Timeouts
 }
 
-func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepository{DB: db}
+func NewUserRepository(db *sql.DB, timeouts database.Timeouts) UserRepository {
+	return &userRepository{DB: database.Trace(db), timeouts: timeouts}
 }
 
-func (r *userRepository) Register(u *domain.User) error {
-	query := "INSERT INTO users (id, name, email, role) VALUES (?, ?, ?, ?)"
-	_, err := r.DB.Exec(query, u.ID, u.Name, u.Email, u.Role)
+func (r *userRepository) WithTx(tx database.DBTX) UserRepository {
+	return &userRepository{DB: database.Trace(tx), timeouts: r.timeouts}
+}
+
+func (r *userRepository) Register(ctx context.Context, u *domain.User) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "INSERT INTO users (id, name, email, role, locale, email_verified, disabled) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	_, err := r.DB.ExecContext(ctx, query, u.ID, u.Name, u.Email, u.Role, u.Locale, u.EmailVerified, u.Disabled)
 	if err != nil {
-		return err
+		return database.Translate(err)
 	}
 	return nil
 }
 
-func (r *userRepository) FindByID(id string) (*domain.User, error) {
-	query := "SELECT id, name, email, role FROM users WHERE id = ?"
-	row := r.DB.QueryRow(query, id)
+func (r *userRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, name, email, role, locale, email_verified, disabled FROM users WHERE id = ?"
+	row := r.DB.QueryRowContext(ctx, query, id)
 
 	var u domain.User
-	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Role)
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Locale, &u.EmailVerified, &u.Disabled)
 	if err != nil {
-		return nil, err
+		return nil, database.Translate(err)
 	}
 	return &u, nil
 }
 
-func (r *userRepository) GetAll() ([]domain.User, error) {
-	query := "SELECT id, name, email, role FROM users"
-	rows, err := r.DB.Query(query)
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, name, email, role, locale, email_verified, disabled FROM users WHERE email = ?"
+	row := r.DB.QueryRowContext(ctx, query, email)
+
+	var u domain.User
+	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Locale, &u.EmailVerified, &u.Disabled)
 	if err != nil {
-		return nil, err
+		return nil, database.Translate(err)
+	}
+	return &u, nil
+}
+
+func (r *userRepository) GetAll(ctx context.Context) ([]domain.User, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, name, email, role, locale, email_verified, disabled FROM users"
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, database.Translate(err)
 	}
 	defer rows.Close()
 
 	var users []domain.User
 	for rows.Next() {
 		var u domain.User
-		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role)
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role, &u.Locale, &u.EmailVerified, &u.Disabled)
 		if err != nil {
-			return nil, err
+			return nil, database.Translate(err)
 		}
 		users = append(users, u)
 	}
 	return users, nil
 }
+
+func (r *userRepository) Update(ctx context.Context, u *domain.User) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE users SET name = ?, email = ?, role = ?, locale = ?, email_verified = ?, disabled = ? WHERE id = ?"
+	result, err := r.DB.ExecContext(ctx, query, u.Name, u.Email, u.Role, u.Locale, u.EmailVerified, u.Disabled, u.ID)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return database.RequireRows(result)
+}
+
+func (r *userRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "DELETE FROM users WHERE id = ?"
+	result, err := r.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return database.RequireRows(result)
+}
@@ -1,12 +1,14 @@
 package user
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/database"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,7 +19,7 @@ func TestNewUserRepository(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, database.Timeouts{})
 	assert.NotNil(t, repo)
 }
 
@@ -28,21 +30,21 @@ func TestRepositoryRegister(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, database.Timeouts{})
 
 	t.Run("successful registration", func(t *testing.T) {
 		user := &domain.User{ID: "1", Name: "John Doe", Email: "john@example.com", Role: "user"}
-		mock.ExpectExec("INSERT INTO users").WithArgs(user.ID, user.Name, user.Email, user.Role).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO users").WithArgs(user.ID, user.Name, user.Email, user.Role, user.Locale, user.EmailVerified, user.Disabled).WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := repo.Register(user)
+		err := repo.Register(context.Background(), user)
 		assert.NoError(t, err)
 	})
 
 	t.Run("registration error", func(t *testing.T) {
 		user := &domain.User{ID: "2", Name: "Jane Doe", Email: "jane@example.com", Role: "user"}
-		mock.ExpectExec("INSERT INTO users").WithArgs(user.ID, user.Name, user.Email, user.Role).WillReturnError(errors.New("database error"))
+		mock.ExpectExec("INSERT INTO users").WithArgs(user.ID, user.Name, user.Email, user.Role, user.Locale, user.EmailVerified, user.Disabled).WillReturnError(errors.New("database error"))
 
-		err := repo.Register(user)
+		err := repo.Register(context.Background(), user)
 		assert.Error(t, err)
 	})
 }
@@ -54,29 +56,49 @@ func TestRepositoryFindByID(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, database.Timeouts{})
 
 	t.Run("user found", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "email", "role"}).
-			AddRow("1", "John Doe", "john@example.com", "user")
+		rows := sqlmock.NewRows([]string{"id", "name", "email", "role", "locale", "email_verified", "disabled"}).
+			AddRow("1", "John Doe", "john@example.com", "user", "en", true, false)
 		mock.ExpectQuery("SELECT (.+) FROM users WHERE id = ?").WithArgs("1").WillReturnRows(rows)
 
-		user, err := repo.FindByID("1")
+		user, err := repo.FindByID(context.Background(), "1")
 		assert.NoError(t, err)
 		assert.NotNil(t, user)
 		assert.Equal(t, "1", user.ID)
 		assert.Equal(t, "John Doe", user.Name)
+		assert.True(t, user.EmailVerified)
 	})
 
 	t.Run("user not found", func(t *testing.T) {
 		mock.ExpectQuery("SELECT (.+) FROM users WHERE id = ?").WithArgs("2").WillReturnError(sql.ErrNoRows)
 
-		user, err := repo.FindByID("2")
+		user, err := repo.FindByID(context.Background(), "2")
 		assert.Error(t, err)
 		assert.Nil(t, user)
 	})
 }
 
+func TestRepositoryFindByEmail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db, database.Timeouts{})
+
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "role", "locale", "email_verified", "disabled"}).
+		AddRow("1", "John Doe", "john@example.com", "user", "", false, false)
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE email = ?").WithArgs("john@example.com").WillReturnRows(rows)
+
+	user, err := repo.FindByEmail(context.Background(), "john@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", user.ID)
+	assert.False(t, user.EmailVerified)
+}
+
 func TestRepositoryGetAll(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -84,26 +106,77 @@ func TestRepositoryGetAll(t *testing.T) {
 	}
 	defer db.Close()
 
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, database.Timeouts{})
 
 	t.Run("get all users", func(t *testing.T) {
-		rows := sqlmock.NewRows([]string{"id", "name", "email", "role"}).
-			AddRow("1", "John Doe", "john@example.com", "user").
-			AddRow("2", "Jane Doe", "jane@example.com", "admin")
+		rows := sqlmock.NewRows([]string{"id", "name", "email", "role", "locale", "email_verified", "disabled"}).
+			AddRow("1", "John Doe", "john@example.com", "user", "en", true, false).
+			AddRow("2", "Jane Doe", "jane@example.com", "admin", "es", false, true)
 		mock.ExpectQuery("SELECT (.+) FROM users").WillReturnRows(rows)
 
-		users, err := repo.GetAll()
+		users, err := repo.GetAll(context.Background())
 		assert.NoError(t, err)
 		assert.Len(t, users, 2)
 		assert.Equal(t, "John Doe", users[0].Name)
 		assert.Equal(t, "Jane Doe", users[1].Name)
+		assert.True(t, users[1].Disabled)
 	})
 
 	t.Run("database error", func(t *testing.T) {
 		mock.ExpectQuery("SELECT (.+) FROM users").WillReturnError(errors.New("database error"))
 
-		users, err := repo.GetAll()
+		users, err := repo.GetAll(context.Background())
 		assert.Error(t, err)
 		assert.Nil(t, users)
 	})
-}
\ No newline at end of file
+}
+
+func TestRepositoryUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db, database.Timeouts{})
+
+	t.Run("successful update", func(t *testing.T) {
+		user := &domain.User{ID: "1", Name: "John Smith", Email: "john@example.com", Role: "user", Disabled: true}
+		mock.ExpectExec("UPDATE users SET").WithArgs(user.Name, user.Email, user.Role, user.Locale, user.EmailVerified, user.Disabled, user.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Update(context.Background(), user)
+		assert.NoError(t, err)
+	})
+
+	t.Run("update error", func(t *testing.T) {
+		user := &domain.User{ID: "2", Name: "Jane Doe", Email: "jane@example.com", Role: "user"}
+		mock.ExpectExec("UPDATE users SET").WithArgs(user.Name, user.Email, user.Role, user.Locale, user.EmailVerified, user.Disabled, user.ID).WillReturnError(errors.New("database error"))
+
+		err := repo.Update(context.Background(), user)
+		assert.Error(t, err)
+	})
+}
+
+func TestRepositoryDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewUserRepository(db, database.Timeouts{})
+
+	t.Run("successful delete", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM users WHERE id = ?").WithArgs("1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Delete(context.Background(), "1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("delete error", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM users WHERE id = ?").WithArgs("2").WillReturnError(errors.New("database error"))
+
+		err := repo.Delete(context.Background(), "2")
+		assert.Error(t, err)
+	})
+}
@@ -2,67 +2,208 @@ package user
 
 import (
 	"context"
-	"log"
+	"errors"
 
-	"firebase.google.com/go/v4/auth"
 	"github.com/Jacobo0312/go-web/internal/domain"
-	"github.com/Jacobo0312/go-web/pkg/firebase"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/internal/notification"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/Jacobo0312/go-web/pkg/logging"
 )
 
+// ErrUnknownRole is returned when a user is created with a role that is not
+// in the roles table
+var ErrUnknownRole = errors.New("unknown role")
+
+// RoleFinder looks roles up, rbac.RBACRepository implements it
+type RoleFinder interface {
+	GetRole(ctx context.Context, name string) (*domain.Role, error)
+}
+
 type UserService interface {
 	CreateUser(ctx context.Context, userRequest *domain.CreateUserRequest) (*domain.User, error)
-	GetUsers() ([]domain.User, error)
+	GetUsers(ctx context.Context) ([]domain.User, error)
+	GetUserByID(ctx context.Context, id string) (*domain.User, error)
+	UpdateUser(ctx context.Context, id string, userRequest *domain.UpdateUserRequest) (*domain.User, error)
+	DeleteUser(ctx context.Context, id string) error
 }
 
 type userService struct {
-	repo UserRepository
+	repo     UserRepository
+	roles    RoleFinder
+	tx       database.Transactor
+	outbox   events.Outbox
+	provider identity.IdentityProvider
+	notifier notification.Notifier
 }
 
-func NewUserService(repo UserRepository) UserService {
-	return &userService{repo: repo}
+func NewUserService(repo UserRepository, roles RoleFinder, tx database.Transactor, outbox events.Outbox, provider identity.IdentityProvider, notifier notification.Notifier) UserService {
+	return &userService{repo: repo, roles: roles, tx: tx, outbox: outbox, provider: provider, notifier: notifier}
 }
 
 func (s *userService) CreateUser(ctx context.Context, userRequest *domain.CreateUserRequest) (*domain.User, error) {
+	// Check the role before the provider creates an account for it
+	if _, err := s.roles.GetRole(ctx, userRequest.Role); err != nil {
+		if errors.Is(err, appErrors.ErrNotFound) {
+			return nil, ErrUnknownRole
+		}
+		return nil, err
+	}
 
-	params := (&auth.UserToCreate{}).
-		Email(userRequest.Email).
-		EmailVerified(false).
-		Password(userRequest.Password).
-		DisplayName(userRequest.Name).
-		Disabled(false)
+	params := &identity.UserToCreate{
+		Email:         userRequest.Email,
+		EmailVerified: false,
+		Password:      userRequest.Password,
+		DisplayName:   userRequest.Name,
+		Disabled:      false,
+	}
 
-	user, err := firebase.FirebaseAuth.CreateUser(ctx, params)
+	user, err := s.provider.CreateUser(ctx, params)
 
 	if err != nil {
-		log.Printf("Error creating user: %v", err)
+		logging.FromContext(ctx).Error("Error creating user", "error", err)
 		return nil, err
 	}
 
 	defer func() {
 		if err != nil {
-			if err := firebase.FirebaseAuth.DeleteUser(ctx, user.UID); err != nil {
-				log.Printf("Error deleting user from Firebase: %v", err)
+			if err := s.provider.DeleteUser(ctx, user.UID); err != nil {
+				logging.FromContext(ctx).Error("Error deleting user from identity provider", "error", err)
 			}
 		}
 	}()
 
 	userModel := &domain.User{
-		ID:    user.UID,
-		Name:  userRequest.Name,
-		Email: userRequest.Email,
-		Role:  userRequest.Role,
+		ID:     user.UID,
+		Name:   userRequest.Name,
+		Email:  userRequest.Email,
+		Role:   userRequest.Role,
+		Locale: userRequest.Locale,
 	}
 
-	err = s.repo.Register(userModel)
+	err = s.save(ctx, userModel, events.UserCreated, func(repo UserRepository) error {
+		return repo.Register(ctx, userModel)
+	})
 	if err != nil {
-		log.Printf("Error creating user: %v", err)
+		logging.FromContext(ctx).Error("Error creating user", "error", err)
 		return nil, err
 	}
 
+	if err := s.provider.SetCustomClaims(ctx, user.UID, RoleClaims(userModel.Role)); err != nil {
+		logging.FromContext(ctx).Error("Error setting custom claims", "error", err)
+	}
+
+	welcome := notification.WelcomeData{Name: userModel.Name, Email: userModel.Email}
+	if err := s.notifier.Notify(ctx, userModel.Email, userModel.Locale, notification.TemplateWelcome, welcome); err != nil {
+		logging.FromContext(ctx).Error("Error queueing welcome email", "error", err)
+	}
+
 	return userModel, nil
 
 }
 
-func (s *userService) GetUsers() ([]domain.User, error) {
-	return s.repo.GetAll()
+func (s *userService) GetUsers(ctx context.Context) ([]domain.User, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *userService) GetUserByID(ctx context.Context, id string) (*domain.User, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+// UpdateUser applies the changes to the identity provider first and then to
+// the users table. If the table update fails, the provider is restored to the
+// previous values.
+func (s *userService) UpdateUser(ctx context.Context, id string, userRequest *domain.UpdateUserRequest) (*domain.User, error) {
+	current, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *current
+	if userRequest.Name != nil {
+		updated.Name = *userRequest.Name
+	}
+	if userRequest.Email != nil && *userRequest.Email != current.Email {
+		// A new address has to be verified again
+		updated.Email = *userRequest.Email
+		updated.EmailVerified = false
+	}
+	if userRequest.Locale != nil {
+		updated.Locale = *userRequest.Locale
+	}
+	if userRequest.Disabled != nil {
+		updated.Disabled = *userRequest.Disabled
+	}
+
+	if _, err := s.provider.UpdateUser(ctx, id, identityUserToUpdate(&updated)); err != nil {
+		logging.FromContext(ctx).Error("Error updating user in identity provider", "error", err)
+		return nil, err
+	}
+
+	err = s.save(ctx, &updated, events.UserUpdated, func(repo UserRepository) error {
+		return repo.Update(ctx, &updated)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Error updating user", "error", err)
+		if _, rbErr := s.provider.UpdateUser(ctx, id, identityUserToUpdate(current)); rbErr != nil {
+			logging.FromContext(ctx).Error("Error restoring user in identity provider", "error", rbErr)
+		}
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// DeleteUser removes the user from the users table and the identity provider
+// in one transaction. If the provider fails, the database delete is rolled
+// back.
+func (s *userService) DeleteUser(ctx context.Context, id string) error {
+	// The provider goes last, so the delete, and the rows that cascade from
+	// it, only commit once the user is gone from the provider. A user missing
+	// there was left by an earlier attempt whose commit failed.
+	err := s.tx.WithinTx(ctx, func(tx database.DBTX) error {
+		if err := s.repo.WithTx(tx).Delete(ctx, id); err != nil {
+			return err
+		}
+		if err := s.outbox.Add(ctx, tx, events.UserDeleted, map[string]string{"id": id}); err != nil {
+			return err
+		}
+		if err := s.provider.DeleteUser(ctx, id); err != nil && !errors.Is(err, identity.ErrUserNotFound) {
+			logging.FromContext(ctx).Error("Error deleting user from identity provider", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Error deleting user", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// save runs write and records the event in the same transaction
+func (s *userService) save(ctx context.Context, data interface{}, eventType string, write func(repo UserRepository) error) error {
+	return s.tx.WithinTx(ctx, func(tx database.DBTX) error {
+		if err := write(s.repo.WithTx(tx)); err != nil {
+			return err
+		}
+		return s.outbox.Add(ctx, tx, eventType, data)
+	})
+}
+
+// RoleClaims returns the custom claims published to the identity provider for a role
+func RoleClaims(role string) map[string]interface{} {
+	return map[string]interface{}{"role": role}
+}
+
+func identityUserToUpdate(u *domain.User) *identity.UserToUpdate {
+	return &identity.UserToUpdate{
+		DisplayName:   &u.Name,
+		Email:         &u.Email,
+		EmailVerified: &u.EmailVerified,
+		Disabled:      &u.Disabled,
+	}
 }
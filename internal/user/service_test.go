@@ -1,9 +1,19 @@
 package user
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/internal/notification"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/Jacobo0312/go-web/pkg/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -12,24 +22,258 @@ type mockUserRepository struct {
 	mock.Mock
 }
 
-func (m *mockUserRepository) Register(u *domain.User) error {
+func (m *mockUserRepository) Register(ctx context.Context, u *domain.User) error {
 	args := m.Called(u)
 	return args.Error(0)
 }
 
-func (m *mockUserRepository) FindByID(id string) (*domain.User, error) {
+func (m *mockUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
 	args := m.Called(id)
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
-func (m *mockUserRepository) GetAll() ([]domain.User, error) {
+func (m *mockUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	args := m.Called(email)
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *mockUserRepository) GetAll(ctx context.Context) ([]domain.User, error) {
 	args := m.Called()
 	return args.Get(0).([]domain.User), args.Error(1)
 }
 
+func (m *mockUserRepository) Update(ctx context.Context, u *domain.User) error {
+	args := m.Called(u)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockUserRepository) WithTx(tx database.DBTX) UserRepository {
+	return m
+}
+
+type mockIdentityProvider struct {
+	mock.Mock
+}
+
+func (m *mockIdentityProvider) CreateUser(ctx context.Context, params *identity.UserToCreate) (*identity.User, error) {
+	args := m.Called(params)
+	return args.Get(0).(*identity.User), args.Error(1)
+}
+
+func (m *mockIdentityProvider) UpdateUser(ctx context.Context, uid string, params *identity.UserToUpdate) (*identity.User, error) {
+	args := m.Called(uid, params)
+	return args.Get(0).(*identity.User), args.Error(1)
+}
+
+func (m *mockIdentityProvider) DeleteUser(ctx context.Context, uid string) error {
+	args := m.Called(uid)
+	return args.Error(0)
+}
+
+func (m *mockIdentityProvider) VerifyToken(ctx context.Context, token string) (*identity.Token, error) {
+	args := m.Called(token)
+	return args.Get(0).(*identity.Token), args.Error(1)
+}
+
+func (m *mockIdentityProvider) SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	args := m.Called(uid, claims)
+	return args.Error(0)
+}
+
+type mockNotifier struct {
+	mock.Mock
+}
+
+func (m *mockNotifier) Notify(ctx context.Context, to, locale, template string, data interface{}) error {
+	args := m.Called(to, locale, template, data)
+	return args.Error(0)
+}
+
+// knownRoles finds the roles it lists
+type knownRoles []string
+
+func (r knownRoles) GetRole(ctx context.Context, name string) (*domain.Role, error) {
+	for _, role := range r {
+		if role == name {
+			return &domain.Role{Name: name}, nil
+		}
+	}
+	return nil, fmt.Errorf("finding role: %w", appErrors.ErrNotFound)
+}
+
+func TestServiceCreateUser(t *testing.T) {
+	request := &domain.CreateUserRequest{Name: "John Doe", Email: "john@example.com", Password: "secret123", Role: "user", Locale: "es"}
+	params := &identity.UserToCreate{Email: request.Email, Password: request.Password, DisplayName: request.Name}
+
+	t.Run("successful creation", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		mockNotifier := new(mockNotifier)
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, &events.MemoryOutbox{}, mockProvider, mockNotifier)
+
+		expectedUser := &domain.User{ID: "uid-1", Name: "John Doe", Email: "john@example.com", Role: "user", Locale: "es"}
+		mockProvider.On("CreateUser", params).Return(&identity.User{UID: "uid-1"}, nil)
+		mockRepo.On("Register", expectedUser).Return(nil)
+		mockProvider.On("SetCustomClaims", "uid-1", RoleClaims("user")).Return(nil)
+		mockNotifier.On("Notify", "john@example.com", "es", notification.TemplateWelcome, notification.WelcomeData{Name: "John Doe", Email: "john@example.com"}).Return(nil)
+
+		user, err := service.CreateUser(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, expectedUser, user)
+		mockRepo.AssertExpectations(t)
+		mockProvider.AssertExpectations(t)
+		mockNotifier.AssertExpectations(t)
+	})
+
+	t.Run("repository error deletes the provider account", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, &events.MemoryOutbox{}, mockProvider, new(mockNotifier))
+
+		mockProvider.On("CreateUser", params).Return(&identity.User{UID: "uid-2"}, nil)
+		mockRepo.On("Register", mock.Anything).Return(errors.New("duplicate email"))
+		mockProvider.On("DeleteUser", "uid-2").Return(nil)
+
+		user, err := service.CreateUser(context.Background(), request)
+
+		assert.Error(t, err)
+		assert.Nil(t, user)
+		mockRepo.AssertExpectations(t)
+		mockProvider.AssertExpectations(t)
+	})
+
+	t.Run("unknown role never reaches the provider", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, &events.MemoryOutbox{}, mockProvider, new(mockNotifier))
+
+		unknown := *request
+		unknown.Role = "superuser"
+		user, err := service.CreateUser(context.Background(), &unknown)
+
+		assert.ErrorIs(t, err, ErrUnknownRole)
+		assert.Nil(t, user)
+		mockProvider.AssertNotCalled(t, "CreateUser", mock.Anything)
+		mockRepo.AssertNotCalled(t, "Register", mock.Anything)
+	})
+}
+
+func TestServiceUpdateUser(t *testing.T) {
+	name := "John Smith"
+	current := domain.User{ID: "uid-1", Name: "John Doe", Email: "john@example.com", Role: "user"}
+
+	t.Run("successful update", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, &events.MemoryOutbox{}, mockProvider, new(mockNotifier))
+
+		stored := current
+		expected := current
+		expected.Name = name
+		mockRepo.On("FindByID", "uid-1").Return(&stored, nil)
+		mockProvider.On("UpdateUser", "uid-1", identityUserToUpdate(&expected)).Return(&identity.User{UID: "uid-1"}, nil).Once()
+		mockRepo.On("Update", &expected).Return(nil)
+
+		user, err := service.UpdateUser(context.Background(), "uid-1", &domain.UpdateUserRequest{Name: &name})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &expected, user)
+		mockRepo.AssertExpectations(t)
+		mockProvider.AssertExpectations(t)
+	})
+
+	t.Run("repository error restores the provider account", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, &events.MemoryOutbox{}, mockProvider, new(mockNotifier))
+
+		stored := current
+		expected := current
+		expected.Name = name
+		mockRepo.On("FindByID", "uid-1").Return(&stored, nil)
+		mockProvider.On("UpdateUser", "uid-1", identityUserToUpdate(&expected)).Return(&identity.User{UID: "uid-1"}, nil).Once()
+		mockRepo.On("Update", &expected).Return(errors.New("database error"))
+		mockProvider.On("UpdateUser", "uid-1", identityUserToUpdate(&current)).Return(&identity.User{UID: "uid-1"}, nil).Once()
+
+		user, err := service.UpdateUser(context.Background(), "uid-1", &domain.UpdateUserRequest{Name: &name})
+
+		assert.Error(t, err)
+		assert.Nil(t, user)
+		mockRepo.AssertExpectations(t)
+		mockProvider.AssertExpectations(t)
+	})
+}
+
+func TestServiceDeleteUser(t *testing.T) {
+	t.Run("successful delete", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		transactor := &test.FakeTransactor{}
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, transactor, &events.MemoryOutbox{}, mockProvider, new(mockNotifier))
+
+		mockRepo.On("Delete", "uid-1").Return(nil)
+		mockProvider.On("DeleteUser", "uid-1").Return(nil)
+
+		err := service.DeleteUser(context.Background(), "uid-1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, transactor.Commits)
+		mockRepo.AssertExpectations(t)
+		mockProvider.AssertExpectations(t)
+	})
+
+	t.Run("provider error rolls back the delete", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		transactor := &test.FakeTransactor{}
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, transactor, &events.MemoryOutbox{}, mockProvider, new(mockNotifier))
+
+		mockRepo.On("Delete", "uid-1").Return(nil)
+		mockProvider.On("DeleteUser", "uid-1").Return(errors.New("provider error"))
+
+		err := service.DeleteUser(context.Background(), "uid-1")
+
+		assert.Error(t, err)
+		assert.Equal(t, 0, transactor.Commits)
+		assert.Equal(t, 1, transactor.Rollbacks)
+		mockRepo.AssertExpectations(t)
+		mockProvider.AssertExpectations(t)
+	})
+
+	t.Run("user already gone from the provider", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		transactor := &test.FakeTransactor{}
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, transactor, &events.MemoryOutbox{}, mockProvider, new(mockNotifier))
+
+		mockRepo.On("Delete", "uid-1").Return(nil)
+		mockProvider.On("DeleteUser", "uid-1").Return(identity.ErrUserNotFound)
+
+		assert.NoError(t, service.DeleteUser(context.Background(), "uid-1"))
+		assert.Equal(t, 1, transactor.Commits)
+	})
+
+	t.Run("missing user is not deleted from the provider", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, &events.MemoryOutbox{}, mockProvider, new(mockNotifier))
+
+		mockRepo.On("Delete", "uid-1").Return(sql.ErrNoRows)
+
+		assert.ErrorIs(t, service.DeleteUser(context.Background(), "uid-1"), sql.ErrNoRows)
+		mockProvider.AssertNotCalled(t, "DeleteUser", "uid-1")
+	})
+}
+
 func TestServiceGetUsers(t *testing.T) {
 	mockRepo := new(mockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, &events.MemoryOutbox{}, new(mockIdentityProvider), new(mockNotifier))
 
 	t.Run("successful get users", func(t *testing.T) {
 		expectedUsers := []domain.User{
@@ -39,7 +283,7 @@ func TestServiceGetUsers(t *testing.T) {
 
 		mockRepo.On("GetAll").Return(expectedUsers, nil)
 
-		users, err := service.GetUsers()
+		users, err := service.GetUsers(context.Background())
 
 		assert.NoError(t, err)
 		assert.Equal(t, expectedUsers, users)
@@ -47,3 +291,68 @@ func TestServiceGetUsers(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestServiceGetUserByID(t *testing.T) {
+	mockRepo := new(mockUserRepository)
+	service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, &events.MemoryOutbox{}, new(mockIdentityProvider), new(mockNotifier))
+
+	t.Run("user found", func(t *testing.T) {
+		expectedUser := &domain.User{ID: "1", Name: "User 1", Email: "user1@example.com", Role: "user"}
+		mockRepo.On("FindByID", "1").Return(expectedUser, nil)
+
+		user, err := service.GetUserByID(context.Background(), "1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, expectedUser, user)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockRepo.On("FindByID", "2").Return((*domain.User)(nil), errors.New("user not found"))
+
+		user, err := service.GetUserByID(context.Background(), "2")
+
+		assert.Error(t, err)
+		assert.Nil(t, user)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestServiceUserEvents(t *testing.T) {
+	t.Run("create emits user.created", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		mockNotifier := new(mockNotifier)
+		outbox := &events.MemoryOutbox{}
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, outbox, mockProvider, mockNotifier)
+
+		mockProvider.On("CreateUser", mock.Anything).Return(&identity.User{UID: "uid-1"}, nil)
+		mockRepo.On("Register", mock.Anything).Return(nil)
+		mockProvider.On("SetCustomClaims", "uid-1", mock.Anything).Return(nil)
+		mockNotifier.On("Notify", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		_, err := service.CreateUser(context.Background(), &domain.CreateUserRequest{Name: "John Doe", Email: "john@example.com", Password: "secret123", Role: "user"})
+
+		assert.NoError(t, err)
+		emitted := outbox.Events()
+		assert.Len(t, emitted, 1)
+		assert.Equal(t, events.UserCreated, emitted[0].Type)
+		assert.Contains(t, string(emitted[0].Data), `"id":"uid-1"`)
+	})
+
+	t.Run("delete emits user.deleted", func(t *testing.T) {
+		mockRepo := new(mockUserRepository)
+		mockProvider := new(mockIdentityProvider)
+		outbox := &events.MemoryOutbox{}
+		service := NewUserService(mockRepo, knownRoles{"user", "admin"}, &test.FakeTransactor{}, outbox, mockProvider, new(mockNotifier))
+
+		mockRepo.On("Delete", "uid-1").Return(nil)
+		mockProvider.On("DeleteUser", "uid-1").Return(nil)
+
+		assert.NoError(t, service.DeleteUser(context.Background(), "uid-1"))
+		emitted := outbox.Events()
+		assert.Len(t, emitted, 1)
+		assert.Equal(t, events.UserDeleted, emitted[0].Type)
+		assert.JSONEq(t, `{"id":"uid-1"}`, string(emitted[0].Data))
+	})
+}
@@ -0,0 +1,70 @@
+package user
+
+import (
+	"context"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Jacobo0312/go-web/internal/user")
+
+type tracedUserService struct {
+	next UserService
+}
+
+// NewTracedUserService return a UserService that records a span around each
+// call to next. Emails and names are not recorded.
+func NewTracedUserService(next UserService) UserService {
+	return &tracedUserService{next: next}
+}
+
+func (s *tracedUserService) CreateUser(ctx context.Context, userRequest *domain.CreateUserRequest) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.CreateUser")
+	defer func() {
+		if user != nil {
+			span.SetAttributes(attribute.String("user.id", user.ID))
+		}
+		tracing.End(span, err)
+	}()
+
+	return s.next.CreateUser(ctx, userRequest)
+}
+
+func (s *tracedUserService) GetUsers(ctx context.Context) (users []domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUsers")
+	defer func() {
+		span.SetAttributes(attribute.Int("user.count", len(users)))
+		tracing.End(span, err)
+	}()
+
+	return s.next.GetUsers(ctx)
+}
+
+func (s *tracedUserService) GetUserByID(ctx context.Context, id string) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByID", traceUserID(id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.GetUserByID(ctx, id)
+}
+
+func (s *tracedUserService) UpdateUser(ctx context.Context, id string, userRequest *domain.UpdateUserRequest) (user *domain.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserService.UpdateUser", traceUserID(id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.UpdateUser(ctx, id, userRequest)
+}
+
+func (s *tracedUserService) DeleteUser(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUser", traceUserID(id))
+	defer func() { tracing.End(span, err) }()
+
+	return s.next.DeleteUser(ctx, id)
+}
+
+func traceUserID(id string) trace.SpanStartOption {
+	return trace.WithAttributes(attribute.String("user.id", id))
+}
@@ -0,0 +1,377 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/pkg/database"
+)
+
+// DueDelivery is a delivery ready to be sent, with the target of its webhook
+type DueDelivery struct {
+	ID        int64
+	WebhookID string
+	URL       string
+	Secret    string
+	EventType string
+	Payload   []byte
+	Attempts  int
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, w *domain.Webhook) error
+	GetAll(ctx context.Context) ([]domain.Webhook, error)
+	GetByID(ctx context.Context, id string) (*domain.Webhook, error)
+	Update(ctx context.Context, w *domain.Webhook) error
+	// Delete reports whether the webhook existed
+	Delete(ctx context.Context, id string) (bool, error)
+	GetDeliveries(ctx context.Context, webhookID, status string, limit int) ([]domain.WebhookDelivery, error)
+	// Replay reports whether the delivery was found and queued again
+	Replay(ctx context.Context, webhookID string, deliveryID int64, at time.Time) (bool, error)
+	// RelayEvents turns up to limit outbox events into deliveries for the
+	// subscribed webhooks and returns how many events were relayed
+	RelayEvents(ctx context.Context, now time.Time, limit int) (int, error)
+	// ClaimDue returns up to limit pending deliveries due at now and hides
+	// them from other dispatchers for lease
+	ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]DueDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, attempts, statusCode int, at time.Time) error
+	MarkRetry(ctx context.Context, id int64, attempts int, statusCode *int, next time.Time, lastError string) error
+	MarkDead(ctx context.Context, id int64, attempts int, statusCode *int, lastError string) error
+}
+
+type webhookRepository struct {
+	DB       *sql.DB
+	timeouts database.Timeouts
+}
+
+func NewWebhookRepository(db *sql.DB, timeouts database.Timeouts) WebhookRepository {
+	return &webhookRepository{DB: db, timeouts: timeouts}
+}
+
+func (r *webhookRepository) Create(ctx context.Context, w *domain.Webhook) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	subscribed, err := json.Marshal(w.Events)
+	if err != nil {
+		return database.Translate(err)
+	}
+
+	query := "INSERT INTO webhooks (id, url, events, secret, active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	_, err = r.DB.ExecContext(ctx, query, w.ID, w.URL, subscribed, w.Secret, w.Active, w.CreatedAt, w.UpdatedAt)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) GetAll(ctx context.Context) ([]domain.Webhook, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, url, events, secret, active, created_at, updated_at FROM webhooks ORDER BY created_at"
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	webhooks := []domain.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, database.Translate(err)
+		}
+		webhooks = append(webhooks, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, database.Translate(err)
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, url, events, secret, active, created_at, updated_at FROM webhooks WHERE id = ?"
+	row := r.DB.QueryRowContext(ctx, query, id)
+
+	return scanWebhook(row)
+}
+
+func (r *webhookRepository) Update(ctx context.Context, w *domain.Webhook) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	subscribed, err := json.Marshal(w.Events)
+	if err != nil {
+		return database.Translate(err)
+	}
+
+	query := "UPDATE webhooks SET url = ?, events = ?, secret = ?, active = ?, updated_at = ? WHERE id = ?"
+	result, err := r.DB.ExecContext(ctx, query, w.URL, subscribed, w.Secret, w.Active, w.UpdatedAt, w.ID)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return database.RequireRows(result)
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id string) (bool, error) {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	result, err := r.DB.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return false, database.Translate(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, database.Translate(err)
+	}
+	return rows == 1, nil
+}
+
+func (r *webhookRepository) GetDeliveries(ctx context.Context, webhookID, status string, limit int) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := r.timeouts.ForRead(ctx)
+	defer cancel()
+
+	query := "SELECT id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, delivered_at FROM webhook_deliveries WHERE webhook_id = ?"
+	args := []interface{}{webhookID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer rows.Close()
+
+	deliveries := []domain.WebhookDelivery{}
+	for rows.Next() {
+		var d domain.WebhookDelivery
+		var payload []byte
+		var statusCode sql.NullInt64
+		var lastError sql.NullString
+		var deliveredAt sql.NullTime
+		err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts, &d.NextAttemptAt, &statusCode, &lastError, &d.CreatedAt, &deliveredAt)
+		if err != nil {
+			return nil, database.Translate(err)
+		}
+
+		d.Payload = payload
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			d.LastStatusCode = &code
+		}
+		if lastError.Valid {
+			d.LastError = &lastError.String
+		}
+		if deliveredAt.Valid {
+			d.DeliveredAt = &deliveredAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, database.Translate(err)
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) Replay(ctx context.Context, webhookID string, deliveryID int64, at time.Time) (bool, error) {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = ?, last_status_code = NULL, last_error = NULL, delivered_at = NULL WHERE id = ? AND webhook_id = ?"
+	result, err := r.DB.ExecContext(ctx, query, at, deliveryID, webhookID)
+	if err != nil {
+		return false, database.Translate(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, database.Translate(err)
+	}
+	return rows == 1, nil
+}
+
+func (r *webhookRepository) RelayEvents(ctx context.Context, now time.Time, limit int) (int, error) {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, database.Translate(err)
+	}
+	defer tx.Rollback()
+
+	query := "SELECT id, event_type, payload, created_at FROM events_outbox WHERE dispatched_at IS NULL ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED"
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return 0, database.Translate(err)
+	}
+
+	var pending []events.Event
+	for rows.Next() {
+		var e events.Event
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.Type, &payload, &e.CreatedAt); err != nil {
+			rows.Close()
+			return 0, database.Translate(err)
+		}
+		e.Data = payload
+		pending = append(pending, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, database.Translate(err)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT id, url, events, secret, active, created_at, updated_at FROM webhooks WHERE active = TRUE")
+	if err != nil {
+		return 0, database.Translate(err)
+	}
+
+	var webhooks []domain.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			rows.Close()
+			return 0, database.Translate(err)
+		}
+		webhooks = append(webhooks, *w)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, database.Translate(err)
+	}
+
+	for _, e := range pending {
+		// The envelope is stored with the delivery so replays send the same body
+		envelope, err := json.Marshal(e)
+		if err != nil {
+			return 0, database.Translate(err)
+		}
+
+		for _, w := range webhooks {
+			if !w.Subscribes(e.Type) {
+				continue
+			}
+			query := "INSERT IGNORE INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, next_attempt_at) VALUES (?, ?, ?, ?, 'pending', ?)"
+			if _, err := tx.ExecContext(ctx, query, w.ID, e.ID, e.Type, envelope, now); err != nil {
+				return 0, database.Translate(err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, "UPDATE events_outbox SET dispatched_at = ? WHERE id = ?", now, e.ID); err != nil {
+			return 0, database.Translate(err)
+		}
+	}
+
+	return len(pending), tx.Commit()
+}
+
+func (r *webhookRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]DueDelivery, error) {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+	defer tx.Rollback()
+
+	query := "SELECT d.id, d.webhook_id, w.url, w.secret, d.event_type, d.payload, d.attempts FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE d.status = 'pending' AND d.next_attempt_at <= ? AND w.active = TRUE ORDER BY d.next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED"
+	rows, err := tx.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	var deliveries []DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.URL, &d.Secret, &d.EventType, &d.Payload, &d.Attempts); err != nil {
+			rows.Close()
+			return nil, database.Translate(err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, database.Translate(err)
+	}
+
+	for _, d := range deliveries {
+		if _, err := tx.ExecContext(ctx, "UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id = ?", now.Add(lease), d.ID); err != nil {
+			return nil, database.Translate(err)
+		}
+	}
+
+	return deliveries, tx.Commit()
+}
+
+func (r *webhookRepository) MarkDelivered(ctx context.Context, id int64, attempts, statusCode int, at time.Time) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE webhook_deliveries SET status = 'delivered', attempts = ?, last_status_code = ?, last_error = NULL, delivered_at = ? WHERE id = ?"
+	_, err := r.DB.ExecContext(ctx, query, attempts, statusCode, at, id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) MarkRetry(ctx context.Context, id int64, attempts int, statusCode *int, next time.Time, lastError string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE webhook_deliveries SET attempts = ?, last_status_code = ?, next_attempt_at = ?, last_error = ? WHERE id = ?"
+	_, err := r.DB.ExecContext(ctx, query, attempts, statusCode, next, lastError, id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+func (r *webhookRepository) MarkDead(ctx context.Context, id int64, attempts int, statusCode *int, lastError string) error {
+	ctx, cancel := r.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	query := "UPDATE webhook_deliveries SET status = 'dead', attempts = ?, last_status_code = ?, last_error = ? WHERE id = ?"
+	_, err := r.DB.ExecContext(ctx, query, attempts, statusCode, lastError, id)
+	if err != nil {
+		return database.Translate(err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row scanner) (*domain.Webhook, error) {
+	var w domain.Webhook
+	var subscribed []byte
+	err := row.Scan(&w.ID, &w.URL, &subscribed, &w.Secret, &w.Active, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, database.Translate(err)
+	}
+
+	if err := json.Unmarshal(subscribed, &w.Events); err != nil {
+		return nil, database.Translate(err)
+	}
+	return &w, nil
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepositoryCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewWebhookRepository(db, database.Timeouts{})
+	now := time.Now()
+	w := &domain.Webhook{ID: "wh-1", URL: "https://partner.example.com/hooks", Events: []string{events.ProductCreated}, Secret: "s3cret", Active: true, CreatedAt: now, UpdatedAt: now}
+
+	mock.ExpectExec("INSERT INTO webhooks").
+		WithArgs(w.ID, w.URL, []byte(`["product.created"]`), w.Secret, true, now, now).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, repo.Create(context.Background(), w))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryRelayEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewWebhookRepository(db, database.Timeouts{})
+	now := time.Now()
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	eventRows := sqlmock.NewRows([]string{"id", "event_type", "payload", "created_at"}).
+		AddRow(7, events.ProductUpdated, []byte(`{"id":1}`), created)
+	webhookRows := sqlmock.NewRows([]string{"id", "url", "events", "secret", "active", "created_at", "updated_at"}).
+		AddRow("wh-products", "https://a.example.com", []byte(`["product.updated"]`), "a", true, now, now).
+		AddRow("wh-users", "https://b.example.com", []byte(`["user.created"]`), "b", true, now, now).
+		AddRow("wh-all", "https://c.example.com", []byte(`["*"]`), "c", true, now, now)
+
+	envelope, _ := json.Marshal(events.Event{ID: 7, Type: events.ProductUpdated, Data: json.RawMessage(`{"id":1}`), CreatedAt: created})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT (.+) FROM events_outbox WHERE dispatched_at IS NULL (.+) FOR UPDATE SKIP LOCKED").
+		WithArgs(10).WillReturnRows(eventRows)
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE active = TRUE").WillReturnRows(webhookRows)
+	mock.ExpectExec("INSERT IGNORE INTO webhook_deliveries").
+		WithArgs("wh-products", int64(7), events.ProductUpdated, envelope, now).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT IGNORE INTO webhook_deliveries").
+		WithArgs("wh-all", int64(7), events.ProductUpdated, envelope, now).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("UPDATE events_outbox SET dispatched_at = \\? WHERE id = \\?").
+		WithArgs(now, int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	relayed, err := repo.RelayEvents(context.Background(), now, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, relayed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepositoryReplay(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := NewWebhookRepository(db, database.Timeouts{})
+	now := time.Now()
+
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = 'pending', attempts = 0").
+		WithArgs(now, int64(3), "wh-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE webhook_deliveries SET status = 'pending', attempts = 0").
+		WithArgs(now, int64(4), "wh-1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	replayed, err := repo.Replay(context.Background(), "wh-1", 3, now)
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+
+	replayed, err = repo.Replay(context.Background(), "wh-1", 4, now)
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,474 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderWebhookID = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// deliveriesLimit caps the deliveries returned by GetDeliveries
+const deliveriesLimit = 100
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrInvalidURL       = errors.New("webhook url must be an absolute http or https url of a public host")
+	ErrInvalidEvents    = errors.New("webhook events must be known event types or *")
+	ErrInvalidStatus    = errors.New("unknown delivery status")
+
+	// ErrPrivateAddress is returned when delivering to a host that resolves to
+	// a loopback, private or link-local address, e.g. a cloud metadata
+	// endpoint
+	ErrPrivateAddress = errors.New("webhook host resolves to a non-public address")
+)
+
+// WebhookService interface
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, request *domain.CreateWebhookRequest) (*domain.CreatedWebhook, error)
+	GetWebhooks(ctx context.Context) ([]domain.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*domain.Webhook, error)
+	UpdateWebhook(ctx context.Context, id string, request *domain.UpdateWebhookRequest) (*domain.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	GetDeliveries(ctx context.Context, webhookID, status string) ([]domain.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, webhookID string, deliveryID int64) error
+}
+
+type webhookService struct {
+	repo WebhookRepository
+}
+
+// NewWebhookService return a new WebhookService
+func NewWebhookService(repo WebhookRepository) WebhookService {
+	return &webhookService{repo: repo}
+}
+
+// CreateWebhook subscribe a URL to events. The secret is only returned here.
+func (s *webhookService) CreateWebhook(ctx context.Context, request *domain.CreateWebhookRequest) (*domain.CreatedWebhook, error) {
+	if err := validateURL(request.URL); err != nil {
+		return nil, err
+	}
+	if err := validateEvents(request.Events); err != nil {
+		return nil, err
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	secret := request.Secret
+	if secret == "" {
+		if secret, err = randomHex(32); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now().UTC()
+	w := domain.Webhook{
+		ID:        id,
+		URL:       request.URL,
+		Events:    request.Events,
+		Secret:    secret,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.repo.Create(ctx, &w); err != nil {
+		logging.FromContext(ctx).Error("Error creating webhook", "error", err)
+		return nil, err
+	}
+
+	return &domain.CreatedWebhook{Webhook: w, Secret: secret}, nil
+}
+
+// GetWebhooks return all webhooks
+func (s *webhookService) GetWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	return s.repo.GetAll(ctx)
+}
+
+// GetWebhook return a webhook by id
+func (s *webhookService) GetWebhook(ctx context.Context, id string) (*domain.Webhook, error) {
+	w, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	return w, err
+}
+
+// UpdateWebhook change the url, events, secret or active flag of a webhook
+func (s *webhookService) UpdateWebhook(ctx context.Context, id string, request *domain.UpdateWebhookRequest) (*domain.Webhook, error) {
+	w, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.URL != nil {
+		if err := validateURL(*request.URL); err != nil {
+			return nil, err
+		}
+		w.URL = *request.URL
+	}
+	if request.Events != nil {
+		if err := validateEvents(request.Events); err != nil {
+			return nil, err
+		}
+		w.Events = request.Events
+	}
+	if request.Secret != nil && *request.Secret != "" {
+		w.Secret = *request.Secret
+	}
+	if request.Active != nil {
+		w.Active = *request.Active
+	}
+	w.UpdatedAt = time.Now().UTC()
+
+	if err := s.repo.Update(ctx, w); err != nil {
+		logging.FromContext(ctx).Error("Error updating webhook", "error", err)
+		return nil, err
+	}
+	return w, nil
+}
+
+// DeleteWebhook delete a webhook and its deliveries
+func (s *webhookService) DeleteWebhook(ctx context.Context, id string) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// GetDeliveries return the latest deliveries of a webhook, optionally
+// filtered by status
+func (s *webhookService) GetDeliveries(ctx context.Context, webhookID, status string) ([]domain.WebhookDelivery, error) {
+	switch status {
+	case "", domain.DeliveryPending, domain.DeliveryDelivered, domain.DeliveryDead:
+	default:
+		return nil, ErrInvalidStatus
+	}
+
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeliveries(ctx, webhookID, status, deliveriesLimit)
+}
+
+// ReplayDelivery queue a delivery again with a fresh attempt count
+func (s *webhookService) ReplayDelivery(ctx context.Context, webhookID string, deliveryID int64) error {
+	replayed, err := s.repo.Replay(ctx, webhookID, deliveryID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !replayed {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// Sign returns the signature header value for a delivery. Receivers compute
+// the same HMAC-SHA256 over "<timestamp>.<body>" with their secret and
+// compare it in constant time.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DispatcherOptions configures the relay, delivery and retries
+type DispatcherOptions struct {
+	Interval    time.Duration
+	BatchSize   int
+	Concurrency int
+	Timeout     time.Duration
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// AllowPrivateNetworks lets deliveries reach loopback and private
+	// addresses, for tests and local setups
+	AllowPrivateNetworks bool
+}
+
+// Dispatcher relays the events outbox into deliveries and sends them,
+// retrying failures with exponential backoff until MaxAttempts, after which
+// the delivery is dead
+type Dispatcher struct {
+	repo    WebhookRepository
+	client  *http.Client
+	options DispatcherOptions
+}
+
+// NewDispatcher return a new Dispatcher. Redirects are not followed, so a
+// receiver cannot bounce deliveries to another host.
+func NewDispatcher(repo WebhookRepository, options DispatcherOptions) *Dispatcher {
+	if options.Concurrency < 1 {
+		options.Concurrency = 1
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !options.AllowPrivateNetworks {
+		// Checked on the resolved address, so DNS cannot point a public name
+		// to a private address after the webhook was validated
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !isPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would dial the receiver itself, skipping the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	client := &http.Client{
+		Timeout:   options.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &Dispatcher{repo: repo, client: client, options: options}
+}
+
+// Run relays and delivers every interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.repo.RelayEvents(ctx, time.Now().UTC(), d.options.BatchSize); err != nil {
+			slog.Error("Error relaying events outbox", "error", err)
+		}
+		if _, err := d.DispatchDue(ctx); err != nil {
+			slog.Error("Error dispatching webhooks", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends one batch of due deliveries and returns how many were
+// delivered
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// The lease outlives the slowest possible batch, so no other instance
+	// sends the same delivery twice
+	rounds := (d.options.BatchSize + d.options.Concurrency - 1) / d.options.Concurrency
+	lease := time.Duration(rounds)*d.options.Timeout + time.Minute
+	deliveries, err := d.repo.ClaimDue(ctx, time.Now().UTC(), d.options.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		mu        sync.Mutex
+		delivered int
+		firstErr  error
+		wg        sync.WaitGroup
+	)
+	sem := make(chan struct{}, d.options.Concurrency)
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(delivery DueDelivery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// A delivery that has started is finished even when ctx is done,
+			// bounded by the client timeout
+			ok, err := d.deliver(context.WithoutCancel(ctx), delivery)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				delivered++
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(delivery)
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return delivered, firstErr
+}
+
+// deliver sends a delivery and records the outcome. It reports whether the
+// receiver accepted it; the error is only set when the outcome could not be
+// stored.
+func (d *Dispatcher) deliver(ctx context.Context, delivery DueDelivery) (bool, error) {
+	attempts := delivery.Attempts + 1
+	statusCode, err := d.send(ctx, delivery)
+
+	// The outcome is recorded even when ctx is canceled mid-send
+	ctx = context.WithoutCancel(ctx)
+	if err == nil {
+		return true, d.repo.MarkDelivered(ctx, delivery.ID, attempts, *statusCode, time.Now().UTC())
+	}
+
+	slog.Warn("Error delivering webhook", "event_type", delivery.EventType, "webhook_id", delivery.WebhookID, "delivery_id", delivery.ID, "attempt", attempts, "error", err)
+	if attempts >= d.options.MaxAttempts {
+		return false, d.repo.MarkDead(ctx, delivery.ID, attempts, statusCode, err.Error())
+	}
+	return false, d.repo.MarkRetry(ctx, delivery.ID, attempts, statusCode, time.Now().UTC().Add(d.backoff(attempts)), err.Error())
+}
+
+// send posts the payload and returns the response status code, if any
+func (d *Dispatcher) send(ctx context.Context, delivery DueDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-web-webhooks/1.0")
+	req.Header.Set(HeaderWebhookID, delivery.WebhookID)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	statusCode := resp.StatusCode
+	if statusCode < 200 || statusCode > 299 {
+		return &statusCode, fmt.Errorf("receiver responded %s", resp.Status)
+	}
+	return &statusCode, nil
+}
+
+// backoff doubles the wait after every failed attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.options.BaseBackoff
+	for i := 1; i < attempts && wait < d.options.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.options.MaxBackoff {
+		wait = d.options.MaxBackoff
+	}
+	return wait
+}
+
+// validateURL rejects urls that are not http or https and hosts that are
+// obviously not public. Names are only resolved when delivering, by the
+// dispatcher.
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrInvalidURL
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublicAddr(addr) {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// nonPublicPrefixes are the reserved ranges that net/netip does not flag
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// isPublicAddr reports whether addr is a global unicast address outside the
+// private and reserved ranges
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateEvents(subscribed []string) error {
+	if len(subscribed) == 0 {
+		return ErrInvalidEvents
+	}
+	for _, e := range subscribed {
+		if e == domain.WebhookEventsAll {
+			continue
+		}
+		known := false
+		for _, t := range events.Types {
+			if e == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: %q", ErrInvalidEvents, e)
+		}
+	}
+	return nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Jacobo0312/go-web/internal/domain"
+	"github.com/Jacobo0312/go-web/internal/events"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryWebhookRepository keeps webhooks and deliveries in memory
+type memoryWebhookRepository struct {
+	WebhookRepository
+	mu         sync.Mutex
+	webhooks   map[string]*domain.Webhook
+	deliveries []*memoryDelivery
+}
+
+type memoryDelivery struct {
+	DueDelivery
+	status     string
+	nextAt     time.Time
+	statusCode *int
+	lastError  string
+}
+
+func newMemoryWebhookRepository() *memoryWebhookRepository {
+	return &memoryWebhookRepository{webhooks: map[string]*domain.Webhook{}}
+}
+
+func (r *memoryWebhookRepository) Create(ctx context.Context, w *domain.Webhook) error {
+	stored := *w
+	r.webhooks[w.ID] = &stored
+	return nil
+}
+
+func (r *memoryWebhookRepository) GetByID(ctx context.Context, id string) (*domain.Webhook, error) {
+	w, ok := r.webhooks[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	found := *w
+	return &found, nil
+}
+
+func (r *memoryWebhookRepository) Update(ctx context.Context, w *domain.Webhook) error {
+	stored := *w
+	r.webhooks[w.ID] = &stored
+	return nil
+}
+
+func (r *memoryWebhookRepository) queue(w *domain.Webhook, eventType, payload string) {
+	r.deliveries = append(r.deliveries, &memoryDelivery{
+		DueDelivery: DueDelivery{ID: int64(len(r.deliveries) + 1), WebhookID: w.ID, URL: w.URL, Secret: w.Secret, EventType: eventType, Payload: []byte(payload)},
+		status:      domain.DeliveryPending,
+	})
+}
+
+func (r *memoryWebhookRepository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]DueDelivery, error) {
+	var due []DueDelivery
+	for _, d := range r.deliveries {
+		if d.status == domain.DeliveryPending && !d.nextAt.After(now) && len(due) < limit {
+			d.nextAt = now.Add(lease)
+			due = append(due, d.DueDelivery)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryWebhookRepository) MarkDelivered(ctx context.Context, id int64, attempts, statusCode int, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.deliveries[id-1]
+	d.status, d.Attempts, d.statusCode = domain.DeliveryDelivered, attempts, &statusCode
+	return nil
+}
+
+func (r *memoryWebhookRepository) MarkRetry(ctx context.Context, id int64, attempts int, statusCode *int, next time.Time, lastError string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.deliveries[id-1]
+	d.Attempts, d.statusCode, d.nextAt, d.lastError = attempts, statusCode, next, lastError
+	return nil
+}
+
+func (r *memoryWebhookRepository) MarkDead(ctx context.Context, id int64, attempts int, statusCode *int, lastError string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d := r.deliveries[id-1]
+	d.status, d.Attempts, d.statusCode, d.lastError = domain.DeliveryDead, attempts, statusCode, lastError
+	return nil
+}
+
+func TestServiceCreateWebhook(t *testing.T) {
+	service := NewWebhookService(newMemoryWebhookRepository())
+
+	t.Run("generates a secret", func(t *testing.T) {
+		created, err := service.CreateWebhook(context.Background(), &domain.CreateWebhookRequest{URL: "https://partner.example.com/hooks", Events: []string{events.ProductCreated, events.UserDeleted}})
+
+		assert.NoError(t, err)
+		assert.True(t, created.Active)
+		assert.Len(t, created.Secret, 64)
+		assert.Equal(t, created.Secret, created.Webhook.Secret)
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		for _, url := range []string{"ftp://partner.example.com", "http://localhost:8080/hooks", "http://127.0.0.1/hooks", "http://169.254.169.254/latest/meta-data", "http://10.0.0.5/hooks", "http://[::1]/hooks", "http://[::ffff:192.168.1.1]/hooks"} {
+			_, err := service.CreateWebhook(context.Background(), &domain.CreateWebhookRequest{URL: url, Events: []string{"*"}})
+			assert.ErrorIs(t, err, ErrInvalidURL, url)
+		}
+	})
+
+	t.Run("unknown event", func(t *testing.T) {
+		_, err := service.CreateWebhook(context.Background(), &domain.CreateWebhookRequest{URL: "https://partner.example.com", Events: []string{"order.created"}})
+		assert.ErrorIs(t, err, ErrInvalidEvents)
+	})
+}
+
+func TestServiceUpdateWebhookNotFound(t *testing.T) {
+	service := NewWebhookService(newMemoryWebhookRepository())
+
+	active := false
+	_, err := service.UpdateWebhook(context.Background(), "missing", &domain.UpdateWebhookRequest{Active: &active})
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+}
+
+func TestSign(t *testing.T) {
+	// Reference value computed with: printf '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163", Sign("secret", 1700000000, []byte("{}")))
+}
+
+func TestDispatcherDispatchDue(t *testing.T) {
+	var received []*http.Request
+	var bodies [][]byte
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		mu.Unlock()
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := newMemoryWebhookRepository()
+	ok := &domain.Webhook{ID: "wh-ok", URL: server.URL + "/ok", Secret: "ok-secret"}
+	failing := &domain.Webhook{ID: "wh-fail", URL: server.URL + "/fail", Secret: "fail-secret"}
+	repo.queue(ok, events.ProductCreated, `{"id":1,"type":"product.created"}`)
+	repo.queue(failing, events.ProductCreated, `{"id":1,"type":"product.created"}`)
+
+	dispatcher := NewDispatcher(repo, DispatcherOptions{BatchSize: 10, Concurrency: 2, Timeout: time.Second, MaxAttempts: 2, BaseBackoff: time.Minute, MaxBackoff: time.Hour, AllowPrivateNetworks: true})
+
+	delivered, err := dispatcher.DispatchDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	assert.Len(t, received, 2)
+	for i, r := range received {
+		timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		assert.NoError(t, err)
+		secret := "ok-secret"
+		if r.Header.Get(HeaderWebhookID) == "wh-fail" {
+			secret = "fail-secret"
+		}
+		assert.Equal(t, Sign(secret, timestamp, bodies[i]), r.Header.Get(HeaderSignature))
+		assert.Equal(t, events.ProductCreated, r.Header.Get(HeaderEvent))
+	}
+
+	assert.Equal(t, domain.DeliveryDelivered, repo.deliveries[0].status)
+	assert.Equal(t, domain.DeliveryPending, repo.deliveries[1].status)
+	assert.Equal(t, 1, repo.deliveries[1].Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, *repo.deliveries[1].statusCode)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), repo.deliveries[1].nextAt, 5*time.Second)
+
+	// The last attempt moves the delivery to the dead letter state
+	repo.deliveries[1].nextAt = time.Time{}
+	delivered, err = dispatcher.DispatchDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, domain.DeliveryDead, repo.deliveries[1].status)
+	assert.Equal(t, 2, repo.deliveries[1].Attempts)
+}
+
+func TestDispatcherRefusesPrivateAddresses(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	repo := newMemoryWebhookRepository()
+	repo.queue(&domain.Webhook{ID: "wh-local", URL: server.URL, Secret: "secret"}, events.ProductCreated, `{}`)
+
+	dispatcher := NewDispatcher(repo, DispatcherOptions{BatchSize: 10, Concurrency: 1, Timeout: time.Second, MaxAttempts: 2, BaseBackoff: time.Minute, MaxBackoff: time.Hour})
+
+	delivered, err := dispatcher.DispatchDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Equal(t, 0, hits)
+	assert.Contains(t, repo.deliveries[0].lastError, ErrPrivateAddress.Error())
+}
+
+func TestDispatcherDoesNotFollowRedirects(t *testing.T) {
+	var followed bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			http.Redirect(w, r, "/target", http.StatusTemporaryRedirect)
+			return
+		}
+		followed = true
+	}))
+	defer server.Close()
+
+	repo := newMemoryWebhookRepository()
+	repo.queue(&domain.Webhook{ID: "wh-moved", URL: server.URL + "/moved", Secret: "secret"}, events.ProductCreated, `{}`)
+
+	dispatcher := NewDispatcher(repo, DispatcherOptions{BatchSize: 10, Concurrency: 1, Timeout: time.Second, MaxAttempts: 2, BaseBackoff: time.Minute, MaxBackoff: time.Hour, AllowPrivateNetworks: true})
+
+	delivered, err := dispatcher.DispatchDue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.False(t, followed)
+	assert.Equal(t, http.StatusTemporaryRedirect, *repo.deliveries[0].statusCode)
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":        true,
+		"2606:2800:220:1::":    true,
+		"127.0.0.1":            false,
+		"10.1.2.3":             false,
+		"172.16.0.1":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false,
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"::1":                  false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:93.184.216.34": true,
+	} {
+		assert.Equal(t, public, isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	dispatcher := NewDispatcher(nil, DispatcherOptions{BaseBackoff: 30 * time.Second, MaxBackoff: 10 * time.Minute})
+
+	assert.Equal(t, 30*time.Second, dispatcher.backoff(1))
+	assert.Equal(t, time.Minute, dispatcher.backoff(2))
+	assert.Equal(t, 8*time.Minute, dispatcher.backoff(5))
+	assert.Equal(t, 10*time.Minute, dispatcher.backoff(6))
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// DBTX is implemented by both *sql.DB and *sql.Tx, so repositories can run
+// inside or outside a transaction
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Transactor runs functions inside a database transaction
+type Transactor interface {
+	// WithinTx commits when fn returns nil and rolls back otherwise
+	WithinTx(ctx context.Context, fn func(tx DBTX) error) error
+}
+
+type transactor struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+// NewTransactor return a new Transactor. The transactions it hands out are
+// traced like the ones of Trace, and rolled back when they outlive the write
+// timeout.
+func NewTransactor(db *sql.DB, timeouts Timeouts) Transactor {
+	return &transactor{db: db, timeouts: timeouts}
+}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(tx DBTX) error) (err error) {
+	ctx, cancel := t.timeouts.ForWrite(ctx)
+	defer cancel()
+
+	ctx, span := tracer.Start(ctx, "transaction", spanOptions("BEGIN")...)
+	defer func() { endSpan(span, err) }()
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(Trace(tx)); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers translated by Translate
+const (
+	mysqlDuplicateEntry  = 1062
+	mysqlNoReferencedRow = 1452
+)
+
+// Translate wraps err with the sentinel error of pkg/errors that describes
+// it: ErrNotFound for sql.ErrNoRows, ErrConflict for a duplicate key,
+// ErrForeignKey for a missing referenced row and ErrUnavailable for a lost
+// connection. err stays in the chain, so errors.Is(err, sql.ErrNoRows) still
+// holds. Other errors, and errors already translated, are returned as is.
+func Translate(err error) error {
+	if err == nil || isTranslated(err) {
+		return err
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %w", appErrors.ErrNotFound, err)
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlDuplicateEntry:
+			return fmt.Errorf("%w: %w", appErrors.ErrConflict, err)
+		case mysqlNoReferencedRow:
+			return fmt.Errorf("%w: %w", appErrors.ErrForeignKey, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", appErrors.ErrUnavailable, err)
+	}
+
+	return err
+}
+
+// RequireRows returns ErrNotFound when a statement changed no rows, e.g. an
+// update of a missing id
+func RequireRows(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("%w: no rows affected", appErrors.ErrNotFound)
+	}
+	return nil
+}
+
+func isTranslated(err error) bool {
+	for _, sentinel := range []error{appErrors.ErrNotFound, appErrors.ErrConflict, appErrors.ErrForeignKey, appErrors.ErrUnavailable} {
+		if errors.Is(err, sentinel) {
+			return true
+		}
+	}
+	return false
+}
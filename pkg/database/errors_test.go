@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+	}{
+		{"no rows", sql.ErrNoRows, appErrors.ErrNotFound},
+		{"duplicate entry", &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, appErrors.ErrConflict},
+		{"missing referenced row", &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"}, appErrors.ErrForeignKey},
+		{"bad connection", fmt.Errorf("query: %w", driver.ErrBadConn), appErrors.ErrUnavailable},
+		{"connection done", sql.ErrConnDone, appErrors.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Translate(tt.err)
+			assert.ErrorIs(t, err, tt.sentinel)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("other errors are returned as is", func(t *testing.T) {
+		syntax := &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax"}
+		assert.Same(t, syntax, Translate(syntax))
+		assert.Nil(t, Translate(nil))
+	})
+
+	t.Run("translated errors are returned as is", func(t *testing.T) {
+		err := fmt.Errorf("loading product: %w", Translate(sql.ErrNoRows))
+		assert.Same(t, err, Translate(err))
+	})
+}
+
+func TestRequireRows(t *testing.T) {
+	assert.NoError(t, RequireRows(sqlmock.NewResult(0, 1)))
+	assert.ErrorIs(t, RequireRows(sqlmock.NewResult(0, 0)), appErrors.ErrNotFound)
+
+	failure := errors.New("rows affected not supported")
+	assert.ErrorIs(t, RequireRows(sqlmock.NewErrorResult(failure)), failure)
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouts bounds each database operation. A zero duration means no
+// deadline besides the one of the caller.
+type Timeouts struct {
+	// Read bounds queries that only read
+	Read time.Duration
+	// Write bounds statements and transactions that change data
+	Write time.Duration
+}
+
+// ForRead returns ctx bounded by the read timeout
+func (t Timeouts) ForRead(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, t.Read)
+}
+
+// ForWrite returns ctx bounded by the write timeout
+func (t Timeouts) ForWrite(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, t.Write)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/Jacobo0312/go-web/pkg/tracing"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/Jacobo0312/go-web/pkg/database")
+
+type tracedDB struct {
+	db DBTX
+}
+
+// Trace returns a DBTX that records a span for each query. The statement is
+// recorded without its arguments.
+func Trace(db DBTX) DBTX {
+	if traced, ok := db.(tracedDB); ok {
+		return traced
+	}
+	return tracedDB{db: db}
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	ctx, span := startSpan(ctx, query)
+	defer func() { endSpan(span, err) }()
+
+	return t.db.ExecContext(ctx, query, args...)
+}
+
+// QueryContext spans until the first rows are available, not until they
+// are read
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	ctx, span := startSpan(ctx, query)
+	defer func() { endSpan(span, err) }()
+
+	return t.db.QueryContext(ctx, query, args...)
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startSpan(ctx, query)
+	row := t.db.QueryRowContext(ctx, query, args...)
+	endSpan(span, row.Err())
+	return row
+}
+
+func startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := query
+	if i := strings.IndexByte(query, ' '); i > 0 {
+		operation = query[:i]
+	}
+	operation = strings.ToUpper(operation)
+
+	return tracer.Start(ctx, operation, append(spanOptions(operation), trace.WithAttributes(semconv.DBStatement(query)))...)
+}
+
+func spanOptions(operation string) []trace.SpanStartOption {
+	return []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperation(operation)),
+	}
+}
+
+// endSpan ignores sql.ErrNoRows, which callers handle as a normal result
+func endSpan(span trace.Span, err error) {
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTransactorTracesQueries(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE products").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT name FROM products").WillReturnError(sql.ErrNoRows)
+	mock.ExpectCommit()
+
+	err = NewTransactor(db, Timeouts{}).WithinTx(context.Background(), func(tx DBTX) error {
+		if _, err := tx.ExecContext(context.Background(), "UPDATE products SET name = ? WHERE id = ?", "Tea", 1); err != nil {
+			return err
+		}
+		var name string
+		err := tx.QueryRowContext(context.Background(), "SELECT name FROM products WHERE id = ?", 2).Scan(&name)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 3) {
+		assert.Equal(t, "UPDATE", spans[0].Name())
+		assert.Equal(t, "SELECT", spans[1].Name())
+		// No rows is a normal result, not an error
+		assert.Equal(t, codes.Unset, spans[1].Status().Code)
+		assert.Equal(t, "transaction", spans[2].Name())
+	}
+}
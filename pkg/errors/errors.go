@@ -1,36 +1,150 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 )
 
+// StatusClientClosedRequest is the non-standard status logged when the
+// client goes away before the response is written
+const StatusClientClosedRequest = 499
+
+// Codes let clients tell errors apart without parsing the message
+const (
+	CodeBadRequest          = "bad_request"
+	CodeValidationFailed    = "validation_failed"
+	CodeUnauthorized        = "unauthorized"
+	CodeForbidden           = "forbidden"
+	CodeNotFound            = "not_found"
+	CodeConflict            = "conflict"
+	CodePayloadTooLarge     = "payload_too_large"
+	CodeTooManyRequests     = "too_many_requests"
+	CodeClientClosedRequest = "client_closed_request"
+	CodeInternal            = "internal"
+	CodeUnavailable         = "unavailable"
+	CodeTimeout             = "timeout"
+)
+
+// Sentinel errors of the data layer. Repositories wrap their driver errors
+// with them, so services and handlers can tell failures apart without
+// knowing about SQL.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrForeignKey  = errors.New("foreign key violation")
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// Violation is an invalid field of a request
+type Violation struct {
+	// Field is the JSON path of the field, e.g. "price" or "items[0].sku"
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// AppError is an error meant for the client. Err is the internal cause: it
+// is logged but never sent.
 type AppError struct {
-	Code    int
-	Message string
-	Err     error
+	// Status is the HTTP status code
+	Status     int
+	Code       string
+	Message    string
+	Err        error
+	Violations []Violation
 }
 
 func (e *AppError) Error() string {
-	return fmt.Sprintf("Error %d: %s", e.Code, e.Message)
+	if e.Err != nil {
+		return fmt.Sprintf("Error %d: %s: %v", e.Status, e.Message, e.Err)
+	}
+	return fmt.Sprintf("Error %d: %s", e.Status, e.Message)
+}
+
+// Unwrap returns the cause, so errors.Is and errors.As see through AppError
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an AppError with the same code, so
+// errors.Is(err, &AppError{Code: CodeNotFound}) matches any not found error
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// WithCode returns a copy of the error with a more specific code
+func (e *AppError) WithCode(code string) *AppError {
+	c := *e
+	c.Code = code
+	return &c
 }
 
-func New(code int, message string, err error) *AppError {
+// WithViolations returns a copy of the error listing the invalid fields
+func (e *AppError) WithViolations(violations ...Violation) *AppError {
+	c := *e
+	c.Violations = append(append([]Violation(nil), e.Violations...), violations...)
+	return &c
+}
+
+// New returns an error with the default code of the status
+func New(status int, message string, err error) *AppError {
 	return &AppError{
-		Code:    code,
+		Status:  status,
+		Code:    codeForStatus(status),
 		Message: message,
 		Err:     err,
 	}
 }
 
+// From returns err if it is, or wraps, an AppError. Any other error becomes
+// an internal server error.
+func From(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return NewInternalServerError("Internal server error", err)
+}
+
+// Classify maps the sentinel errors to a response. resource names what was
+// looked for in the messages, e.g. "Product". Any other error becomes an
+// internal server error with message.
+func Classify(err error, resource, message string) *AppError {
+	var appErr *AppError
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, ErrNotFound):
+		return NewNotFound(resource+" not found", err)
+	case errors.Is(err, ErrConflict):
+		return NewConflict(resource+" already exists", err)
+	case errors.Is(err, ErrForeignKey):
+		return New(http.StatusUnprocessableEntity, resource+" references a record that does not exist", err)
+	case errors.Is(err, ErrUnavailable):
+		return New(http.StatusServiceUnavailable, "Service unavailable, try again later", err)
+	default:
+		return NewInternalServerError(message, err)
+	}
+}
+
 func NewBadRequest(message string, err error) *AppError {
 	return New(http.StatusBadRequest, message, err)
 }
 
+// NewValidation returns an error listing the invalid fields of a request
+func NewValidation(message string, violations ...Violation) *AppError {
+	return New(http.StatusUnprocessableEntity, message, nil).WithViolations(violations...)
+}
+
 func NewNotFound(message string, err error) *AppError {
 	return New(http.StatusNotFound, message, err)
 }
 
+func NewConflict(message string, err error) *AppError {
+	return New(http.StatusConflict, message, err)
+}
+
 func NewInternalServerError(message string, err error) *AppError {
 	return New(http.StatusInternalServerError, message, err)
 }
@@ -38,3 +152,46 @@ func NewInternalServerError(message string, err error) *AppError {
 func NewUnauthorized(message string) *AppError {
 	return New(http.StatusUnauthorized, message, nil)
 }
+
+func NewForbidden(message string) *AppError {
+	return New(http.StatusForbidden, message, nil)
+}
+
+func NewGatewayTimeout(message string, err error) *AppError {
+	return New(http.StatusGatewayTimeout, message, err)
+}
+
+func NewClientClosedRequest(message string, err error) *AppError {
+	return New(StatusClientClosedRequest, message, err)
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case StatusClientClosedRequest:
+		return CodeClientClosedRequest
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
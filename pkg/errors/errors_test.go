@@ -0,0 +1,68 @@
+package errors
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppErrorWrapping(t *testing.T) {
+	err := fmt.Errorf("loading product: %w", NewNotFound("Product not found", sql.ErrNoRows))
+
+	assert.True(t, errors.Is(err, sql.ErrNoRows))
+	assert.True(t, errors.Is(err, &AppError{Code: CodeNotFound}))
+	assert.False(t, errors.Is(err, &AppError{Code: CodeConflict}))
+
+	var appErr *AppError
+	if assert.True(t, errors.As(err, &appErr)) {
+		assert.Equal(t, http.StatusNotFound, appErr.Status)
+		assert.Equal(t, "Error 404: Product not found: sql: no rows in result set", appErr.Error())
+	}
+}
+
+func TestFrom(t *testing.T) {
+	notFound := NewNotFound("Product not found", nil)
+	assert.Same(t, notFound, From(fmt.Errorf("wrapped: %w", notFound)))
+
+	internal := From(errors.New("boom"))
+	assert.Equal(t, http.StatusInternalServerError, internal.Status)
+	assert.Equal(t, CodeInternal, internal.Code)
+}
+
+func TestWithCodeAndViolations(t *testing.T) {
+	base := NewBadRequest("Invalid request", nil)
+	err := base.WithCode("expired_token").WithViolations(Violation{Field: "token", Message: "has expired"})
+
+	assert.Equal(t, CodeBadRequest, base.Code)
+	assert.Empty(t, base.Violations)
+	assert.Equal(t, "expired_token", err.Code)
+	assert.Equal(t, []Violation{{Field: "token", Message: "has expired"}}, err.Violations)
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		msg    string
+	}{
+		{"not found", fmt.Errorf("%w: %w", ErrNotFound, sql.ErrNoRows), http.StatusNotFound, "Product not found"},
+		{"conflict", fmt.Errorf("%w: duplicate entry", ErrConflict), http.StatusConflict, "Product already exists"},
+		{"foreign key", fmt.Errorf("%w: no referenced row", ErrForeignKey), http.StatusUnprocessableEntity, "Product references a record that does not exist"},
+		{"unavailable", fmt.Errorf("%w: bad connection", ErrUnavailable), http.StatusServiceUnavailable, "Service unavailable, try again later"},
+		{"app error", NewForbidden("Not allowed"), http.StatusForbidden, "Not allowed"},
+		{"other", errors.New("syntax error"), http.StatusInternalServerError, "Error getting product"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appErr := Classify(tt.err, "Product", "Error getting product")
+			assert.Equal(t, tt.status, appErr.Status)
+			assert.Equal(t, tt.msg, appErr.Message)
+		})
+	}
+}
@@ -1,26 +0,0 @@
-package firebase
-
-import (
-	"context"
-	"log"
-
-	firebase "firebase.google.com/go/v4"
-	"firebase.google.com/go/v4/auth"
-	"google.golang.org/api/option"
-)
-
-var FirebaseAuth *auth.Client
-
-func InitFirebase() {
-	log.Println("Initializing Firebase...")
-	opt := option.WithCredentialsFile("./credentials.json")
-	app, err := firebase.NewApp(context.Background(), nil, opt)
-	if err != nil {
-		log.Fatalf("error initializing app: %v\n", err)
-	}
-
-	FirebaseAuth, err = app.Auth(context.Background())
-	if err != nil {
-		log.Fatalf("error getting Auth client: %v\n", err)
-	}
-}
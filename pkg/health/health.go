@@ -0,0 +1,180 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Statuses of a check and of a report
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Checker reports whether a dependency is usable
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs float64   `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	// Cached is true when the result was reused instead of running the check
+	Cached bool `json:"cached"`
+}
+
+// Report is the outcome of every check of a registry
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// OK reports whether every check passed
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// RegistryOptions configures the checks
+type RegistryOptions struct {
+	// Timeout bounds each check
+	Timeout time.Duration
+	// CacheTTL is how long a result is reused, so probes from many load
+	// balancers do not hit the dependencies on every request
+	CacheTTL time.Duration
+}
+
+// Registry runs named checks
+type Registry struct {
+	options RegistryOptions
+
+	mu     sync.Mutex
+	checks []*check
+}
+
+type check struct {
+	name    string
+	checker Checker
+
+	// mu is held while the check runs, so concurrent callers wait for its
+	// result instead of running it again
+	mu      sync.Mutex
+	result  Result
+	expires time.Time
+}
+
+// NewRegistry return a new Registry
+func NewRegistry(options RegistryOptions) *Registry {
+	return &Registry{options: options}
+}
+
+// Register adds a named check
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checks = append(r.checks, &check{name: name, checker: checker})
+}
+
+// Run runs the checks concurrently, reusing the results that are still
+// cached. The report fails when any check fails.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.Lock()
+	checks := append([]*check(nil), r.checks...)
+	r.mu.Unlock()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(checks))}
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = r.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c *check) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(c.expires) {
+		result := c.result
+		result.Cached = true
+		return result
+	}
+
+	// Not tied to the request, so a client going away does not cache a failure
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.options.Timeout)
+	defer cancel()
+
+	err := c.checker.Check(ctx)
+	result := Result{
+		Name:      c.name,
+		Status:    StatusOK,
+		LatencyMs: float64(time.Since(now).Microseconds()) / 1000,
+		CheckedAt: now.UTC(),
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	c.result = result
+	c.expires = now.Add(r.options.CacheTTL)
+	return result
+}
+
+// PingDB checks that the database answers
+func PingDB(db *sql.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	})
+}
+
+// MigrationVersion checks that the schema is at the expected migration
+// version and that no migration was left half applied
+func MigrationVersion(db *sql.DB, expected uint) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		var version uint
+		var dirty bool
+		err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("no migrations applied")
+		}
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		if version != expected {
+			return fmt.Errorf("schema version is %d, expected %d", version, expected)
+		}
+		return nil
+	})
+}
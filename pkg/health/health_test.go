@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryRun(t *testing.T) {
+	registry := NewRegistry(RegistryOptions{Timeout: time.Second})
+	registry.Register("up", CheckerFunc(func(ctx context.Context) error { return nil }))
+	registry.Register("down", CheckerFunc(func(ctx context.Context) error { return errors.New("connection refused") }))
+
+	report := registry.Run(context.Background())
+
+	assert.False(t, report.OK())
+	assert.Equal(t, "up", report.Checks[0].Name)
+	assert.Equal(t, StatusOK, report.Checks[0].Status)
+	assert.Equal(t, StatusFail, report.Checks[1].Status)
+	assert.Equal(t, "connection refused", report.Checks[1].Error)
+}
+
+func TestRegistryTimeout(t *testing.T) {
+	registry := NewRegistry(RegistryOptions{Timeout: 10 * time.Millisecond})
+	registry.Register("slow", CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	report := registry.Run(context.Background())
+
+	assert.False(t, report.OK())
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[0].Error)
+}
+
+func TestRegistryCachesResults(t *testing.T) {
+	var calls atomic.Int32
+	registry := NewRegistry(RegistryOptions{Timeout: time.Second, CacheTTL: time.Minute})
+	registry.Register("db", CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, registry.Run(context.Background()).OK())
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	assert.True(t, registry.Run(context.Background()).Checks[0].Cached)
+}
+
+func TestMigrationVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	query := "SELECT version, dirty FROM schema_migrations LIMIT 1"
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(11, false))
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(10, false))
+	mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(11, true))
+
+	checker := MigrationVersion(db, 11)
+	assert.NoError(t, checker.Check(context.Background()))
+	assert.EqualError(t, checker.Check(context.Background()), "schema version is 10, expected 11")
+	assert.EqualError(t, checker.Check(context.Background()), "migration 11 is dirty")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
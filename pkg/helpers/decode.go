@@ -0,0 +1,44 @@
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/validation"
+)
+
+// MaxBodyBytes bounds the request bodies read by DecodeJSON
+const MaxBodyBytes = 1 << 20
+
+// DecodeJSON decodes the body of r into dst, a pointer to a struct, and
+// validates it against its validate tags. The body must be a single JSON
+// value of at most MaxBodyBytes without unknown fields. Violations are
+// written in the language of the Accept-Language header.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst any) *appErrors.AppError {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return InvalidPayload(err)
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return appErrors.NewBadRequest("Request body must contain a single JSON value", err)
+	}
+
+	if violations := validation.Struct(dst, preferredLocale(r)); len(violations) > 0 {
+		return appErrors.NewValidation("Invalid request payload", violations...)
+	}
+	return nil
+}
+
+// preferredLocale returns the first language of the Accept-Language header.
+// Quality values are ignored: clients list their preferred language first.
+func preferredLocale(r *http.Request) string {
+	first, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+	locale, _, _ := strings.Cut(first, ";")
+	return strings.TrimSpace(locale)
+}
@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type decodeRequest struct {
+	Name  string  `json:"name" validate:"required"`
+	Price float64 `json:"price" validate:"min=0"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		acceptLanguage string
+		expectedStatus int
+		violations     []appErrors.Violation
+	}{
+		{
+			name: "valid body",
+			body: `{"name":"Keyboard","price":10}`,
+		},
+		{
+			name:           "empty body",
+			body:           ``,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "malformed body",
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown field",
+			body:           `{"name":"Keyboard","colour":"black"}`,
+			expectedStatus: http.StatusBadRequest,
+			violations:     []appErrors.Violation{{Field: "colour", Message: "is not allowed"}},
+		},
+		{
+			name:           "trailing data",
+			body:           `{"name":"Keyboard"}{"name":"Mouse"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "body too large",
+			body:           `{"name":"` + strings.Repeat("a", MaxBodyBytes) + `"}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:           "invalid fields",
+			body:           `{"price":-1}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			violations: []appErrors.Violation{
+				{Field: "name", Message: "is required"},
+				{Field: "price", Message: "must be at least 0"},
+			},
+		},
+		{
+			name:           "localized violations",
+			body:           `{"price":1}`,
+			acceptLanguage: "es-AR,es;q=0.9,en;q=0.8",
+			expectedStatus: http.StatusUnprocessableEntity,
+			violations:     []appErrors.Violation{{Field: "name", Message: "es obligatorio"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/products", strings.NewReader(tt.body))
+			r.Header.Set("Accept-Language", tt.acceptLanguage)
+
+			var request decodeRequest
+			err := DecodeJSON(httptest.NewRecorder(), r, &request)
+			if tt.expectedStatus == 0 {
+				assert.Nil(t, err)
+				return
+			}
+			if assert.NotNil(t, err) {
+				assert.Equal(t, tt.expectedStatus, err.Status)
+				assert.Equal(t, tt.violations, err.Violations)
+			}
+		})
+	}
+}
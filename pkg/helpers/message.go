@@ -3,11 +3,9 @@ package helpers
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
-
-	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
 )
 
 func ReadIdParam(r *http.Request) (int64, error) {
@@ -21,18 +19,10 @@ func ReadIdParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
-func RespondWithError(w http.ResponseWriter, err *appErrors.AppError) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(err.Code)
-	if err := json.NewEncoder(w).Encode(map[string]string{"error": err.Message}); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
-	}
-}
-
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
+		slog.Error("Error encoding JSON response", "error", err)
 	}
 }
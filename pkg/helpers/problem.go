@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+)
+
+// RequestIDHeader carries the request ID, set by the logging middleware
+const RequestIDHeader = "X-Request-ID"
+
+// ProblemContentType is the media type of RFC 9457 problem details
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details body
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code identifies the kind of error, e.g. "not_found"
+	Code string `json:"code"`
+	// RequestID lets support find the log lines of the request
+	RequestID string                `json:"request_id,omitempty"`
+	Errors    []appErrors.Violation `json:"errors,omitempty"`
+}
+
+// RespondWithError writes the error as problem details. The cause in
+// err.Err is logged, never sent. Errors caused by a deadline, such as a
+// database query timeout, become 504 and errors caused by the client
+// canceling the request become 499, whatever their status.
+func RespondWithError(w http.ResponseWriter, r *http.Request, err *appErrors.AppError) {
+	switch {
+	case errors.Is(err.Err, context.DeadlineExceeded):
+		err = appErrors.NewGatewayTimeout("Request timed out", err.Err)
+	case errors.Is(err.Err, context.Canceled):
+		err = appErrors.NewClientClosedRequest("Client closed request", err.Err)
+	}
+
+	if err.Err != nil {
+		level := slog.LevelDebug
+		if err.Status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(r.Context()).Log(r.Context(), level, err.Message, "status", err.Status, "code", err.Code, "error", err.Err)
+	}
+
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     statusTitle(err.Status),
+		Status:    err.Status,
+		Detail:    err.Message,
+		Instance:  r.URL.Path,
+		Code:      err.Code,
+		RequestID: logging.RequestID(r.Context()),
+		Errors:    err.Violations,
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(err.Status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		slog.Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// InvalidPayload returns a bad request for a body that could not be decoded,
+// pointing at the field when the decoder names it. A body over the size
+// limit is a 413.
+func InvalidPayload(err error) *appErrors.AppError {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return appErrors.New(http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body must not be larger than %d bytes", maxBytesErr.Limit), err)
+	}
+	if errors.Is(err, io.EOF) {
+		return appErrors.NewBadRequest("Request body is empty", err)
+	}
+
+	appErr := appErrors.NewBadRequest("Invalid request payload", err)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return appErr.WithViolations(appErrors.Violation{Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type)})
+	}
+	// encoding/json has no type for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return appErr.WithViolations(appErrors.Violation{Field: strings.Trim(field, `"`), Message: "is not allowed"})
+	}
+	return appErr
+}
+
+// jsonKind names the JSON value expected for t
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Pointer:
+		return jsonKind(t.Elem())
+	default:
+		return "an object"
+	}
+}
+
+func statusTitle(status int) string {
+	if status == appErrors.StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+	return http.StatusText(status)
+}
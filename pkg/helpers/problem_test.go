@@ -0,0 +1,78 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondWithError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          *appErrors.AppError
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "writes problem details without the cause",
+			err:          appErrors.NewNotFound("Product not found", fmt.Errorf("sql: no rows in result set")),
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"type":"about:blank","title":"Not Found","status":404,"detail":"Product not found","instance":"/products/7","code":"not_found"}`,
+		},
+		{
+			name: "lists the invalid fields",
+			err: appErrors.NewValidation("Invalid product",
+				appErrors.Violation{Field: "name", Message: "is required"},
+				appErrors.Violation{Field: "price", Message: "must be positive"},
+			),
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,"detail":"Invalid product","instance":"/products/7","code":"validation_failed",
+				"errors":[{"field":"name","message":"is required"},{"field":"price","message":"must be positive"}]}`,
+		},
+		{
+			name:         "deadline exceeded is a gateway timeout",
+			err:          appErrors.NewInternalServerError("Error getting products", fmt.Errorf("query: %w", context.DeadlineExceeded)),
+			expectedCode: http.StatusGatewayTimeout,
+			expectedBody: `{"type":"about:blank","title":"Gateway Timeout","status":504,"detail":"Request timed out","instance":"/products/7","code":"timeout"}`,
+		},
+		{
+			name:         "client cancellation is 499",
+			err:          appErrors.NewInternalServerError("Error getting products", context.Canceled),
+			expectedCode: appErrors.StatusClientClosedRequest,
+			expectedBody: `{"type":"about:blank","title":"Client Closed Request","status":499,"detail":"Client closed request","instance":"/products/7","code":"client_closed_request"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+
+			RespondWithError(rr, httptest.NewRequest(http.MethodGet, "/products/7", nil), tt.err)
+
+			assert.Equal(t, tt.expectedCode, rr.Code)
+			assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+			assert.JSONEq(t, tt.expectedBody, rr.Body.String())
+		})
+	}
+}
+
+func TestInvalidPayload(t *testing.T) {
+	var payload struct {
+		Price float64 `json:"price"`
+	}
+	err := json.NewDecoder(strings.NewReader(`{"price":"free"}`)).Decode(&payload)
+
+	appErr := InvalidPayload(err)
+	assert.Equal(t, http.StatusBadRequest, appErr.Status)
+	assert.Equal(t, []appErrors.Violation{{Field: "price", Message: "must be a number"}}, appErr.Violations)
+
+	err = json.NewDecoder(strings.NewReader(`{`)).Decode(&payload)
+	assert.Empty(t, InvalidPayload(err).Violations)
+}
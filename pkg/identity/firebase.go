@@ -0,0 +1,122 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+	"google.golang.org/api/option"
+
+	"github.com/Jacobo0312/go-web/pkg/logging"
+)
+
+type firebaseProvider struct {
+	client *auth.Client
+}
+
+// NewFirebaseProvider return an IdentityProvider backed by Firebase Authentication
+func NewFirebaseProvider(ctx context.Context, credentialsFile string) (IdentityProvider, error) {
+	logging.FromContext(ctx).Info("Initializing Firebase...")
+	opt := option.WithCredentialsFile(credentialsFile)
+	app, err := firebase.NewApp(ctx, nil, opt)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing app: %w", err)
+	}
+
+	client, err := app.Auth(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting Auth client: %w", err)
+	}
+
+	return &firebaseProvider{client: client}, nil
+}
+
+func (p *firebaseProvider) CreateUser(ctx context.Context, params *UserToCreate) (*User, error) {
+	toCreate := (&auth.UserToCreate{}).
+		Email(params.Email).
+		EmailVerified(params.EmailVerified).
+		Password(params.Password).
+		DisplayName(params.DisplayName).
+		Disabled(params.Disabled)
+
+	record, err := p.client.CreateUser(ctx, toCreate)
+	if err != nil {
+		return nil, translateFirebaseError(err)
+	}
+
+	return fromUserRecord(record), nil
+}
+
+func (p *firebaseProvider) UpdateUser(ctx context.Context, uid string, params *UserToUpdate) (*User, error) {
+	toUpdate := &auth.UserToUpdate{}
+	if params.Email != nil {
+		toUpdate = toUpdate.Email(*params.Email)
+	}
+	if params.Password != nil {
+		toUpdate = toUpdate.Password(*params.Password)
+	}
+	if params.DisplayName != nil {
+		toUpdate = toUpdate.DisplayName(*params.DisplayName)
+	}
+	if params.EmailVerified != nil {
+		toUpdate = toUpdate.EmailVerified(*params.EmailVerified)
+	}
+	if params.Disabled != nil {
+		toUpdate = toUpdate.Disabled(*params.Disabled)
+	}
+
+	record, err := p.client.UpdateUser(ctx, uid, toUpdate)
+	if err != nil {
+		return nil, translateFirebaseError(err)
+	}
+
+	return fromUserRecord(record), nil
+}
+
+func (p *firebaseProvider) DeleteUser(ctx context.Context, uid string) error {
+	return translateFirebaseError(p.client.DeleteUser(ctx, uid))
+}
+
+func (p *firebaseProvider) VerifyToken(ctx context.Context, token string) (*Token, error) {
+	verified, err := p.client.VerifyIDToken(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &Token{UID: verified.UID, Claims: verified.Claims}, nil
+}
+
+func (p *firebaseProvider) SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	return p.client.SetCustomUserClaims(ctx, uid, claims)
+}
+
+// Ping looks up a user that does not exist; not found means Firebase answered
+func (p *firebaseProvider) Ping(ctx context.Context) error {
+	_, err := p.client.GetUser(ctx, "health-check")
+	if auth.IsUserNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func fromUserRecord(record *auth.UserRecord) *User {
+	return &User{
+		UID:           record.UID,
+		Email:         record.Email,
+		DisplayName:   record.DisplayName,
+		EmailVerified: record.EmailVerified,
+		Disabled:      record.Disabled,
+	}
+}
+
+// translateFirebaseError maps the Firebase errors to the ones of LocalProvider
+func translateFirebaseError(err error) error {
+	switch {
+	case auth.IsEmailAlreadyExists(err):
+		return fmt.Errorf("%w: %v", ErrEmailExists, err)
+	case auth.IsUserNotFound(err):
+		return fmt.Errorf("%w: %v", ErrUserNotFound, err)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package identity
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrInvalidToken is returned when a token cannot be verified
+var ErrInvalidToken = errors.New("invalid token")
+
+// User is the account stored by the identity provider
+type User struct {
+	UID           string
+	Email         string
+	DisplayName   string
+	EmailVerified bool
+	Disabled      bool
+}
+
+// UserToCreate holds the parameters to create an account
+type UserToCreate struct {
+	Email         string
+	Password      string
+	DisplayName   string
+	EmailVerified bool
+	Disabled      bool
+}
+
+// UserToUpdate holds the fields to change on an account. Nil fields are left untouched.
+type UserToUpdate struct {
+	Email         *string
+	Password      *string
+	DisplayName   *string
+	EmailVerified *bool
+	Disabled      *bool
+}
+
+// Token is a verified ID token
+type Token struct {
+	UID    string
+	Claims map[string]interface{}
+}
+
+// IdentityProvider manages accounts and verifies the tokens they sign in with
+type IdentityProvider interface {
+	CreateUser(ctx context.Context, params *UserToCreate) (*User, error)
+	UpdateUser(ctx context.Context, uid string, params *UserToUpdate) (*User, error)
+	DeleteUser(ctx context.Context, uid string) error
+	VerifyToken(ctx context.Context, token string) (*Token, error)
+	SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error
+}
+
+// Pinger is implemented by providers that can report whether they are
+// reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
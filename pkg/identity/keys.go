@@ -0,0 +1,207 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	signingKeyBits = 2048
+	keyCacheTTL    = time.Minute
+	// keyMissReloadInterval limits the reloads caused by unknown key ids, so
+	// tokens with made-up ids cannot flood the store
+	keyMissReloadInterval = 5 * time.Second
+)
+
+// ErrKeyNotFound is returned when a token references an unknown key id
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// SigningKey is an RSA key used to sign access tokens
+type SigningKey struct {
+	ID         string
+	PrivateKey *rsa.PrivateKey
+	CreatedAt  time.Time
+	RetiredAt  *time.Time
+}
+
+// KeyStore persists signing keys so every instance shares them
+type KeyStore interface {
+	// ListKeys returns the keys still active or retired after since, newest first
+	ListKeys(ctx context.Context, since time.Time) ([]SigningKey, error)
+	// RotateKey stores key and retires every other active key at
+	// key.CreatedAt, atomically, so concurrent rotations leave one active key
+	RotateKey(ctx context.Context, key *SigningKey) error
+}
+
+// JWK is the public part of a signing key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// KeyManager hands out the current signing key, rotates it when it gets too
+// old and keeps retired keys available for verification while tokens signed
+// with them may still be valid
+type KeyManager struct {
+	store            KeyStore
+	rotationInterval time.Duration
+	retention        time.Duration
+
+	mu       sync.Mutex
+	keys     []SigningKey
+	loadedAt time.Time
+}
+
+// NewKeyManager return a new KeyManager. Retired keys are published for
+// retention, which should be at least the access token TTL.
+func NewKeyManager(store KeyStore, rotationInterval, retention time.Duration) *KeyManager {
+	return &KeyManager{
+		store:            store,
+		rotationInterval: rotationInterval,
+		retention:        retention,
+	}
+}
+
+// Current returns the key new tokens must be signed with
+func (m *KeyManager) Current(ctx context.Context) (SigningKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(ctx, false); err != nil {
+		return SigningKey{}, err
+	}
+
+	if len(m.keys) > 0 {
+		newest := m.keys[0]
+		if newest.RetiredAt == nil && time.Since(newest.CreatedAt) < m.rotationInterval {
+			return newest, nil
+		}
+	}
+
+	return m.rotate(ctx)
+}
+
+// Rotate creates a new signing key and retires the previous ones
+func (m *KeyManager) Rotate(ctx context.Context) (SigningKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.rotate(ctx)
+}
+
+// PublicKey returns the public key for kid, reloading the store once when
+// the key is not cached, so keys rotated by another instance are found.
+// Misses reload at most every keyMissReloadInterval.
+func (m *KeyManager) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(ctx, false); err != nil {
+		return nil, err
+	}
+	if key := m.find(kid); key != nil {
+		return &key.PrivateKey.PublicKey, nil
+	}
+
+	if time.Since(m.loadedAt) < keyMissReloadInterval {
+		return nil, ErrKeyNotFound
+	}
+
+	if err := m.load(ctx, true); err != nil {
+		return nil, err
+	}
+	if key := m.find(kid); key != nil {
+		return &key.PrivateKey.PublicKey, nil
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// JWKS returns the public keys that can verify unexpired tokens
+func (m *KeyManager) JWKS(ctx context.Context) (*JWKS, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(ctx, false); err != nil {
+		return nil, err
+	}
+
+	set := &JWKS{Keys: make([]JWK, 0, len(m.keys))}
+	for _, key := range m.keys {
+		pub := key.PrivateKey.PublicKey
+		set.Keys = append(set.Keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: "RS256",
+			Kid: key.ID,
+			N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		})
+	}
+
+	return set, nil
+}
+
+// rotate must be called with the lock held
+func (m *KeyManager) rotate(ctx context.Context) (SigningKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	id, err := newUID()
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	key := SigningKey{ID: id, PrivateKey: privateKey, CreatedAt: time.Now().UTC()}
+	if err := m.store.RotateKey(ctx, &key); err != nil {
+		return SigningKey{}, err
+	}
+
+	if err := m.load(ctx, true); err != nil {
+		return SigningKey{}, err
+	}
+
+	return key, nil
+}
+
+// load must be called with the lock held
+func (m *KeyManager) load(ctx context.Context, force bool) error {
+	if !force && !m.loadedAt.IsZero() && time.Since(m.loadedAt) < keyCacheTTL {
+		return nil
+	}
+
+	keys, err := m.store.ListKeys(ctx, time.Now().Add(-m.retention))
+	if err != nil {
+		return err
+	}
+
+	m.keys = keys
+	m.loadedAt = time.Now()
+	return nil
+}
+
+func (m *KeyManager) find(kid string) *SigningKey {
+	for i := range m.keys {
+		if m.keys[i].ID == kid {
+			return &m.keys[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package identity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingKeyStore counts the loads of a MemoryKeyStore
+type countingKeyStore struct {
+	*MemoryKeyStore
+	loads int
+}
+
+func (s *countingKeyStore) ListKeys(ctx context.Context, since time.Time) ([]SigningKey, error) {
+	s.loads++
+	return s.MemoryKeyStore.ListKeys(ctx, since)
+}
+
+func TestKeyManagerUnknownKidReloads(t *testing.T) {
+	ctx := context.Background()
+	store := &countingKeyStore{MemoryKeyStore: NewMemoryKeyStore()}
+	keys := NewKeyManager(store, 24*time.Hour, time.Hour)
+
+	current, err := keys.Current(ctx)
+	assert.NoError(t, err)
+	loads := store.loads
+
+	for _, kid := range []string{"made-up-1", "made-up-2", "made-up-3"} {
+		_, err := keys.PublicKey(ctx, kid)
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	}
+	assert.Equal(t, loads, store.loads, "misses right after a load do not reload")
+
+	// Past the interval, a miss reloads once, finding keys rotated elsewhere
+	keys.loadedAt = time.Now().Add(-keyMissReloadInterval)
+	rotated := SigningKey{ID: "rotated", PrivateKey: current.PrivateKey, CreatedAt: time.Now().UTC()}
+	assert.NoError(t, store.RotateKey(ctx, &rotated))
+
+	_, err = keys.PublicKey(ctx, "rotated")
+	assert.NoError(t, err)
+	assert.Equal(t, loads+1, store.loads)
+}
+
+func TestMemoryKeyStoreRotateKey(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryKeyStore()
+	first := SigningKey{ID: "first", CreatedAt: time.Now().UTC().Add(-time.Minute)}
+	second := SigningKey{ID: "second", CreatedAt: time.Now().UTC()}
+	assert.NoError(t, store.RotateKey(ctx, &first))
+	assert.NoError(t, store.RotateKey(ctx, &second))
+
+	keys, err := store.ListKeys(ctx, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "second", keys[0].ID)
+	assert.Nil(t, keys[0].RetiredAt)
+	assert.Equal(t, second.CreatedAt, *keys[1].RetiredAt)
+}
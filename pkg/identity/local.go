@@ -0,0 +1,251 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrUserNotFound is returned when the uid is unknown to the provider.
+	// It is an appErrors.ErrNotFound.
+	ErrUserNotFound = fmt.Errorf("user %w", appErrors.ErrNotFound)
+	// ErrEmailExists is returned when another account already uses the
+	// email. It is an appErrors.ErrConflict.
+	ErrEmailExists = fmt.Errorf("%w: email already exists", appErrors.ErrConflict)
+	// ErrInvalidCredentials is returned when the email or password do not match
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrUserDisabled is returned when a disabled account tries to sign in
+	ErrUserDisabled = errors.New("user is disabled")
+)
+
+// Credential is an account of the local provider
+type Credential struct {
+	User
+	PasswordHash []byte
+	Claims       map[string]interface{}
+}
+
+// CredentialStore persists the accounts of the local provider
+type CredentialStore interface {
+	Create(ctx context.Context, c *Credential) error
+	Get(ctx context.Context, uid string) (*Credential, error)
+	GetByEmail(ctx context.Context, email string) (*Credential, error)
+	Update(ctx context.Context, c *Credential) error
+	Delete(ctx context.Context, uid string) error
+}
+
+// LocalProvider is a self-contained IdentityProvider. It stores bcrypt
+// password hashes in a CredentialStore and signs RS256 JWTs with the keys
+// handed out by a KeyManager.
+type LocalProvider struct {
+	store    CredentialStore
+	keys     *KeyManager
+	issuer   string
+	tokenTTL time.Duration
+}
+
+// NewLocalProvider return a new LocalProvider
+func NewLocalProvider(store CredentialStore, keys *KeyManager, issuer string, tokenTTL time.Duration) *LocalProvider {
+	return &LocalProvider{
+		store:    store,
+		keys:     keys,
+		issuer:   issuer,
+		tokenTTL: tokenTTL,
+	}
+}
+
+// TokenTTL returns the lifetime of the access tokens
+func (p *LocalProvider) TokenTTL() time.Duration {
+	return p.tokenTTL
+}
+
+// Keys returns the key manager used to sign tokens
+func (p *LocalProvider) Keys() *KeyManager {
+	return p.keys
+}
+
+// Ping checks that a signing key can be loaded
+func (p *LocalProvider) Ping(ctx context.Context) error {
+	_, err := p.keys.Current(ctx)
+	return err
+}
+
+func (p *LocalProvider) CreateUser(ctx context.Context, params *UserToCreate) (*User, error) {
+	hash, err := HashPassword(params.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := newUID()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Credential{
+		User: User{
+			UID:           uid,
+			Email:         params.Email,
+			DisplayName:   params.DisplayName,
+			EmailVerified: params.EmailVerified,
+			Disabled:      params.Disabled,
+		},
+		PasswordHash: hash,
+	}
+	if err := p.store.Create(ctx, c); err != nil {
+		return nil, err
+	}
+
+	return &c.User, nil
+}
+
+func (p *LocalProvider) UpdateUser(ctx context.Context, uid string, params *UserToUpdate) (*User, error) {
+	c, err := p.store.Get(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Email != nil {
+		c.Email = *params.Email
+	}
+	if params.Password != nil {
+		hash, err := HashPassword(*params.Password)
+		if err != nil {
+			return nil, err
+		}
+		c.PasswordHash = hash
+	}
+	if params.DisplayName != nil {
+		c.DisplayName = *params.DisplayName
+	}
+	if params.EmailVerified != nil {
+		c.EmailVerified = *params.EmailVerified
+	}
+	if params.Disabled != nil {
+		c.Disabled = *params.Disabled
+	}
+
+	if err := p.store.Update(ctx, c); err != nil {
+		return nil, err
+	}
+
+	return &c.User, nil
+}
+
+func (p *LocalProvider) DeleteUser(ctx context.Context, uid string) error {
+	return p.store.Delete(ctx, uid)
+}
+
+func (p *LocalProvider) SetCustomClaims(ctx context.Context, uid string, claims map[string]interface{}) error {
+	c, err := p.store.Get(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	c.Claims = claims
+	return p.store.Update(ctx, c)
+}
+
+// Authenticate checks the password of the account registered with email
+func (p *LocalProvider) Authenticate(ctx context.Context, email, password string) (*User, error) {
+	c, err := p.store.GetByEmail(ctx, email)
+	if errors.Is(err, ErrUserNotFound) {
+		// Spend the same time as a real comparison so emails cannot be probed
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(c.PasswordHash, []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if c.Disabled {
+		return nil, ErrUserDisabled
+	}
+
+	return &c.User, nil
+}
+
+// IssueToken signs an access token for the user, including its custom claims
+func (p *LocalProvider) IssueToken(ctx context.Context, uid string) (string, error) {
+	c, err := p.store.Get(ctx, uid)
+	if err != nil {
+		return "", err
+	}
+
+	if c.Disabled {
+		return "", ErrUserDisabled
+	}
+
+	key, err := p.keys.Current(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{}
+	for k, v := range c.Claims {
+		claims[k] = v
+	}
+	claims["iss"] = p.issuer
+	claims["sub"] = uid
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(p.tokenTTL).Unix()
+	claims["email"] = c.Email
+	claims["email_verified"] = c.EmailVerified
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.PrivateKey)
+}
+
+func (p *LocalProvider) VerifyToken(ctx context.Context, token string) (*Token, error) {
+	claims := jwt.MapClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.PublicKey(ctx, kid)
+	})
+	if err != nil || !parsed.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if !claims.VerifyIssuer(p.issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+
+	uid, _ := claims["sub"].(string)
+	if uid == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+
+	return &Token{UID: uid, Claims: claims}, nil
+}
+
+// HashPassword returns the bcrypt hash of password
+func HashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+func newUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
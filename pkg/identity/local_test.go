@@ -0,0 +1,116 @@
+package identity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLocalProvider(tokenTTL time.Duration) *LocalProvider {
+	keys := NewKeyManager(NewMemoryKeyStore(), 24*time.Hour, time.Hour)
+	return NewLocalProvider(NewMemoryCredentialStore(), keys, "go-web", tokenTTL)
+}
+
+func TestLocalProviderTokens(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestLocalProvider(time.Hour)
+
+	user, err := provider.CreateUser(ctx, &UserToCreate{Email: "john@example.com", Password: "secret123", DisplayName: "John"})
+	assert.NoError(t, err)
+	assert.NoError(t, provider.SetCustomClaims(ctx, user.UID, map[string]interface{}{"role": "admin"}))
+
+	token, err := provider.IssueToken(ctx, user.UID)
+	assert.NoError(t, err)
+
+	verified, err := provider.VerifyToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, user.UID, verified.UID)
+	assert.Equal(t, "admin", verified.Claims["role"])
+
+	t.Run("token signed by another provider", func(t *testing.T) {
+		other := newTestLocalProvider(time.Hour)
+		_, err := other.VerifyToken(ctx, token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("token signed with a rotated key", func(t *testing.T) {
+		_, err := provider.Keys().Rotate(ctx)
+		assert.NoError(t, err)
+
+		verified, err := provider.VerifyToken(ctx, token)
+		assert.NoError(t, err)
+		assert.Equal(t, user.UID, verified.UID)
+
+		jwks, err := provider.Keys().JWKS(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, jwks.Keys, 2)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		expired := newTestLocalProvider(-time.Minute)
+		u, err := expired.CreateUser(ctx, &UserToCreate{Email: "old@example.com", Password: "secret123"})
+		assert.NoError(t, err)
+		token, err := expired.IssueToken(ctx, u.UID)
+		assert.NoError(t, err)
+
+		_, err = expired.VerifyToken(ctx, token)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestLocalProviderAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestLocalProvider(time.Hour)
+
+	user, err := provider.CreateUser(ctx, &UserToCreate{Email: "john@example.com", Password: "secret123"})
+	assert.NoError(t, err)
+
+	authenticated, err := provider.Authenticate(ctx, "john@example.com", "secret123")
+	assert.NoError(t, err)
+	assert.Equal(t, user.UID, authenticated.UID)
+
+	_, err = provider.Authenticate(ctx, "john@example.com", "wrong")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, err = provider.Authenticate(ctx, "nobody@example.com", "secret123")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	disabled := true
+	_, err = provider.UpdateUser(ctx, user.UID, &UserToUpdate{Disabled: &disabled})
+	assert.NoError(t, err)
+
+	_, err = provider.Authenticate(ctx, "john@example.com", "secret123")
+	assert.ErrorIs(t, err, ErrUserDisabled)
+	_, err = provider.IssueToken(ctx, user.UID)
+	assert.ErrorIs(t, err, ErrUserDisabled)
+}
+
+func TestLocalProviderUsers(t *testing.T) {
+	ctx := context.Background()
+	provider := newTestLocalProvider(time.Hour)
+
+	user, err := provider.CreateUser(ctx, &UserToCreate{Email: "john@example.com", Password: "secret123"})
+	assert.NoError(t, err)
+
+	_, err = provider.CreateUser(ctx, &UserToCreate{Email: "JOHN@example.com", Password: "secret123"})
+	assert.ErrorIs(t, err, ErrEmailExists)
+
+	name := "John"
+	updated, err := provider.UpdateUser(ctx, user.UID, &UserToUpdate{DisplayName: &name})
+	assert.NoError(t, err)
+	assert.Equal(t, "John", updated.DisplayName)
+
+	password, verified := "new-secret", true
+	updated, err = provider.UpdateUser(ctx, user.UID, &UserToUpdate{Password: &password, EmailVerified: &verified})
+	assert.NoError(t, err)
+	assert.True(t, updated.EmailVerified)
+	_, err = provider.Authenticate(ctx, "john@example.com", "secret123")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = provider.Authenticate(ctx, "john@example.com", "new-secret")
+	assert.NoError(t, err)
+
+	assert.NoError(t, provider.DeleteUser(ctx, user.UID))
+	assert.ErrorIs(t, provider.DeleteUser(ctx, user.UID), ErrUserNotFound)
+}
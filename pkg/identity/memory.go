@@ -0,0 +1,131 @@
+package identity
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryCredentialStore is a CredentialStore kept in memory, for tests and
+// throwaway local setups
+type MemoryCredentialStore struct {
+	mu          sync.RWMutex
+	credentials map[string]Credential
+}
+
+// NewMemoryCredentialStore return an empty MemoryCredentialStore
+func NewMemoryCredentialStore() *MemoryCredentialStore {
+	return &MemoryCredentialStore{credentials: make(map[string]Credential)}
+}
+
+func (s *MemoryCredentialStore) Create(ctx context.Context, c *Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findByEmail(c.Email) != nil {
+		return ErrEmailExists
+	}
+	s.credentials[c.UID] = *c
+	return nil
+}
+
+func (s *MemoryCredentialStore) Get(ctx context.Context, uid string) (*Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.credentials[uid]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &c, nil
+}
+
+func (s *MemoryCredentialStore) GetByEmail(ctx context.Context, email string) (*Credential, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c := s.findByEmail(email)
+	if c == nil {
+		return nil, ErrUserNotFound
+	}
+	found := *c
+	return &found, nil
+}
+
+func (s *MemoryCredentialStore) Update(ctx context.Context, c *Credential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.credentials[c.UID]; !ok {
+		return ErrUserNotFound
+	}
+	if other := s.findByEmail(c.Email); other != nil && other.UID != c.UID {
+		return ErrEmailExists
+	}
+	s.credentials[c.UID] = *c
+	return nil
+}
+
+func (s *MemoryCredentialStore) Delete(ctx context.Context, uid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.credentials[uid]; !ok {
+		return ErrUserNotFound
+	}
+	delete(s.credentials, uid)
+	return nil
+}
+
+// findByEmail must be called with the lock held
+func (s *MemoryCredentialStore) findByEmail(email string) *Credential {
+	for uid := range s.credentials {
+		c := s.credentials[uid]
+		if strings.EqualFold(c.Email, email) {
+			return &c
+		}
+	}
+	return nil
+}
+
+// MemoryKeyStore is a KeyStore kept in memory, for tests and single
+// instance local setups
+type MemoryKeyStore struct {
+	mu   sync.Mutex
+	keys []SigningKey
+}
+
+// NewMemoryKeyStore return an empty MemoryKeyStore
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{}
+}
+
+func (s *MemoryKeyStore) ListKeys(ctx context.Context, since time.Time) ([]SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []SigningKey
+	for _, key := range s.keys {
+		if key.RetiredAt == nil || key.RetiredAt.After(since) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (s *MemoryKeyStore) RotateKey(ctx context.Context, key *SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.keys {
+		if s.keys[i].RetiredAt == nil {
+			retiredAt := key.CreatedAt
+			s.keys[i].RetiredAt = &retiredAt
+		}
+	}
+	s.keys = append(s.keys, *key)
+	return nil
+}
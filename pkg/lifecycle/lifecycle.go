@@ -0,0 +1,68 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// Manager runs background workers and stops them in the reverse order they
+// were started, so workers started first, usually the ones others feed, are
+// stopped last
+type Manager struct {
+	mu      sync.Mutex
+	workers []*worker
+}
+
+type worker struct {
+	name   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewManager return a new Manager
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Go runs fn in a goroutine. fn must return soon after its context is done.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &worker{name: name, cancel: cancel, done: make(chan struct{})}
+
+	m.mu.Lock()
+	m.workers = append(m.workers, w)
+	m.mu.Unlock()
+
+	go func() {
+		defer close(w.done)
+		fn(ctx)
+	}()
+}
+
+// Stop cancels the workers one at a time, waiting for each to return. When
+// ctx is done first, the remaining workers are cancelled without waiting and
+// an error is returned.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	workers := m.workers
+	m.workers = nil
+	m.mu.Unlock()
+
+	for i := len(workers) - 1; i >= 0; i-- {
+		w := workers[i]
+		slog.Info("Stopping worker", "worker", w.name)
+		w.cancel()
+
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			for _, pending := range workers[:i] {
+				pending.cancel()
+			}
+			return fmt.Errorf("stopping %s: %w", w.name, ctx.Err())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagerStopsInReverseOrder(t *testing.T) {
+	manager := NewManager()
+
+	var mu sync.Mutex
+	var stopped []string
+	for _, name := range []string{"outbox", "webhooks", "push"} {
+		name := name
+		manager.Go(name, func(ctx context.Context) {
+			<-ctx.Done()
+			mu.Lock()
+			stopped = append(stopped, name)
+			mu.Unlock()
+		})
+	}
+
+	assert.NoError(t, manager.Stop(context.Background()))
+	assert.Equal(t, []string{"push", "webhooks", "outbox"}, stopped)
+}
+
+func TestManagerStopTimeout(t *testing.T) {
+	manager := NewManager()
+
+	cancelled := make(chan struct{})
+	manager.Go("fast", func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+	release := make(chan struct{})
+	defer close(release)
+	manager.Go("stuck", func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := manager.Stop(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "stuck")
+
+	// The remaining workers are still told to stop
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the fast worker was not cancelled")
+	}
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Supported log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// New returns a logger writing to w in the given format, "text" or "json",
+// at the given level. A *slog.LevelVar lets the level change while running.
+func New(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(format) {
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// ParseLevel parses a level name, e.g. "info"
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+type contextKey struct{}
+
+// entry is the request-scoped logger. It is shared by pointer, so
+// attributes added deep in the handler chain, like the user UID, also reach
+// the access log line written by the outermost middleware.
+type entry struct {
+	mu        sync.Mutex
+	logger    *slog.Logger
+	requestID string
+}
+
+// WithRequest returns a copy of ctx carrying a request-scoped logger that
+// tags every line with the request ID
+func WithRequest(ctx context.Context, logger *slog.Logger, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &entry{
+		logger:    logger.With("request_id", requestID),
+		requestID: requestID,
+	})
+}
+
+// FromContext returns the request-scoped logger, or the default logger
+// outside of a request
+func FromContext(ctx context.Context) *slog.Logger {
+	e, ok := ctx.Value(contextKey{}).(*entry)
+	if !ok {
+		return slog.Default()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.logger
+}
+
+// AddAttrs tags the following lines of the request-scoped logger, including
+// the access log line. It does nothing outside of a request.
+func AddAttrs(ctx context.Context, args ...any) {
+	e, ok := ctx.Value(contextKey{}).(*entry)
+	if !ok {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.logger = e.logger.With(args...)
+}
+
+// RequestID returns the ID of the request, or "" outside of a request
+func RequestID(ctx context.Context) string {
+	if e, ok := ctx.Value(contextKey{}).(*entry); ok {
+		return e.requestID
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger, err := New(&buf, FormatJSON, slog.LevelWarn)
+	assert.NoError(t, err)
+	logger.Info("skipped")
+	logger.Warn("kept", "n", 1)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "kept", entry["msg"])
+
+	buf.Reset()
+	var level slog.LevelVar
+	logger, err = New(&buf, FormatText, &level)
+	assert.NoError(t, err)
+	logger.Info("hello")
+	assert.Contains(t, buf.String(), "msg=hello")
+	level.Set(slog.LevelError)
+	logger.Warn("muted")
+	assert.NotContains(t, buf.String(), "muted")
+
+	_, err = New(&buf, "xml", slog.LevelInfo)
+	assert.Error(t, err)
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("warn")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+
+	_, err = ParseLevel("loud")
+	assert.Error(t, err)
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+	assert.Empty(t, RequestID(context.Background()))
+	// Outside of a request AddAttrs does nothing
+	AddAttrs(context.Background(), "user_id", "u1")
+
+	ctx := WithRequest(context.Background(), logger, "req-1")
+	AddAttrs(ctx, "user_id", "u1")
+	FromContext(ctx).Info("hello")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "req-1", entry["request_id"])
+	assert.Equal(t, "u1", entry["user_id"])
+	assert.Equal(t, "req-1", RequestID(ctx))
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"database/sql"
+	"io"
+	"runtime"
+	"time"
+)
+
+// RegisterDBStats exports the connection pool statistics of db
+func (r *Registry) RegisterDBStats(db *sql.DB) {
+	r.register(dbStatsCollector{db: db})
+}
+
+type dbStatsCollector struct {
+	db *sql.DB
+}
+
+func (c dbStatsCollector) write(w io.Writer) {
+	stats := c.db.Stats()
+
+	gauges := []struct {
+		name, help string
+		value      float64
+	}{
+		{"db_max_open_connections", "Maximum number of open connections to the database.", float64(stats.MaxOpenConnections)},
+		{"db_open_connections", "Established connections, both in use and idle.", float64(stats.OpenConnections)},
+		{"db_in_use_connections", "Connections currently in use.", float64(stats.InUse)},
+		{"db_idle_connections", "Idle connections.", float64(stats.Idle)},
+	}
+	for _, g := range gauges {
+		writeHeader(w, g.name, g.help, "gauge")
+		writeSample(w, g.name, "", g.value)
+	}
+
+	counters := []struct {
+		name, help string
+		value      float64
+	}{
+		{"db_wait_count_total", "Connections waited for.", float64(stats.WaitCount)},
+		{"db_wait_duration_seconds_total", "Time blocked waiting for a connection.", stats.WaitDuration.Seconds()},
+		{"db_max_idle_closed_total", "Connections closed due to SetMaxIdleConns.", float64(stats.MaxIdleClosed)},
+		{"db_max_idle_time_closed_total", "Connections closed due to SetConnMaxIdleTime.", float64(stats.MaxIdleTimeClosed)},
+		{"db_max_lifetime_closed_total", "Connections closed due to SetConnMaxLifetime.", float64(stats.MaxLifetimeClosed)},
+	}
+	for _, c := range counters {
+		writeHeader(w, c.name, c.help, "counter")
+		writeSample(w, c.name, "", c.value)
+	}
+}
+
+// RegisterRuntime exports Go runtime statistics
+func (r *Registry) RegisterRuntime() {
+	r.register(runtimeCollector{start: time.Now()})
+}
+
+type runtimeCollector struct {
+	start time.Time
+}
+
+func (c runtimeCollector) write(w io.Writer) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	writeHeader(w, "go_info", "Information about the Go environment.", "gauge")
+	writeSample(w, "go_info", `version="`+escapeLabelValue(runtime.Version())+`"`, 1)
+
+	gauges := []struct {
+		name, help string
+		value      float64
+	}{
+		{"go_goroutines", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine())},
+		{"go_memstats_alloc_bytes", "Bytes of allocated heap objects.", float64(mem.Alloc)},
+		{"go_memstats_heap_inuse_bytes", "Bytes in in-use heap spans.", float64(mem.HeapInuse)},
+		{"go_memstats_heap_objects", "Number of allocated heap objects.", float64(mem.HeapObjects)},
+		{"go_memstats_sys_bytes", "Bytes of memory obtained from the OS.", float64(mem.Sys)},
+		{"process_start_time_seconds", "Start time of the process since the Unix epoch in seconds.", float64(c.start.UnixNano()) / 1e9},
+	}
+	for _, g := range gauges {
+		writeHeader(w, g.name, g.help, "gauge")
+		writeSample(w, g.name, "", g.value)
+	}
+
+	counters := []struct {
+		name, help string
+		value      float64
+	}{
+		{"go_memstats_alloc_bytes_total", "Total bytes allocated for heap objects.", float64(mem.TotalAlloc)},
+		{"go_gc_cycles_total", "Completed garbage collection cycles.", float64(mem.NumGC)},
+		{"go_gc_pause_seconds_total", "Time spent in stop-the-world garbage collection pauses.", float64(mem.PauseTotalNs) / 1e9},
+	}
+	for _, c := range counters {
+		writeHeader(w, c.name, c.help, "counter")
+		writeSample(w, c.name, "", c.value)
+	}
+}
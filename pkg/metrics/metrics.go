@@ -0,0 +1,335 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are latency buckets in seconds suited to HTTP requests
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector writes one or more metric families
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry holds the metrics and writes them in the Prometheus text
+// exposition format
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry return a new Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.collectors = append(r.collectors, c)
+}
+
+// Write writes every metric in registration order
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler serves the metrics to Prometheus
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// NewCounterVec registers a counter partitioned by labels
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{vec: newVec[value](name, help, "counter", labels)}
+	r.register(v)
+	return v
+}
+
+// NewGaugeVec registers a gauge partitioned by labels
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{vec: newVec[value](name, help, "gauge", labels)}
+	r.register(v)
+	return v
+}
+
+// NewHistogramVec registers a histogram partitioned by labels. buckets must
+// be sorted.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := &HistogramVec{vec: newVec[Histogram](name, help, "histogram", labels), buckets: buckets}
+	r.register(v)
+	return v
+}
+
+// NewGaugeFunc registers a gauge read from fn on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(funcCollector{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// value is a float updated atomically
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (v *value) get() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+// Counter only goes up
+type Counter struct {
+	v *value
+}
+
+func (c Counter) Inc() {
+	c.v.add(1)
+}
+
+// Add panics when delta is negative
+func (c Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.v.add(delta)
+}
+
+// Gauge goes up and down
+type Gauge struct {
+	v *value
+}
+
+func (g Gauge) Inc() {
+	g.v.add(1)
+}
+
+func (g Gauge) Dec() {
+	g.v.add(-1)
+}
+
+func (g Gauge) Set(v float64) {
+	g.v.bits.Store(math.Float64bits(v))
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// CounterVec is a counter partitioned by labels
+type CounterVec struct {
+	vec[value]
+}
+
+// WithLabelValues returns the counter of the label values, in the order
+// the labels were declared
+func (v *CounterVec) WithLabelValues(values ...string) Counter {
+	return Counter{v.get(values, func() *value { return &value{} })}
+}
+
+func (v *CounterVec) write(w io.Writer) {
+	v.writeHeader(w)
+	v.each(func(labels string, c *value) {
+		writeSample(w, v.name, labels, c.get())
+	})
+}
+
+// GaugeVec is a gauge partitioned by labels
+type GaugeVec struct {
+	vec[value]
+}
+
+// WithLabelValues returns the gauge of the label values, in the order the
+// labels were declared
+func (v *GaugeVec) WithLabelValues(values ...string) Gauge {
+	return Gauge{v.get(values, func() *value { return &value{} })}
+}
+
+func (v *GaugeVec) write(w io.Writer) {
+	v.writeHeader(w)
+	v.each(func(labels string, g *value) {
+		writeSample(w, v.name, labels, g.get())
+	})
+}
+
+// HistogramVec is a histogram partitioned by labels
+type HistogramVec struct {
+	vec[Histogram]
+	buckets []float64
+}
+
+// WithLabelValues returns the histogram of the label values, in the order
+// the labels were declared
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return v.get(values, func() *Histogram {
+		return &Histogram{buckets: v.buckets, counts: make([]uint64, len(v.buckets))}
+	})
+}
+
+func (v *HistogramVec) write(w io.Writer) {
+	v.writeHeader(w)
+	v.each(func(labels string, h *Histogram) {
+		h.mu.Lock()
+		counts := append([]uint64(nil), h.counts...)
+		count, sum := h.count, h.sum
+		h.mu.Unlock()
+
+		for i, upper := range h.buckets {
+			writeSample(w, v.name+"_bucket", joinLabels(labels, `le="`+formatFloat(upper)+`"`), float64(counts[i]))
+		}
+		writeSample(w, v.name+"_bucket", joinLabels(labels, `le="+Inf"`), float64(count))
+		writeSample(w, v.name+"_sum", labels, sum)
+		writeSample(w, v.name+"_count", labels, float64(count))
+	})
+}
+
+// vec keeps one child per combination of label values
+type vec[T any] struct {
+	name, help, kind string
+	labels           []string
+
+	mu       sync.RWMutex
+	children map[string]*T
+}
+
+func newVec[T any](name, help, kind string, labels []string) vec[T] {
+	return vec[T]{name: name, help: help, kind: kind, labels: labels, children: map[string]*T{}}
+}
+
+func (v *vec[T]) get(values []string, create func() *T) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+
+	pairs := make([]string, len(values))
+	for i, label := range v.labels {
+		pairs[i] = label + `="` + escapeLabelValue(values[i]) + `"`
+	}
+	key := strings.Join(pairs, ",")
+
+	v.mu.RLock()
+	child, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return child
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if child, ok := v.children[key]; ok {
+		return child
+	}
+	child = create()
+	v.children[key] = child
+	return child
+}
+
+// each visits the children sorted by labels, so scrapes are stable
+func (v *vec[T]) each(fn func(labels string, child *T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.children))
+	children := make(map[string]*T, len(v.children))
+	for key, child := range v.children {
+		keys = append(keys, key)
+		children[key] = child
+	}
+	v.mu.RUnlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		fn(key, children[key])
+	}
+}
+
+func (v *vec[T]) writeHeader(w io.Writer) {
+	writeHeader(w, v.name, v.help, v.kind)
+}
+
+type funcCollector struct {
+	name, help, kind string
+	fn               func() float64
+}
+
+func (c funcCollector) write(w io.Writer) {
+	writeHeader(w, c.name, c.help, c.kind)
+	writeSample(w, c.name, "", c.fn())
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, strings.ReplaceAll(help, "\n", " "), name, kind)
+}
+
+func writeSample(w io.Writer, name, labels string, v float64) {
+	if labels != "" {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(v))
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+}
+
+func joinLabels(labels, extra string) string {
+	if labels == "" {
+		return extra
+	}
+	return labels + "," + extra
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("requests_total", "Requests served.", "route")
+	inFlight := registry.NewGaugeVec("in_flight", "Requests being served.", "method")
+	durations := registry.NewHistogramVec("duration_seconds", "Time to serve.", []float64{0.1, 1}, "route")
+	registry.NewGaugeFunc("connections", "Open connections.", func() float64 { return 3 })
+
+	requests.WithLabelValues("GET /products").Inc()
+	requests.WithLabelValues("GET /products").Add(2)
+	requests.WithLabelValues(`say "hi"`).Inc()
+	inFlight.WithLabelValues("GET").Inc()
+	durations.WithLabelValues("GET /products").Observe(0.05)
+	durations.WithLabelValues("GET /products").Observe(0.5)
+	durations.WithLabelValues("GET /products").Observe(2)
+
+	var out strings.Builder
+	registry.Write(&out)
+
+	assert.Equal(t, `# HELP requests_total Requests served.
+# TYPE requests_total counter
+requests_total{route="GET /products"} 3
+requests_total{route="say \"hi\""} 1
+# HELP in_flight Requests being served.
+# TYPE in_flight gauge
+in_flight{method="GET"} 1
+# HELP duration_seconds Time to serve.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="GET /products",le="0.1"} 1
+duration_seconds_bucket{route="GET /products",le="1"} 2
+duration_seconds_bucket{route="GET /products",le="+Inf"} 3
+duration_seconds_sum{route="GET /products"} 2.55
+duration_seconds_count{route="GET /products"} 3
+# HELP connections Open connections.
+# TYPE connections gauge
+connections 3
+`, out.String())
+}
+
+func TestWithLabelValuesPanicsOnWrongCount(t *testing.T) {
+	requests := NewRegistry().NewCounterVec("requests_total", "Requests served.", "method", "route")
+
+	assert.Panics(t, func() { requests.WithLabelValues("GET") })
+}
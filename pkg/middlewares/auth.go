@@ -6,29 +6,84 @@ import (
 	"strings"
 
 	"github.com/Jacobo0312/go-web/pkg/errors"
-	"github.com/Jacobo0312/go-web/pkg/firebase"
 	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/identity"
+	"github.com/Jacobo0312/go-web/pkg/logging"
 )
 
-func FirebaseAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		idToken := strings.TrimSpace(strings.Replace(authHeader, "Bearer", "", 1))
+type contextKey string
 
-		if idToken == "" {
-			helpers.RespondWithError(w, errors.NewUnauthorized("Unauthorized"))
-			return
-		}
+const (
+	userIDKey contextKey = "userID"
+	scopesKey contextKey = "scopes"
+)
 
-		token, err := firebase.FirebaseAuth.VerifyIDToken(context.Background(), idToken)
-		if err != nil {
-			helpers.RespondWithError(w, errors.NewUnauthorized("Invalid token"))
-			return
-		}
+// APIKeyVerifier resolves an API key to its owner and scopes
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (string, []string, error)
+}
+
+// AuthMiddleware verifies the bearer token with the identity provider, or
+// the API key when the header uses the ApiKey scheme, and puts the caller UID
+// in the request context. apiKeys may be nil to only accept bearer tokens.
+func AuthMiddleware(provider identity.IdentityProvider, apiKeys APIKeyVerifier) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+
+			if key, ok := strings.CutPrefix(authHeader, "ApiKey "); ok && apiKeys != nil {
+				userID, scopes, err := apiKeys.VerifyAPIKey(r.Context(), strings.TrimSpace(key))
+				if err != nil {
+					helpers.RespondWithError(w, r, errors.NewUnauthorized("Invalid API key"))
+					return
+				}
+
+				logging.AddAttrs(r.Context(), "user_id", userID, "auth", "api_key")
+				ctx := ContextWithScopes(ContextWithUserID(r.Context(), userID), scopes)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			idToken := strings.TrimSpace(strings.Replace(authHeader, "Bearer", "", 1))
 
-		// Add userID to context
-		type contextKey string
-		ctx := context.WithValue(r.Context(), contextKey("userID"), token.UID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+			if idToken == "" {
+				helpers.RespondWithError(w, r, errors.NewUnauthorized("Unauthorized"))
+				return
+			}
+
+			token, err := provider.VerifyToken(r.Context(), idToken)
+			if err != nil {
+				helpers.RespondWithError(w, r, errors.NewUnauthorized("Invalid token"))
+				return
+			}
+
+			// Add userID to context
+			logging.AddAttrs(r.Context(), "user_id", token.UID)
+			next.ServeHTTP(w, r.WithContext(ContextWithUserID(r.Context(), token.UID)))
+		}
 	}
-}
\ No newline at end of file
+}
+
+// ContextWithUserID returns a copy of ctx carrying the authenticated user ID
+func ContextWithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserIDFromContext returns the user ID set by the auth middleware
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDKey).(string)
+	return userID, ok && userID != ""
+}
+
+// ContextWithScopes returns a copy of ctx carrying the scopes of the API key
+// used to authenticate
+func ContextWithScopes(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, scopesKey, scopes)
+}
+
+// ScopesFromContext returns the API key scopes. ok is false when the caller
+// did not authenticate with an API key.
+func ScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopesKey).([]string)
+	return scopes, ok
+}
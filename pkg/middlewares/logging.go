@@ -1,9 +1,17 @@
 package middlewares
 
 import (
-	"log"
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
+
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/logging"
 )
 
 type (
@@ -30,32 +38,103 @@ func (r *loggingResponseWriter) WriteHeader(statusCode int) {
 	r.responseData.status = statusCode       // capture status code
 }
 
-func LoggingMiddleware(next http.Handler) http.HandlerFunc {
-	loggingFn := func(rw http.ResponseWriter, req *http.Request) {
-		start := time.Now()
+// Flush lets streaming handlers, like server-sent events, push partial
+// responses through the logger
+func (r *loggingResponseWriter) Flush() {
+	if r.responseData.status == 0 {
+		r.responseData.status = http.StatusOK
+	}
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
 
-		responseData := &responseData{
-			status: 0,
-			size:   0,
-		}
-		lrw := loggingResponseWriter{
-			ResponseWriter: rw,
-			responseData:   responseData,
-		}
-		next.ServeHTTP(&lrw, req)
+// Hijack lets WebSocket upgrades take over the connection. The request is
+// logged as switching protocols.
+func (r *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		r.responseData.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap exposes the original writer to http.ResponseController
+func (r *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
 
-		duration := time.Since(start)
+// LoggingMiddleware gives each request an ID, taken from a well formed
+// X-Request-ID header or generated, and echoes it in the response. It puts a
+// logger tagged with the ID and the route pattern in the request context
+// and writes an access log line once the request is served. router resolves
+// the pattern up front, since the ServeMux only sets it on the request it is
+// given.
+func LoggingMiddleware(logger *slog.Logger, router *http.ServeMux) Middleware {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(rw http.ResponseWriter, req *http.Request) {
+			start := time.Now()
 
-		log.Printf("Request: %s %s %s %s %d %d %s",
-			start.Format("2006-01-02 15:04:05"),
-			req.Method,
-			req.RequestURI,
-			req.Proto,
-			responseData.status,
-			responseData.size,
-			duration,
-		)
+			requestID := req.Header.Get(helpers.RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			rw.Header().Set(helpers.RequestIDHeader, requestID)
 
+			_, route := router.Handler(req)
+			if route == "" {
+				route = unmatchedRoute
+			}
+			ctx := logging.WithRequest(req.Context(), logger.With("route", route), requestID)
+
+			responseData := &responseData{}
+			next.ServeHTTP(&loggingResponseWriter{ResponseWriter: rw, responseData: responseData}, req.WithContext(ctx))
+
+			status := responseData.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			logging.FromContext(ctx).Log(ctx, level, "request served",
+				"method", req.Method,
+				"path", req.URL.Path,
+				"proto", req.Proto,
+				"status", status,
+				"size", responseData.size,
+				"duration", time.Since(start),
+			)
+		}
 	}
-	return http.HandlerFunc(loggingFn)
+}
+
+// validRequestID accepts IDs of up to 128 letters, digits and -_.: so a
+// client cannot inject anything into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand.Read never fails on supported platforms
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
 }
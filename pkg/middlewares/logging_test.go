@@ -0,0 +1,90 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/Jacobo0312/go-web/pkg/helpers"
+	"github.com/Jacobo0312/go-web/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		logging.AddAttrs(r.Context(), "user_id", "u1")
+		logging.FromContext(r.Context()).Info("loading product")
+		helpers.RespondWithError(w, r, errors.NewNotFound("Product not found", nil))
+	})
+	handler := LoggingMiddleware(logger, mux)(mux)
+
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{name: "propagates the request id", requestID: "abc-123"},
+		{name: "generates a missing request id", generated: true},
+		{name: "replaces a malformed request id", requestID: "bad id\n", generated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/products/7", nil)
+			if tt.requestID != "" {
+				req.Header.Set(helpers.RequestIDHeader, tt.requestID)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			requestID := rr.Header().Get(helpers.RequestIDHeader)
+			if tt.generated {
+				assert.Len(t, requestID, 32)
+			} else {
+				assert.Equal(t, tt.requestID, requestID)
+			}
+			assert.Contains(t, rr.Body.String(), `"request_id":"`+requestID+`"`)
+
+			lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+			if !assert.Len(t, lines, 2) {
+				return
+			}
+			for _, line := range lines {
+				var entry map[string]interface{}
+				assert.NoError(t, json.Unmarshal(line, &entry))
+				assert.Equal(t, requestID, entry["request_id"])
+				assert.Equal(t, "GET /products/{id}", entry["route"])
+				assert.Equal(t, "u1", entry["user_id"])
+			}
+
+			var access map[string]interface{}
+			assert.NoError(t, json.Unmarshal(lines[1], &access))
+			assert.Equal(t, "request served", access["msg"])
+			assert.Equal(t, float64(http.StatusNotFound), access["status"])
+		})
+	}
+}
+
+func TestLoggingMiddlewareUnmatchedRoute(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	mux := http.NewServeMux()
+
+	rr := httptest.NewRecorder()
+	LoggingMiddleware(logger, mux)(mux).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	var access map[string]interface{}
+	assert.NoError(t, json.Unmarshal(logs.Bytes(), &access))
+	assert.Equal(t, unmatchedRoute, access["route"])
+	assert.Equal(t, float64(http.StatusNotFound), access["status"])
+}
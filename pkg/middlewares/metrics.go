@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Jacobo0312/go-web/pkg/metrics"
+)
+
+// unmatchedRoute labels requests no route matched, so unknown paths do not
+// create new series
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware records the rate, errors and duration of the requests by
+// method, route pattern and status class. It must wrap the ServeMux, which
+// sets r.Pattern.
+func MetricsMiddleware(registry *metrics.Registry) Middleware {
+	requests := registry.NewCounterVec("http_requests_total", "HTTP requests served.", "method", "route", "status")
+	durations := registry.NewHistogramVec("http_request_duration_seconds", "Time to serve HTTP requests.", metrics.DefBuckets, "method", "route", "status")
+	inFlight := registry.NewGaugeVec("http_requests_in_flight", "HTTP requests being served.", "method")
+
+	return func(next http.Handler) http.HandlerFunc {
+		return func(rw http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			method := methodLabel(req.Method)
+			gauge := inFlight.WithLabelValues(method)
+			gauge.Inc()
+			defer gauge.Dec()
+
+			responseData := &responseData{}
+			next.ServeHTTP(&loggingResponseWriter{ResponseWriter: rw, responseData: responseData}, req)
+
+			route := req.Pattern
+			if route == "" {
+				route = unmatchedRoute
+			}
+			status := statusClass(responseData.status)
+
+			requests.WithLabelValues(method, route, status).Inc()
+			durations.WithLabelValues(method, route, status).Observe(time.Since(start).Seconds())
+		}
+	}
+}
+
+// methodLabel keeps clients from creating series with made up methods
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	default:
+		return "OTHER"
+	}
+}
+
+// statusClass returns e.g. "2xx". Handlers that write nothing answer 200.
+func statusClass(status int) string {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
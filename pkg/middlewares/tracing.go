@@ -0,0 +1,57 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Jacobo0312/go-web/pkg/logging"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware starts a server span for each request, continuing the
+// trace of an incoming W3C traceparent header. Like MetricsMiddleware, it
+// must wrap the ServeMux to name the span after r.Pattern.
+func TracingMiddleware(next http.Handler) http.HandlerFunc {
+	tracer := otel.Tracer("github.com/Jacobo0312/go-web/pkg/middlewares")
+
+	return func(rw http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := tracer.Start(ctx, req.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.URLPath(req.URL.Path),
+			),
+		)
+		defer span.End()
+
+		// Correlates the log lines of the request with its trace
+		if spanContext := span.SpanContext(); spanContext.IsValid() {
+			logging.AddAttrs(ctx, "trace_id", spanContext.TraceID().String())
+		}
+
+		// The mux sets the pattern on the request it is given
+		routed := req.WithContext(ctx)
+		responseData := &responseData{}
+		next.ServeHTTP(&loggingResponseWriter{ResponseWriter: rw, responseData: responseData}, routed)
+
+		if routed.Pattern != "" {
+			span.SetName(routed.Pattern)
+			span.SetAttributes(semconv.HTTPRoute(routed.Pattern))
+		}
+
+		status := responseData.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+		}
+	}
+}
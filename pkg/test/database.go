@@ -0,0 +1,25 @@
+package test
+
+import (
+	"context"
+
+	"github.com/Jacobo0312/go-web/pkg/database"
+)
+
+// FakeTransactor runs the functions without a database. Rollbacks are not
+// simulated, so tests should assert on what was called.
+type FakeTransactor struct {
+	// Commits counts the functions that returned nil
+	Commits int
+	// Rollbacks counts the functions that returned an error
+	Rollbacks int
+}
+
+func (t *FakeTransactor) WithinTx(ctx context.Context, fn func(tx database.DBTX) error) error {
+	if err := fn(nil); err != nil {
+		t.Rollbacks++
+		return err
+	}
+	t.Commits++
+	return nil
+}
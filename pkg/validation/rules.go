@@ -0,0 +1,139 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var builtinRules = map[string]Rule{
+	"notblank": func(value reflect.Value, _ string) bool {
+		return !isEmpty(value)
+	},
+	"min": func(value reflect.Value, param string) bool {
+		n, ok := size(value)
+		return ok && n >= mustParseFloat("min", param)
+	},
+	"max": func(value reflect.Value, param string) bool {
+		n, ok := size(value)
+		return ok && n <= mustParseFloat("max", param)
+	},
+	"len": func(value reflect.Value, param string) bool {
+		n, ok := size(value)
+		return ok && n == mustParseFloat("len", param)
+	},
+	"email": func(value reflect.Value, _ string) bool {
+		if value.Kind() != reflect.String {
+			return false
+		}
+		address, err := mail.ParseAddress(value.String())
+		return err == nil && address.Address == value.String()
+	},
+	"oneof": func(value reflect.Value, param string) bool {
+		s := fmt.Sprint(value.Interface())
+		for _, allowed := range strings.Fields(param) {
+			if s == allowed {
+				return true
+			}
+		}
+		return false
+	},
+	"regex": func(value reflect.Value, param string) bool {
+		return value.Kind() == reflect.String && compile(param).MatchString(value.String())
+	},
+}
+
+var builtinMessages = map[string]map[string]string{
+	"en": {
+		"required":   "is required",
+		"notblank":   "must not be blank",
+		"min.string": "must be at least {param} characters long",
+		"min.number": "must be at least {param}",
+		"min.slice":  "must have at least {param} items",
+		"max.string": "must be at most {param} characters long",
+		"max.number": "must be at most {param}",
+		"max.slice":  "must have at most {param} items",
+		"len.string": "must be exactly {param} characters long",
+		"len.number": "must be {param}",
+		"len.slice":  "must have exactly {param} items",
+		"email":      "must be a valid email address",
+		"oneof":      "must be one of {param}",
+		"regex":      "has an invalid format",
+		"invalid":    "is invalid",
+	},
+	"es": {
+		"required":   "es obligatorio",
+		"notblank":   "no puede estar vacío",
+		"min.string": "debe tener al menos {param} caracteres",
+		"min.number": "debe ser como mínimo {param}",
+		"min.slice":  "debe tener al menos {param} elementos",
+		"max.string": "debe tener como máximo {param} caracteres",
+		"max.number": "debe ser como máximo {param}",
+		"max.slice":  "debe tener como máximo {param} elementos",
+		"len.string": "debe tener exactamente {param} caracteres",
+		"len.number": "debe ser {param}",
+		"len.slice":  "debe tener exactamente {param} elementos",
+		"email":      "debe ser un correo electrónico válido",
+		"oneof":      "debe ser uno de {param}",
+		"regex":      "tiene un formato no válido",
+		"invalid":    "no es válido",
+	},
+}
+
+// size is the length of strings, in characters, and of slices, or the
+// value of numbers
+func size(value reflect.Value) (float64, bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+	return 0, false
+}
+
+// kindName selects the message variant of a rule for value
+func kindName(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "slice"
+	}
+	if _, ok := size(value); ok {
+		return "number"
+	}
+	return ""
+}
+
+// mustParseFloat panics on a malformed tag, which is a programming error
+func mustParseFloat(rule, param string) float64 {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: %s expects a number, got %q", rule, param))
+	}
+	return n
+}
+
+var patterns sync.Map
+
+// compile caches the patterns of regex rules
+func compile(pattern string) *regexp.Regexp {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(pattern)
+	patterns.Store(pattern, re)
+	return re
+}
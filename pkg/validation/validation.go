@@ -0,0 +1,322 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+)
+
+// DefaultLocale is used when the requested locale has no messages
+const DefaultLocale = "en"
+
+// Rule reports whether value satisfies the rule. param is the text after
+// "=" in the tag, e.g. "3" for min=3. value is never a pointer: pointers
+// are dereferenced. Nil values, and empty values that are not behind a
+// pointer, are only checked by required.
+type Rule func(value reflect.Value, param string) bool
+
+// Validator checks structs against their validate tags:
+//
+//	Name  string  `json:"name" validate:"required,max=100"`
+//	Price float64 `json:"price" validate:"min=0"`
+//
+// Rules are separated by commas and run in order; the first one that fails
+// is reported. regex must be the last rule of a tag, since its pattern may
+// contain commas. Fields without required may be left empty, but pointer
+// fields that are set are always checked, so partial updates can use
+// notblank to reject {"name": ""}.
+type Validator struct {
+	mu       sync.RWMutex
+	rules    map[string]Rule
+	messages map[string]map[string]string
+
+	// fields caches the parsed tags of each struct type
+	fields sync.Map
+}
+
+// New returns a Validator with the built-in rules and messages
+func New() *Validator {
+	v := &Validator{rules: map[string]Rule{}, messages: map[string]map[string]string{}}
+	for name, rule := range builtinRules {
+		v.rules[name] = rule
+	}
+	for locale, messages := range builtinMessages {
+		v.RegisterMessages(locale, messages)
+	}
+	return v
+}
+
+// RegisterRule adds a rule, or replaces the one with the same name. Its
+// messages are registered with RegisterMessages under the rule name.
+func (v *Validator) RegisterRule(name string, rule Rule) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.rules[name] = rule
+}
+
+// RegisterMessages adds or replaces the messages of a locale, e.g. "es".
+// Keys are rule names, optionally suffixed with the kind of the field
+// (".string", ".number" or ".slice"), and "{param}" is replaced by the
+// parameter of the rule.
+func (v *Validator) RegisterMessages(locale string, messages map[string]string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	locale = normalizeLocale(locale)
+	if v.messages[locale] == nil {
+		v.messages[locale] = map[string]string{}
+	}
+	for key, message := range messages {
+		v.messages[locale][key] = message
+	}
+}
+
+// Struct validates s, a struct or a pointer to one, and returns the
+// violations with messages in the closest available locale. Nested structs
+// and slices of structs are validated too, e.g. "items[0].sku".
+func (v *Validator) Struct(s any, locale string) []appErrors.Violation {
+	var violations []appErrors.Violation
+	v.validateStruct(reflect.ValueOf(s), "", normalizeLocale(locale), &violations)
+	return violations
+}
+
+func (v *Validator) validateStruct(value reflect.Value, path, locale string, violations *[]appErrors.Violation) {
+	value = indirect(value)
+	if !value.IsValid() || value.Kind() != reflect.Struct {
+		return
+	}
+
+	for _, f := range v.parse(value.Type()) {
+		fieldValue := value.FieldByIndex(f.index)
+		fieldPath := joinPath(path, f.name)
+
+		if f.embedded {
+			v.validateStruct(fieldValue, path, locale, violations)
+			continue
+		}
+
+		if failed, ok := v.check(fieldValue, f.rules); !ok {
+			*violations = append(*violations, appErrors.Violation{
+				Field:   fieldPath,
+				Message: v.message(locale, failed, indirect(fieldValue)),
+			})
+			continue
+		}
+
+		v.validateNested(fieldValue, fieldPath, locale, violations)
+	}
+}
+
+func (v *Validator) validateNested(value reflect.Value, path, locale string, violations *[]appErrors.Violation) {
+	value = indirect(value)
+	if !value.IsValid() {
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		v.validateStruct(value, path, locale, violations)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			v.validateNested(value.Index(i), fmt.Sprintf("%s[%d]", path, i), locale, violations)
+		}
+	}
+}
+
+// check runs the rules of a field and returns the first one that fails
+func (v *Validator) check(value reflect.Value, rules []ruleRef) (ruleRef, bool) {
+	if len(rules) == 0 {
+		return ruleRef{}, true
+	}
+	if rules[0].name == "required" && isEmpty(value) {
+		return rules[0], false
+	}
+
+	sent := value.Kind() == reflect.Pointer && !value.IsNil()
+	value = indirect(value)
+	if !sent && isEmpty(value) {
+		return ruleRef{}, true
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	for _, r := range rules {
+		if r.name == "required" {
+			continue
+		}
+		rule, ok := v.rules[r.name]
+		if !ok {
+			panic(fmt.Sprintf("validation: unknown rule %q", r.name))
+		}
+		if !rule(value, r.param) {
+			return r, false
+		}
+	}
+	return ruleRef{}, true
+}
+
+// message looks up the rule for the kind of value, then the rule alone,
+// in the locale, its language and the default locale
+func (v *Validator) message(locale string, r ruleRef, value reflect.Value) string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	language, _, _ := strings.Cut(locale, "-")
+	keys := []string{r.name + "." + kindName(value), r.name, "invalid"}
+
+	for _, key := range keys {
+		for _, candidate := range []string{locale, language, DefaultLocale} {
+			if message, ok := v.messages[candidate][key]; ok {
+				return strings.ReplaceAll(message, "{param}", paramText(r))
+			}
+		}
+	}
+	return "is invalid"
+}
+
+type ruleRef struct {
+	name, param string
+}
+
+type field struct {
+	index    []int
+	name     string
+	rules    []ruleRef
+	embedded bool
+}
+
+// parse returns the fields of t that are exported and sent as JSON
+func (v *Validator) parse(t reflect.Type) []field {
+	if cached, ok := v.fields.Load(t); ok {
+		return cached.([]field)
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		f := field{index: sf.Index, name: name, rules: parseTag(sf.Tag.Get("validate"))}
+		if sf.Anonymous && name == "" && indirectType(sf.Type).Kind() == reflect.Struct {
+			f.embedded = true
+		}
+		if f.name == "" {
+			f.name = sf.Name
+		}
+		fields = append(fields, f)
+	}
+
+	v.fields.Store(t, fields)
+	return fields
+}
+
+// parseTag splits a validate tag into rules, keeping required first
+func parseTag(tag string) []ruleRef {
+	if tag == "" {
+		return nil
+	}
+
+	var rules []ruleRef
+	required := false
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regex=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "required" {
+			required = true
+			continue
+		}
+		rules = append(rules, ruleRef{name: name, param: param})
+	}
+
+	if required {
+		rules = append([]ruleRef{{name: "required"}}, rules...)
+	}
+	return rules
+}
+
+func paramText(r ruleRef) string {
+	if r.name == "oneof" {
+		return strings.Join(strings.Fields(r.param), ", ")
+	}
+	return r.param
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isEmpty reports whether value is nil, zero, blank or has no elements
+func isEmpty(value reflect.Value) bool {
+	value = indirect(value)
+	if !value.IsValid() {
+		return true
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+var defaultValidator = New()
+
+// Struct validates s with the default Validator
+func Struct(s any, locale string) []appErrors.Violation {
+	return defaultValidator.Struct(s, locale)
+}
+
+// RegisterRule adds a rule to the default Validator
+func RegisterRule(name string, rule Rule) {
+	defaultValidator.RegisterRule(name, rule)
+}
+
+// RegisterMessages adds messages to the default Validator
+func RegisterMessages(locale string, messages map[string]string) {
+	defaultValidator.RegisterMessages(locale, messages)
+}
@@ -0,0 +1,151 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	appErrors "github.com/Jacobo0312/go-web/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type item struct {
+	SKU      string `json:"sku" validate:"required,len=6"`
+	Quantity int    `json:"quantity" validate:"min=1,max=10"`
+}
+
+type order struct {
+	Email    string   `json:"email" validate:"required,email"`
+	Name     *string  `json:"name" validate:"max=5"`
+	Status   string   `json:"status" validate:"oneof=pending paid"`
+	Code     string   `json:"code" validate:"regex=^[A-Z]{2,3}$"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Items    []item   `json:"items" validate:"required"`
+	Internal string   `json:"-" validate:"required"`
+}
+
+func TestStruct(t *testing.T) {
+	name := "Johnny"
+
+	tests := []struct {
+		name       string
+		value      any
+		violations []appErrors.Violation
+	}{
+		{
+			name: "valid",
+			value: &order{
+				Email:  "john@example.com",
+				Status: "paid",
+				Code:   "AB",
+				Items:  []item{{SKU: "ABC123", Quantity: 2}},
+			},
+		},
+		{
+			name:  "required fields",
+			value: order{Email: "  "},
+			violations: []appErrors.Violation{
+				{Field: "email", Message: "is required"},
+				{Field: "items", Message: "is required"},
+			},
+		},
+		{
+			name: "rules of each kind",
+			value: &order{
+				Email:  "not an email",
+				Name:   &name,
+				Status: "shipped",
+				Code:   "abcd",
+				Tags:   []string{"a", "b", "c"},
+				Items:  []item{{SKU: "ABC123", Quantity: 1}, {SKU: "ABC", Quantity: 11}},
+			},
+			violations: []appErrors.Violation{
+				{Field: "email", Message: "must be a valid email address"},
+				{Field: "name", Message: "must be at most 5 characters long"},
+				{Field: "status", Message: "must be one of pending, paid"},
+				{Field: "code", Message: "has an invalid format"},
+				{Field: "tags", Message: "must have at most 2 items"},
+				{Field: "items[1].sku", Message: "must be exactly 6 characters long"},
+				{Field: "items[1].quantity", Message: "must be at most 10"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.violations, Struct(tt.value, "en"))
+		})
+	}
+}
+
+func TestStructSentPointers(t *testing.T) {
+	type update struct {
+		Name  *string `json:"name" validate:"notblank,max=5"`
+		Email *string `json:"email" validate:"email"`
+	}
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name       string
+		value      update
+		violations []appErrors.Violation
+	}{
+		{name: "fields left out", value: update{}},
+		{name: "valid fields", value: update{Name: str("John"), Email: str("john@example.com")}},
+		{
+			name:  "empty strings",
+			value: update{Name: str(""), Email: str("")},
+			violations: []appErrors.Violation{
+				{Field: "name", Message: "must not be blank"},
+				{Field: "email", Message: "must be a valid email address"},
+			},
+		},
+		{
+			name:       "whitespace",
+			value:      update{Name: str("   ")},
+			violations: []appErrors.Violation{{Field: "name", Message: "must not be blank"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.violations, Struct(tt.value, "en"))
+		})
+	}
+}
+
+func TestStructLocales(t *testing.T) {
+	value := order{Email: "john@example.com", Items: []item{{SKU: "ABC123", Quantity: -1}}}
+
+	t.Run("regional locale falls back to its language", func(t *testing.T) {
+		assert.Equal(t, []appErrors.Violation{{Field: "items[0].quantity", Message: "debe ser como mínimo 1"}}, Struct(value, "es_MX"))
+	})
+
+	t.Run("unknown locale falls back to the default", func(t *testing.T) {
+		assert.Equal(t, []appErrors.Violation{{Field: "items[0].quantity", Message: "must be at least 1"}}, Struct(value, "fr"))
+	})
+}
+
+func TestRegisterRule(t *testing.T) {
+	v := New()
+	v.RegisterRule("uppercase", func(value reflect.Value, _ string) bool {
+		return strings.ToUpper(value.String()) == value.String()
+	})
+	v.RegisterMessages("en", map[string]string{"uppercase": "must be uppercase"})
+
+	type country struct {
+		Code string `json:"code" validate:"required,uppercase"`
+		Name string `json:"name" validate:"uppercase"`
+	}
+
+	assert.Empty(t, v.Struct(country{Code: "AR"}, "en"))
+	assert.Equal(t, []appErrors.Violation{{Field: "code", Message: "must be uppercase"}}, v.Struct(country{Code: "ar"}, "en"))
+	// Untranslated messages fall back to the default locale
+	assert.Equal(t, []appErrors.Violation{{Field: "code", Message: "must be uppercase"}}, v.Struct(country{Code: "ar"}, "es"))
+
+	assert.Panics(t, func() {
+		v.Struct(struct {
+			Code string `validate:"missing"`
+		}{Code: "AR"}, "en")
+	})
+}